@@ -0,0 +1,262 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package main
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lied/conf"
+	"lied/dialog"
+	"lied/edit"
+	"lied/help"
+	"lied/keys"
+	"lied/ui"
+
+	"github.com/pgavlin/femto"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	// Actions shown by the keys bar, in this order
+	fkeyActions = []string{"help.show", "panel.next", "git.menu", "shell.input", "search.show", "file.previous",
+		"file.next", "settings.menu", "tools.menu", "main.menu", "problem.next", "app.quit"}
+	ckeyActions = []string{"palette.show", "find.show", "file.quickopen", "tasks.menu", "git.nextchange", "git.previouschange",
+		"git.hunk", "file.save", "file.saveas", "file.new", "file.open", "file.close"}
+)
+
+// ****************************************************************************
+// registerActions()
+// registerActions fills the registry of the actions which can be bound to keys
+// ****************************************************************************
+func registerActions() {
+	// Screens and menus
+	keys.Register("help.show", "Help", func(any) { SwitchHelp() }, nil)
+	keys.Register("panel.next", "Panel", nextPanel, nil)
+	keys.Register("git.menu", "GIT", func(any) { ShowGITMenu() }, nil)
+	keys.Register("shell.input", "Shell", InputShell, nil)
+	keys.Register("search.show", "Search", edit.SwitchSearch, nil)
+	keys.Register("file.previous", "Previous", func(any) { edit.SwitchPreviousFile() }, nil)
+	keys.Register("file.next", "Next", func(any) { edit.SwitchNextFile() }, nil)
+	keys.Register("settings.menu", "Settings", func(any) { ShowConfigMenu() }, nil)
+	keys.Register("tools.menu", "Tools", ShowToolsMenu, nil)
+	keys.Register("main.menu", "Menu", func(any) { ShowMainMenu() }, nil)
+	keys.Register("problem.next", "Next problem", edit.NextProblem, nil)
+	keys.Register("problem.previous", "Previous problem", edit.PreviousProblem, nil)
+	keys.Register("app.quit", "Exit", ShowQuitDialog, nil)
+	keys.Register("find.show", "Find…", edit.ShowFindBar, nil)
+	keys.Register("file.quickopen", "Go to file…", edit.QuickOpen, nil)
+	keys.Register("tasks.menu", "Run task…", ShowTasksMenu, nil)
+	keys.Register("palette.show", "Commands…", keys.ShowPalette, nil)
+	keys.Register("cmdline.show", "Command line", ShowCmdLine, nil)
+	// Files
+	keys.Register("file.save", "Save", func(any) { edit.SaveFile() }, nil)
+	keys.Register("file.saveas", "Save as…", func(any) { edit.SaveFileAs() }, nil)
+	keys.Register("file.new", "New", func(any) { edit.NewFile(config.Workspace) }, nil)
+	keys.Register("file.open", "Open…", func(any) { InputFileOpen(config.Workspace) }, nil)
+	keys.Register("file.close", "Close", func(any) { edit.CloseCurrentFile() }, nil)
+	// Editor
+	keys.Register("edit.copy", "Copy", editAction((*femto.View).Copy), nil)
+	keys.Register("edit.cut", "Cut", editAction((*femto.View).Cut), nil)
+	keys.Register("edit.paste", "Paste", editAction((*femto.View).Paste), nil)
+	keys.Register("edit.undo", "Undo", editAction((*femto.View).Undo), nil)
+	keys.Register("edit.redo", "Redo", editAction((*femto.View).Redo), nil)
+	keys.Register("edit.selectall", "Select all", editAction((*femto.View).SelectAll), nil)
+	keys.Register("edit.deleteline", "Delete line", editAction((*femto.View).DeleteLine), nil)
+	keys.Register("edit.duplicateline", "Duplicate line", editAction((*femto.View).DuplicateLine), nil)
+	keys.Register("edit.selectline", "Select line", editAction((*femto.View).SelectLine), nil)
+	keys.Register("edit.moveup", "Move lines up", editAction((*femto.View).MoveLinesUp), nil)
+	keys.Register("edit.movedown", "Move lines down", editAction((*femto.View).MoveLinesDown), nil)
+	keys.Register("edit.indent", "Indent selection", editAction((*femto.View).IndentSelection), nil)
+	keys.Register("edit.outdent", "Outdent selection", editAction((*femto.View).OutdentSelection), nil)
+	keys.Register("edit.matchbrace", "Go to matching brace", editAction((*femto.View).JumpToMatchingBrace), nil)
+	keys.Register("edit.top", "Go to the start of the file", editAction((*femto.View).CursorStart), nil)
+	keys.Register("edit.bottom", "Go to the end of the file", editAction((*femto.View).CursorEnd), nil)
+	// GIT into the editor
+	keys.Register("git.nextchange", "Next change", edit.NextChange, nil)
+	keys.Register("git.previouschange", "Previous change", edit.PreviousChange, nil)
+	keys.Register("git.hunk", "Hunk…", edit.ShowHunk, nil)
+	keys.Register("git.nextconflict", "Next conflict", edit.NextConflict, nil)
+	keys.Register("git.previousconflict", "Previous conflict", edit.PreviousConflict, nil)
+	keys.Register("git.acceptours", "Accept ours", edit.AcceptOurs, nil)
+	keys.Register("git.accepttheirs", "Accept theirs", edit.AcceptTheirs, nil)
+	keys.Register("git.acceptboth", "Accept both", edit.AcceptBoth, nil)
+	// Panels and dialogs
+	keys.Register("focus.editor", "Editor", func(any) { ui.App.SetFocus(ui.EdtMain) }, nil)
+	keys.Register("focus.openfiles", "Open files", func(any) { ui.App.SetFocus(ui.TblOpenFiles) }, nil)
+	keys.Register("focus.explorer", "Explorer", func(any) { ui.App.SetFocus(ui.TrvExplorer) }, nil)
+	keys.Register("openfiles.open", "Open the selected file", openSelectedFile, nil)
+	keys.Register("dialog.cancel", "Close the dialog", dialog.Escape, nil)
+	keys.Register("dialog.ok", "Validate the dialog", dialog.Validate, nil)
+	// The user's tools, read from lied.ini
+	for _, t := range edit.Tools {
+		keys.Register("tool."+t.Name, "Tool : "+t.Name, edit.RunTool, t)
+	}
+	// The items of the menus
+	keys.RegisterMenu(buildMainMenu)
+	keys.RegisterMenu(buildConfigMenu)
+	keys.RegisterMenu(buildThemeMenu)
+	keys.RegisterMenu(buildGITMenu)
+	keys.RegisterMenu(buildToolsMenu)
+}
+
+// ****************************************************************************
+// editAction()
+// editAction runs an action of the editor as femto does for its own keys, the
+// view follows the cursor when the action asks for it
+// ****************************************************************************
+func editAction(action func(*femto.View) bool) func(any) {
+	return func(any) {
+		if action(ui.EdtMain) {
+			ui.EdtMain.Relocate()
+		}
+	}
+}
+
+// ****************************************************************************
+// defaultBindings()
+// ****************************************************************************
+func defaultBindings() {
+	defaults := map[string][][2]string{
+		keys.CTX_GLOBAL: {
+			{"F1", "help.show"}, {"F2", "panel.next"}, {"F3", "git.menu"}, {"F4", "shell.input"},
+			{"F5", "search.show"}, {"F6", "file.previous"}, {"F7", "file.next"}, {"F8", "settings.menu"},
+			{"F9", "tools.menu"}, {"F10", "main.menu"}, {"F11", "problem.next"}, {"Shift+F11", "problem.previous"},
+			{"F12", "app.quit"}, {"Ctrl+F", "find.show"}, {"Ctrl+P", "file.quickopen"}, {"Ctrl+B", "tasks.menu"},
+			{"Ctrl+S", "file.save"}, {"Alt+S", "file.saveas"}, {"Ctrl+N", "file.new"}, {"Ctrl+O", "file.open"},
+			{"Ctrl+T", "file.close"}, {"Alt+X", "palette.show"}, {"Alt+:", "cmdline.show"},
+		},
+		keys.CTX_EDITOR: {
+			{"Ctrl+C", "edit.copy"}, {"Ctrl+X", "edit.cut"}, {"Ctrl+V", "edit.paste"}, {"Ctrl+Z", "edit.undo"},
+			{"Ctrl+Y", "edit.redo"}, {"Ctrl+A", "edit.selectall"}, {"Ctrl+L", "edit.deleteline"},
+			{"Alt+PgDn", "git.nextchange"}, {"Alt+PgUp", "git.previouschange"}, {"Alt+H", "git.hunk"},
+			{"Alt+N", "git.nextconflict"}, {"Alt+P", "git.previousconflict"}, {"Alt+O", "git.acceptours"},
+			{"Alt+T", "git.accepttheirs"}, {"Alt+B", "git.acceptboth"}, {"Esc", "focus.openfiles"},
+		},
+		keys.CTX_OPENFILES: {
+			{"Enter", "openfiles.open"}, {":", "cmdline.show"},
+		},
+		keys.CTX_EXPLORER: {
+			{":", "cmdline.show"},
+		},
+		keys.CTX_DIALOG: {
+			{"Esc", "dialog.cancel"}, {"Alt+Enter", "dialog.ok"},
+		},
+	}
+	for ctx, list := range defaults {
+		for _, b := range list {
+			keys.Bind(ctx, b[0], b[1])
+		}
+	}
+}
+
+// ****************************************************************************
+// loadBindings()
+// loadBindings sets the default bindings, then the ones of the bindings file,
+// which is written with the defaults when it does not exist yet. The menus are
+// registered too, so the editor screen has to exist.
+// ****************************************************************************
+func loadBindings() {
+	registerActions()
+	defaultBindings()
+	fName := filepath.Join(appDir, conf.FILE_BINDINGS)
+	if _, err := os.Stat(fName); errors.Is(err, os.ErrNotExist) {
+		if err := keys.Save(fName); err != nil {
+			ui.SetStatus(err.Error())
+		}
+	} else {
+		for _, err := range keys.Load(fName) {
+			ui.SetStatus(err.Error())
+		}
+	}
+	bindTools()
+	edit.RegisterScreenKeys()
+	help.RegisterScreenKeys()
+	conf.FKEY_LABELS = keys.Labels(keys.CTX_EDITOR, fkeyActions...)
+	conf.CKEY_LABELS = keys.Labels(keys.CTX_EDITOR, ckeyActions...)
+	for i := range ui.ArrScreens {
+		if ui.ArrScreens[i].Mode == ui.ModeTextEdit {
+			ui.ArrScreens[i].Keys = conf.CKEY_LABELS
+		}
+	}
+	ui.LblKeys.SetText(conf.FKEY_LABELS + "\n" + conf.CKEY_LABELS)
+}
+
+// ****************************************************************************
+// bindTools()
+// bindTools binds the shortcuts of the tools into the global context. They
+// are set by lied.ini, after the bindings file, so they are not written to it.
+// ****************************************************************************
+func bindTools() {
+	for _, t := range edit.Tools {
+		if t.Shortcut == "" {
+			continue
+		}
+		if err := keys.Bind(keys.CTX_GLOBAL, t.Shortcut, "tool."+t.Name); err != nil {
+			ui.SetStatus(fmt.Sprintf("Tool %s : %s", t.Name, err.Error()))
+		}
+	}
+}
+
+// ****************************************************************************
+// keyContext()
+// keyContext tells which bindings apply, from the widget having the focus
+// ****************************************************************************
+func keyContext() string {
+	switch focus := ui.App.GetFocus(); {
+	case dialog.Focused():
+		return keys.CTX_DIALOG
+	case focus == ui.EdtMain:
+		return keys.CTX_EDITOR
+	case focus == ui.TblOpenFiles:
+		return keys.CTX_OPENFILES
+	case focus == ui.TrvExplorer:
+		return keys.CTX_EXPLORER
+	}
+	return keys.CTX_GLOBAL
+}
+
+// ****************************************************************************
+// nextPanel()
+// nextPanel moves the focus from the editor to the open files, then to the
+// explorer and back to the editor
+// ****************************************************************************
+func nextPanel(f any) {
+	switch ui.App.GetFocus() {
+	case ui.TblOpenFiles:
+		ui.App.SetFocus(ui.TrvExplorer)
+	case ui.TrvExplorer:
+		ui.App.SetFocus(ui.EdtMain)
+	case ui.EdtMain:
+		ui.App.SetFocus(ui.TblOpenFiles)
+	default:
+		ui.SetStatus(fmt.Sprintf("No panel into the %s screen", ui.CurrentMode))
+	}
+}
+
+// ****************************************************************************
+// openSelectedFile()
+// ****************************************************************************
+func openSelectedFile(f any) {
+	idx, _ := ui.TblOpenFiles.GetSelection()
+	fName := ui.TblOpenFiles.GetCell(idx, 3).Text
+	edit.SwitchOpenFile(fName)
+	edit.SetFocusOnPath(fName)
+	ui.App.SetFocus(ui.EdtMain)
+}
@@ -0,0 +1,587 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package main
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"lied/conf"
+	"lied/edit"
+	"lied/help"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type cmdSetting struct {
+	values func() []string
+	get    func() string
+	set    func(string) error
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	cmdHistory    []string
+	cmdHistoryPos int
+	// The commands of the command bar, the actions of the key bindings are
+	// accepted too, like ":git.menu"
+	cmdCommands = []string{"e", "q", "qa", "r", "s", "set", "w", "wq"}
+	cmdSettings = map[string]cmdSetting{
+		"theme": {
+			values: func() []string { return arrThemes },
+			get:    func() string { return config.Theme },
+			set: func(v string) error {
+				for _, thm := range arrThemes {
+					if thm == v {
+						setTheme(v)
+						return nil
+					}
+				}
+				return fmt.Errorf("unknown theme %s", v)
+			},
+		},
+		"showhidden": {
+			values: boolValues,
+			get:    func() string { return strconv.FormatBool(config.ShowHidden) },
+			set: func(v string) error {
+				b, err := strconv.ParseBool(v)
+				if err == nil && b != config.ShowHidden {
+					SwitchShowHidden(nil)
+				}
+				return err
+			},
+		},
+		"confirmexit": {
+			values: boolValues,
+			get:    func() string { return strconv.FormatBool(config.ConfirmExit) },
+			set: func(v string) error {
+				b, err := strconv.ParseBool(v)
+				if err == nil && b != config.ConfirmExit {
+					SwitchConfirmExit(nil)
+				}
+				return err
+			},
+		},
+		"backup": {
+			values: func() []string { return []string{conf.BACKUP_NONE, conf.BACKUP_TILDE, conf.BACKUP_TIMESTAMP} },
+			get:    func() string { return config.Backup },
+			set: func(v string) error {
+				for _, mode := range []string{conf.BACKUP_NONE, conf.BACKUP_TILDE, conf.BACKUP_TIMESTAMP} {
+					if strings.EqualFold(mode, v) {
+						applyBackup(mode)
+						return nil
+					}
+				}
+				return fmt.Errorf("unknown backup mode %s", v)
+			},
+		},
+		"timeformat": {
+			get: func() string { return config.FormatTime },
+			set: func(v string) error { applyFormatTime(v); return nil },
+		},
+		"dateformat": {
+			get: func() string { return config.FormatDate },
+			set: func(v string) error { applyFormatDate(v); return nil },
+		},
+		"gituser": {
+			get: func() string { return config.GitUser },
+			set: func(v string) error { applyGitUser(v); return nil },
+		},
+	}
+)
+
+// ****************************************************************************
+// initCmdLine()
+// ****************************************************************************
+func initCmdLine() {
+	loadCmdHistory()
+	help.AddTopic("cmdline", "Command line", cmdLineTopic)
+	ui.InpCmd.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			cmdline := strings.TrimSpace(ui.InpCmd.GetText())
+			hideCmdLine()
+			if cmdline == "" {
+				return
+			}
+			addCmdHistory(cmdline)
+			// Run the command out of the input handler of the field, since
+			// some commands force a redraw of the screen
+			go ui.App.QueueUpdateDraw(func() {
+				if err := runCmd(cmdline); err != nil {
+					ui.SetStatus(fmt.Sprintf("%s : %s", cmdline, err.Error()))
+				}
+			})
+		case tcell.KeyEsc:
+			hideCmdLine()
+		}
+	})
+	ui.InpCmd.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp:
+			browseCmdHistory(-1)
+			return nil
+		case tcell.KeyDown:
+			browseCmdHistory(1)
+			return nil
+		case tcell.KeyTab:
+			completeCmdLine()
+			return nil
+		}
+		return event
+	})
+}
+
+// ****************************************************************************
+// ShowCmdLine()
+// ShowCmdLine moves the focus to the command bar of the editor screen
+// ****************************************************************************
+func ShowCmdLine(f any) {
+	if ui.CurrentMode != ui.ModeTextEdit {
+		edit.ShowEditorScreen()
+	}
+	ui.InpCmd.SetText("")
+	cmdHistoryPos = len(cmdHistory)
+	ui.App.SetFocus(ui.InpCmd)
+}
+
+// ****************************************************************************
+// hideCmdLine()
+// ****************************************************************************
+func hideCmdLine() {
+	ui.InpCmd.SetText("")
+	ui.App.SetFocus(ui.EdtMain)
+}
+
+// ****************************************************************************
+// runCmd()
+// runCmd runs a line of the command bar, through the functions of the menus
+// ****************************************************************************
+func runCmd(cmdline string) error {
+	cmdline = strings.TrimSpace(strings.TrimPrefix(cmdline, ":"))
+	if cmdline == "" {
+		return nil
+	}
+	if strings.HasPrefix(cmdline, "!") {
+		if sh := strings.TrimSpace(cmdline[1:]); sh != "" {
+			edit.RunShell(sh)
+		}
+		return nil
+	}
+	if n, err := strconv.Atoi(cmdline); err == nil {
+		edit.GotoLine(n-1, 0)
+		return nil
+	}
+	if isSubstitute(cmdline) {
+		return substitute(cmdline)
+	}
+	name, arg, _ := strings.Cut(cmdline, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "w":
+		if arg == "" {
+			edit.SaveFile()
+		} else {
+			edit.SaveFileTo(cmdPath(arg))
+		}
+	case "wq":
+		edit.SaveAndCloseFile()
+	case "e":
+		if arg == "" {
+			InputFileOpen(config.Workspace)
+		} else {
+			openFileNamed(cmdPath(arg))
+		}
+	case "q":
+		edit.CloseCurrentFile()
+	case "qa":
+		ShowQuitDialog(nil)
+	case "r":
+		return readInto(arg)
+	case "set":
+		return setOption(arg)
+	default:
+		if keys.Run(name) != nil {
+			return fmt.Errorf("unknown command %s", name)
+		}
+	}
+	return nil
+}
+
+// ****************************************************************************
+// isSubstitute()
+// isSubstitute tells if cmdline looks like s/re/rep/ or %s/re/rep/
+// ****************************************************************************
+func isSubstitute(cmdline string) bool {
+	cmdline = strings.TrimPrefix(cmdline, "%")
+	return len(cmdline) > 1 && cmdline[0] == 's' && strings.ContainsRune("/#|,;:", rune(cmdline[1]))
+}
+
+// ****************************************************************************
+// substitute()
+// substitute runs s/re/rep/flags on the current line, or %s/re/rep/flags on
+// the whole file. The flag g replaces all the matches of the lines, the flag
+// i ignores the case.
+// ****************************************************************************
+func substitute(cmdline string) error {
+	buf := edit.CurrentFile.Buffer
+	if buf == nil {
+		return errors.New("no file to substitute into")
+	}
+	first, last := buf.Cursor.Y, buf.Cursor.Y
+	if strings.HasPrefix(cmdline, "%") {
+		first, last = 0, buf.NumLines-1
+		cmdline = cmdline[1:]
+	}
+	parts := splitUnescaped(cmdline[2:], cmdline[1])
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return fmt.Errorf("usage : s%cregexp%creplacement%c[gi]", cmdline[1], cmdline[1], cmdline[1])
+	}
+	pattern, flags := parts[0], ""
+	if len(parts) == 3 {
+		flags = parts[2]
+	}
+	if strings.Trim(flags, "gi") != "" {
+		return fmt.Errorf("unknown flags %s", flags)
+	}
+	if strings.Contains(flags, "i") {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	n := edit.Substitute(re, parts[1], first, last, strings.Contains(flags, "g"))
+	if n == 0 {
+		return fmt.Errorf("'%s' not found", parts[0])
+	}
+	ui.SetStatus(fmt.Sprintf("%d occurrence(s) replaced", n))
+	return nil
+}
+
+// ****************************************************************************
+// splitUnescaped()
+// splitUnescaped splits s on sep, unless sep is escaped by a backslash
+// ****************************************************************************
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == sep:
+			sb.WriteByte(sep)
+			i++
+		case s[i] == sep:
+			parts = append(parts, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return append(parts, sb.String())
+}
+
+// ****************************************************************************
+// readInto()
+// readInto inserts at the cursor the output of "!cmd" or the content of a file
+// ****************************************************************************
+func readInto(arg string) error {
+	if edit.CurrentFile.Buffer == nil {
+		return errors.New("no file to read into")
+	}
+	if strings.HasPrefix(arg, "!") {
+		if sh := strings.TrimSpace(arg[1:]); sh != "" {
+			edit.ReadShell(sh)
+			return nil
+		}
+	}
+	if arg == "" {
+		return errors.New("usage : r file or r !command")
+	}
+	text, err := edit.ReadText(cmdPath(arg))
+	if err != nil {
+		return err
+	}
+	buf := edit.CurrentFile.Buffer
+	buf.Insert(buf.Cursor.Loc, text)
+	ui.SetStatus(fmt.Sprintf("%s inserted", arg))
+	return nil
+}
+
+// ****************************************************************************
+// setOption()
+// setOption shows all the settings, shows one with "name" or changes it with
+// "name=value"
+// ****************************************************************************
+func setOption(arg string) error {
+	if arg == "" {
+		var list []string
+		for _, name := range settingNames() {
+			list = append(list, name+"="+cmdSettings[name].get())
+		}
+		ui.SetStatus(strings.Join(list, " "))
+		return nil
+	}
+	name, value, hasValue := strings.Cut(arg, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	s, ok := cmdSettings[name]
+	if !ok {
+		return fmt.Errorf("unknown setting %s", name)
+	}
+	if !hasValue {
+		ui.SetStatus(name + "=" + s.get())
+		return nil
+	}
+	return s.set(strings.TrimSpace(value))
+}
+
+// ****************************************************************************
+// settingNames()
+// ****************************************************************************
+func settingNames() []string {
+	names := make([]string, 0, len(cmdSettings))
+	for name := range cmdSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ****************************************************************************
+// boolValues()
+// ****************************************************************************
+func boolValues() []string {
+	return []string{"false", "true"}
+}
+
+// ****************************************************************************
+// cmdPath()
+// cmdPath makes a path typed into the command bar absolute, from the workspace
+// ****************************************************************************
+func cmdPath(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		userDir, _ := os.UserHomeDir()
+		p = filepath.Join(userDir, p[1:])
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(edit.CurrentWorkspace, p)
+	}
+	return filepath.Clean(p)
+}
+
+// ****************************************************************************
+// completeCmdLine()
+// completeCmdLine completes the command bar up to the longest common prefix of
+// the choices, which are shown into the status bar when there are several
+// ****************************************************************************
+func completeCmdLine() {
+	head, word, choices := cmdChoices(ui.InpCmd.GetText())
+	var matches []string
+	for _, c := range choices {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		ui.SetStatus("No completion")
+		return
+	}
+	ui.InpCmd.SetText(head + commonPrefix(matches))
+	if len(matches) > 1 {
+		ui.SetStatus(strings.Join(matches, " "))
+	}
+}
+
+// ****************************************************************************
+// cmdChoices()
+// cmdChoices splits the command bar into the text to keep, the word being
+// typed and the possible completions of this word
+// ****************************************************************************
+func cmdChoices(text string) (string, string, []string) {
+	name, arg, hasArg := strings.Cut(text, " ")
+	if !hasArg {
+		choices := append([]string(nil), cmdCommands...)
+		for _, a := range keys.Actions() {
+			if !strings.HasPrefix(a.Name, "mnu") {
+				choices = append(choices, a.Name)
+			}
+		}
+		return "", text, choices
+	}
+	switch name {
+	case "e", "w", "r":
+		if strings.HasPrefix(arg, "!") {
+			return text, "", nil
+		}
+		dir, base := filepath.Split(arg)
+		return name + " " + dir, base, pathChoices(dir, strings.HasPrefix(base, "."))
+	case "set":
+		key, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue {
+			var choices []string
+			for _, n := range settingNames() {
+				choices = append(choices, n+"=")
+			}
+			return "set ", arg, choices
+		}
+		if s, ok := cmdSettings[key]; ok && s.values != nil {
+			return "set " + key + "=", value, s.values()
+		}
+	}
+	return text, "", nil
+}
+
+// ****************************************************************************
+// pathChoices()
+// ****************************************************************************
+func pathChoices(dir string, hidden bool) []string {
+	entries, err := os.ReadDir(cmdPath(utils.If(dir == "", ".", dir)))
+	if err != nil {
+		return nil
+	}
+	var choices []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") && !hidden {
+			continue
+		}
+		choices = append(choices, e.Name()+utils.If(e.IsDir(), "/", ""))
+	}
+	return choices
+}
+
+// ****************************************************************************
+// commonPrefix()
+// ****************************************************************************
+func commonPrefix(list []string) string {
+	prefix := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// ****************************************************************************
+// cmdLineTopic()
+// ****************************************************************************
+func cmdLineTopic() string {
+	commands := [][2]string{
+		{"w [file]", "Save the file, or save it as file"},
+		{"wq", "Save and close the file"},
+		{"e [file]", "Open a file, or browse for one"},
+		{"q", "Close the file"},
+		{"qa", "Exit"},
+		{"42", "Go to the line 42"},
+		{"s/re/rep/[gi]", "Replace re by rep into the line, g for all the matches, i to ignore the case"},
+		{"%s/re/rep/[gi]", "The same into the whole file, $1 inserts the first group of re"},
+		{"set [name[=value]]", "Show the settings, one setting, or change it"},
+		{"!command", "Run the command into the shell screen"},
+		{"r !command", "Insert the output of the command at the cursor"},
+		{"r file", "Insert the file at the cursor"},
+		{"action", "Run one of the {{actions}}, like git.menu"},
+	}
+	var sb strings.Builder
+	sb.WriteString("The command bar is the last line of the editor screen. Tab completes the commands, " +
+		"the paths and the settings, ↑↓ browse the history of the commands and Esc goes back to the editor.\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "  [yellow]:%-20s[white] %s\n", c[0], c[1])
+	}
+	sb.WriteString("\n[red]Settings[white]\n\n")
+	for _, name := range settingNames() {
+		s := cmdSettings[name]
+		values := ""
+		if s.values != nil {
+			values = strings.Join(s.values(), ", ")
+		}
+		fmt.Fprintf(&sb, "  [yellow]%-12s[white] %-20s [gray]%s[white]\n", name, tview.Escape(s.get()), values)
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// cmdHistoryFile()
+// ****************************************************************************
+func cmdHistoryFile() string {
+	return filepath.Join(appDir, conf.FILE_CMD_HISTORY)
+}
+
+// ****************************************************************************
+// loadCmdHistory()
+// ****************************************************************************
+func loadCmdHistory() {
+	cmdHistory = nil
+	f, err := os.Open(cmdHistoryFile())
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			cmdHistory = append(cmdHistory, scanner.Text())
+		}
+	}
+	cmdHistoryPos = len(cmdHistory)
+}
+
+// ****************************************************************************
+// addCmdHistory()
+// ****************************************************************************
+func addCmdHistory(cmdline string) {
+	if len(cmdHistory) == 0 || cmdHistory[len(cmdHistory)-1] != cmdline {
+		cmdHistory = append(cmdHistory, cmdline)
+	}
+	if len(cmdHistory) > conf.CMD_HISTORY_MAX {
+		cmdHistory = cmdHistory[len(cmdHistory)-conf.CMD_HISTORY_MAX:]
+	}
+	cmdHistoryPos = len(cmdHistory)
+	data := strings.Join(cmdHistory, "\n") + "\n"
+	utils.AtomicWriteFile(cmdHistoryFile(), []byte(data), 0600, nil)
+}
+
+// ****************************************************************************
+// browseCmdHistory()
+// ****************************************************************************
+func browseCmdHistory(delta int) {
+	if len(cmdHistory) == 0 {
+		return
+	}
+	cmdHistoryPos += delta
+	if cmdHistoryPos < 0 {
+		cmdHistoryPos = 0
+	}
+	if cmdHistoryPos >= len(cmdHistory) {
+		cmdHistoryPos = len(cmdHistory)
+		ui.InpCmd.SetText("")
+		return
+	}
+	ui.InpCmd.SetText(cmdHistory[cmdHistoryPos])
+}
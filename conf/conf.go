@@ -23,27 +23,72 @@ const (
 	APP_URL                 = "https://github.com/jplozf/lied"
 	APP_FOLDER              = ".lied"
 	ICON_MODIFIED           = "●"
+	ICON_CONFLICT           = "⚡"
 	NEW_FILE_TEMPLATE       = "lied_"
 	FILE_LOG                = "lied.log"
 	FILE_CONFIG             = "lied.json"
 	FILE_INI                = "lied.ini"
+	FILE_CREDENTIALS        = "credentials"
+	FILE_BINDINGS           = "bindings.ini"
+	ASKPASS_ENV             = "LIED_ASKPASS"
 	FILE_MRU                = "mru"
-	FKEY_LABELS             = "F1=Help F2=Panel F3=GIT F4=Shell F6=Previous F7=Next F8=Settings F10=Menu F12=Exit"
-	CKEY_LABELS             = "Ctrl+F=Find… Ctrl+S=Save Alt+S=Save as… Ctrl+N=New Ctrl+O=Open… Ctrl+T=Close"
+	FILE_MRU_FILES          = "mru_files"
+	FILE_MRU_WORKSPACES     = "mru_workspaces"
+	MRU_MAX                 = 15
+	DIR_BACKUP              = "backup"
+	DIR_SWAP                = "swap"
+	DIR_WORKSPACES          = "workspaces"
+	SWAP_INTERVAL           = 10
+	SWAP_EDITS              = 50
+	WATCH_INTERVAL          = 2
+	GIT_WATCH_INTERVAL      = 500 // ms
+	GIT_DEBOUNCE            = 300 // ms
+	NEW_FILE_PERM           = 0644
+	BACKUP_NONE             = "None"
+	BACKUP_TILDE            = "file~"
+	BACKUP_TIMESTAMP        = "Timestamped"
+	SEARCH_MAX_RESULTS      = 5000
+	INDEX_MAX_FILES         = 100000
+	INDEX_REFRESH           = 5
+	QUICKOPEN_MAX           = 200
+	FILE_SHELL_HISTORY      = "shell_history"
+	SHELL_HISTORY_MAX       = 100
+	FILE_CMD_HISTORY        = "cmd_history"
+	CMD_HISTORY_MAX         = 100
+	PROBLEMS_MAX            = 1000
 )
 
 // var Cwd string
 var LogFile *os.File
 
+// Generated from the active key bindings, see keys.Labels()
+var (
+	FKEY_LABELS = "F1=Help F2=Panel F3=GIT F4=Shell F5=Search F6=Previous F7=Next F8=Settings F9=Tools F10=Menu F11=Next problem F12=Exit"
+	CKEY_LABELS = "Ctrl+F=Find… Ctrl+P=Go to file… Ctrl+B=Run task… Alt+PgUp/PgDn=Changes Alt+H=Hunk… Ctrl+S=Save Alt+S=Save as… Ctrl+N=New Ctrl+O=Open… Ctrl+T=Close"
+)
+
+// Generated from the keys of the screens, see keys.ScreenLabels()
+var (
+	SKEY_LABELS string // Search
+	DKEY_LABELS string // Diff
+	PKEY_LABELS string // Problems
+	GKEY_LABELS string // Git
+	BKEY_LABELS string // Blame
+	YKEY_LABELS string // History
+	IKEY_LABELS string // Help
+	HKEY_LABELS string // Shell
+)
+
 // var Workspace string
 
 type Config struct {
-	Theme       string
-	GitUser     string
-	GitPassword string
-	Workspace   string
-	ShowHidden  bool
-	ConfirmExit bool
-	FormatTime  string
-	FormatDate  string
+	Theme         string
+	GitUser       string
+	Workspace     string
+	WorkspaceName string
+	ShowHidden    bool
+	ConfirmExit   bool
+	FormatTime    string
+	FormatDate    string
+	Backup        string
 }
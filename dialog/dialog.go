@@ -42,6 +42,9 @@ const (
 	INPUT_LIST
 	INPUT_FOLDER
 	INPUT_FILE
+	INPUT_FILTER
+	INPUT_MULTILINE
+	INPUT_PASSWORD
 )
 
 type DlgRC struct {
@@ -55,8 +58,10 @@ type Dialog struct {
 	message string
 	done    func(rc DlgButton, idx int)
 	buttons []*tview.Button
+	actions []func() // Of the buttons, when their labels are not the usual ones
 	Value   string
 	Values  []string
+	Checked []bool
 	Path    string
 	parent  string
 	focus   tview.Primitive
@@ -67,8 +72,17 @@ type Dialog struct {
 	uiMsg   tview.TextView
 	uiList  tview.DropDown
 	uiInput tview.InputField
+	filter  func(query string) []string
+	results *tview.List
+	popup   tview.Primitive // What Popup returned, the results of a filter included
 }
 
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+// shown are the dialogs on screen, the last shown at the end
+var shown []*Dialog
+
 // ****************************************************************************
 // YesNoCancel()
 // ****************************************************************************
@@ -97,6 +111,18 @@ func (m *Dialog) YesNoCancel(title string, message string, done func(rc DlgButto
 	return m
 }
 
+// ****************************************************************************
+// Choice()
+// Choice is a YesNoCancel whose Yes and No buttons tell what they do
+// ****************************************************************************
+func (m *Dialog) Choice(title string, message string, yes string, no string, done func(rc DlgButton, idx int), idx int, parent string, focus tview.Primitive) *Dialog {
+	m = m.YesNoCancel(title, message, done, idx, parent, focus)
+	m.buttons[0].SetLabel(yes)
+	m.buttons[1].SetLabel(no)
+	m.actions = []func(){m.doYes, m.doNo, m.doCancel}
+	return m
+}
+
 // ****************************************************************************
 // YesNo()
 // ****************************************************************************
@@ -178,6 +204,17 @@ func (m *Dialog) Input(title string, message string, value string, done func(rc
 	return m
 }
 
+// ****************************************************************************
+// Password()
+// Password asks for a secret, its characters are masked and there is no
+// default value
+// ****************************************************************************
+func (m *Dialog) Password(title string, message string, done func(rc DlgButton, idx int), idx int, parent string, focus tview.Primitive) *Dialog {
+	m = m.Input(title, message, "", done, idx, parent, focus)
+	m.dtype = INPUT_PASSWORD
+	return m
+}
+
 // ****************************************************************************
 // List()
 // ****************************************************************************
@@ -206,6 +243,38 @@ func (m *Dialog) List(title string, message string, values []string, done func(r
 	return m
 }
 
+// ****************************************************************************
+// Multiline()
+// Multiline asks for a text of several lines, options are shown as checkboxes
+// below it and Checked tells which ones are ticked
+// ****************************************************************************
+func (m *Dialog) Multiline(title string, message string, value string, options []string, checked []bool, done func(rc DlgButton, idx int), idx int, parent string, focus tview.Primitive) *Dialog {
+	m = &Dialog{
+		Form:    tview.NewForm(),
+		title:   title,
+		message: message,
+		Value:   value,
+		Values:  options,
+		Checked: checked,
+		done:    done,
+		parent:  parent,
+		focus:   focus,
+		idx:     idx,
+		dtype:   INPUT_MULTILINE,
+	}
+
+	m.SetButtonsAlign(tview.AlignCenter)
+	m.SetButtonBackgroundColor(tview.Styles.PrimitiveBackgroundColor)
+	m.SetButtonTextColor(tview.Styles.PrimaryTextColor)
+	m.SetBackgroundColor(tview.Styles.ContrastBackgroundColor).SetBorderPadding(0, 0, 0, 0)
+	m.SetBorder(true).
+		SetBackgroundColor(tview.Styles.ContrastBackgroundColor).
+		SetBorderPadding(1, 1, 1, 1)
+	m.buttons = append(m.buttons, tview.NewButton("OK").SetSelectedFunc(m.doOK))
+	m.buttons = append(m.buttons, tview.NewButton("Cancel").SetSelectedFunc(m.doCancel))
+	return m
+}
+
 // ****************************************************************************
 // FileBrowser()
 // ****************************************************************************
@@ -240,6 +309,78 @@ func (m *Dialog) FileBrowser(title string, path string, done func(rc DlgButton,
 	return m
 }
 
+// ****************************************************************************
+// Filter()
+// Filter shows an input field above a list of results, which are given by the
+// filter function each time the input changes
+// ****************************************************************************
+func (m *Dialog) Filter(title string, message string, filter func(query string) []string, done func(rc DlgButton, idx int), idx int, parent string, focus tview.Primitive) *Dialog {
+	m = &Dialog{
+		Form:    tview.NewForm(),
+		title:   title,
+		message: message,
+		filter:  filter,
+		done:    done,
+		parent:  parent,
+		focus:   focus,
+		idx:     idx,
+		dtype:   INPUT_FILTER,
+	}
+	m.results = tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	m.results.SetBackgroundColor(tview.Styles.ContrastBackgroundColor)
+	m.results.SetSelectedFunc(func(int, string, string, rune) {
+		m.doOK()
+	})
+	m.uiInput = *tview.NewInputField()
+	m.uiInput.SetLabel(message)
+	m.uiInput.SetChangedFunc(func(text string) {
+		m.setResults(m.filter(text))
+	})
+	m.uiInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp:
+			if i := m.results.GetCurrentItem(); i > 0 {
+				m.results.SetCurrentItem(i - 1)
+			}
+			return nil
+		case tcell.KeyDown:
+			if i := m.results.GetCurrentItem(); i < m.results.GetItemCount()-1 {
+				m.results.SetCurrentItem(i + 1)
+			}
+			return nil
+		case tcell.KeyEnter:
+			m.doOK()
+			return nil
+		}
+		return event
+	})
+	m.AddFormItem(&m.uiInput)
+	m.SetBackgroundColor(tview.Styles.ContrastBackgroundColor).SetBorderPadding(0, 0, 1, 1)
+	m.setResults(m.filter(""))
+	return m
+}
+
+// ****************************************************************************
+// Refilter()
+// Refilter runs the filter again, when the data behind it have changed
+// ****************************************************************************
+func (m *Dialog) Refilter() {
+	if m != nil && m.dtype == INPUT_FILTER {
+		m.setResults(m.filter(m.uiInput.GetText()))
+	}
+}
+
+// ****************************************************************************
+// setResults()
+// ****************************************************************************
+func (m *Dialog) setResults(values []string) {
+	m.Values = values
+	m.results.Clear()
+	for _, v := range values {
+		m.results.AddItem(tview.Escape(v), "", 0, nil)
+	}
+}
+
 // ****************************************************************************
 // setPath()
 // ****************************************************************************
@@ -288,9 +429,21 @@ func (m *Dialog) refresh() {
 	case INPUT_TEXT:
 		m.AddTextView("", m.message, 0, 1, true, false)
 		m.AddInputField(">", m.Value, 0, nil, nil)
+	case INPUT_PASSWORD:
+		m.AddTextView("", m.message, 0, 1, true, false)
+		m.AddPasswordField(">", "", 0, '*', nil)
 	case INPUT_LIST:
 		m.AddTextView("", m.message, 0, 1, true, false)
 		m.AddDropDown("", m.Values, 0, nil)
+	case INPUT_MULTILINE:
+		m.AddTextView("", m.message, 0, 1, true, false)
+		m.AddTextArea("", m.Value, 0, 6, 0, nil)
+		for i, option := range m.Values {
+			m.AddCheckbox(option, i < len(m.Checked) && m.Checked[i], nil)
+			if len(option)+6 > m.width {
+				m.width = len(option) + 6
+			}
+		}
 	case INPUT_FILE:
 		/*
 			m.uiMsg = *tview.NewTextView()
@@ -306,10 +459,12 @@ func (m *Dialog) refresh() {
 		m.AddTextView("", m.message, 0, 1, true, false)
 	}
 
-	for _, button := range m.buttons {
+	for i, button := range m.buttons {
 		l := button.GetLabel()
 		var f func()
-		if l == "Yes" {
+		if i < len(m.actions) {
+			f = m.actions[i]
+		} else if l == "Yes" {
 			f = m.doYes
 		} else if l == "No" {
 			f = m.doNo
@@ -332,41 +487,147 @@ func (m *Dialog) refresh() {
 	}
 	m.width += 10
 	m.height = 9
-	if m.dtype == INPUT_TEXT || m.dtype == INPUT_LIST || m.dtype == INPUT_FILE {
+	if m.dtype == INPUT_TEXT || m.dtype == INPUT_PASSWORD || m.dtype == INPUT_LIST || m.dtype == INPUT_FILE {
 		m.height += 2
 	}
+	if m.dtype == INPUT_MULTILINE {
+		m.height += 7 + len(m.Values)
+		if m.width < 70 {
+			m.width = 70
+		}
+		// Don't overflow the screen when there are many options
+		_, _, sw, sh := ui.PgsApp.GetRect()
+		if sh > 0 && m.height > sh {
+			m.height = sh
+		}
+		if sw > 0 && m.width > sw {
+			m.width = sw
+		}
+	}
 }
 
 // ****************************************************************************
 // Popup()
 // ****************************************************************************
 func (m *Dialog) Popup() tview.Primitive {
+	if m.dtype == INPUT_FILTER {
+		return m.filterPopup()
+	}
 	m.refresh()
-	m.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEsc:
-			ui.PgsApp.SwitchToPage(m.parent)
-			ui.App.SetFocus(m.focus)
-			return nil
-		}
-		return event
-	})
 
-	return tview.NewFlex().
+	return m.show(m)
+}
+
+// ****************************************************************************
+// filterPopup()
+// ****************************************************************************
+func (m *Dialog) filterPopup() tview.Primitive {
+	_, _, sw, sh := ui.PgsApp.GetRect()
+	m.width = sw * 3 / 4
+	m.height = sh * 3 / 4
+	frame := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(m, 1, 0, true).
+		AddItem(m.results, 0, 1, false)
+	frame.SetBorder(true).
+		SetTitle(m.title).
+		SetBackgroundColor(tview.Styles.ContrastBackgroundColor).
+		SetBorderPadding(1, 0, 1, 1)
+
+	return m.show(frame)
+}
+
+// ****************************************************************************
+// show()
+// show centers the frame of the dialog and stacks the dialog above the ones
+// which still have the focus, the others are closed
+// ****************************************************************************
+func (m *Dialog) show(frame tview.Primitive) tview.Primitive {
+	m.popup = tview.NewFlex().
 		AddItem(nil, 0, 1, false).
 		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
 			AddItem(nil, 0, 1, false).
-			AddItem(m, m.height, 1, true).
+			AddItem(frame, m.height, 1, true).
 			AddItem(nil, 0, 1, false), m.width, 1, true).
 		AddItem(nil, 0, 1, false)
+	open := shown[:0]
+	for _, d := range shown {
+		if d != m && d.popup.HasFocus() {
+			open = append(open, d)
+		}
+	}
+	shown = append(open, m)
+	return m.popup
 }
 
 // ****************************************************************************
-// doYes()
+// close()
 // ****************************************************************************
-func (m *Dialog) doYes() {
+func (m *Dialog) close() {
+	for i, d := range shown {
+		if d == m {
+			shown = append(shown[:i], shown[i+1:]...)
+			break
+		}
+	}
 	ui.PgsApp.SwitchToPage(m.parent)
 	ui.App.SetFocus(m.focus)
+}
+
+// ****************************************************************************
+// focused()
+// focused returns the dialog having the focus, on its form or on its results
+// ****************************************************************************
+func focused() *Dialog {
+	for i := len(shown) - 1; i >= 0; i-- {
+		if shown[i].popup.HasFocus() {
+			return shown[i]
+		}
+	}
+	return nil
+}
+
+// ****************************************************************************
+// Focused()
+// Focused tells if a dialog has the focus, its keys are then the ones of the
+// "dialog" context
+// ****************************************************************************
+func Focused() bool {
+	return focused() != nil
+}
+
+// ****************************************************************************
+// Escape()
+// Escape closes the focused dialog, its done function is not called
+// ****************************************************************************
+func Escape(f any) {
+	if active := focused(); active != nil {
+		active.close()
+	}
+}
+
+// ****************************************************************************
+// Validate()
+// Validate presses the first button (Yes or OK) of the focused dialog
+// ****************************************************************************
+func Validate(f any) {
+	active := focused()
+	if active == nil {
+		return
+	}
+	if len(active.actions) > 0 {
+		active.actions[0]()
+	} else if len(active.buttons) > 0 && active.buttons[0].GetLabel() == "Yes" {
+		active.doYes()
+	} else {
+		active.doOK()
+	}
+}
+
+// ****************************************************************************
+// doYes()
+// ****************************************************************************
+func (m *Dialog) doYes() {
+	m.close()
 	m.done(BUTTON_YES, m.idx)
 }
 
@@ -374,8 +635,7 @@ func (m *Dialog) doYes() {
 // doNo()
 // ****************************************************************************
 func (m *Dialog) doNo() {
-	ui.PgsApp.SwitchToPage(m.parent)
-	ui.App.SetFocus(m.focus)
+	m.close()
 	m.done(BUTTON_NO, m.idx)
 }
 
@@ -383,8 +643,7 @@ func (m *Dialog) doNo() {
 // doCancel()
 // ****************************************************************************
 func (m *Dialog) doCancel() {
-	ui.PgsApp.SwitchToPage(m.parent)
-	ui.App.SetFocus(m.focus)
+	m.close()
 	m.done(BUTTON_CANCEL, m.idx)
 }
 
@@ -392,15 +651,25 @@ func (m *Dialog) doCancel() {
 // doOK()
 // ****************************************************************************
 func (m *Dialog) doOK() {
-	ui.PgsApp.SwitchToPage(m.parent)
-	ui.App.SetFocus(m.focus)
+	m.close()
 	switch m.dtype {
-	case INPUT_TEXT:
+	case INPUT_TEXT, INPUT_PASSWORD:
 		m.Value = m.GetFormItem(1).(*tview.InputField).GetText()
 	case INPUT_LIST:
 		_, m.Value = m.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
 	case INPUT_FILE:
 		_, m.Value = m.GetFormItem(1).(*tview.DropDown).GetCurrentOption()
+	case INPUT_MULTILINE:
+		m.Value = m.GetFormItem(1).(*tview.TextArea).GetText()
+		m.Checked = make([]bool, len(m.Values))
+		for i := range m.Values {
+			m.Checked[i] = m.GetFormItem(2 + i).(*tview.Checkbox).IsChecked()
+		}
+	case INPUT_FILTER:
+		m.Value = ""
+		if i := m.results.GetCurrentItem(); i >= 0 && i < len(m.Values) {
+			m.Value = m.Values[i]
+		}
 	default:
 		m.Value = ""
 	}
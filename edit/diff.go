@@ -0,0 +1,147 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	diffOnClose func()
+)
+
+// ****************************************************************************
+// ShowDiff()
+// ShowDiff displays the differences between two texts in the diff screen,
+// onClose (if any) is called when the screen is left
+// ****************************************************************************
+func ShowDiff(title string, oldText string, newText string, onClose func()) {
+	ShowText(title, RenderDiff(utils.DiffLines(oldText, newText), 3), onClose)
+}
+
+// ****************************************************************************
+// ShowText()
+// ShowText displays an already colored text in the diff screen
+// ****************************************************************************
+func ShowText(title string, text string, onClose func()) {
+	diffOnClose = onClose
+	idx := ui.GetScreenFromTitle("Diff")
+	if idx == "NIL" {
+		ui.AddNewScreen(ui.ModeDiff, nil, nil)
+	} else {
+		i, _ := strconv.Atoi(idx)
+		ui.ShowScreen(i)
+	}
+	ui.TxtDiff.SetTitle(" " + title + " ")
+	ui.TxtDiff.SetText(text)
+	ui.TxtDiff.ScrollToBeginning()
+	ui.TxtDiff.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(diffKeys(), event)
+	})
+	ui.App.SetFocus(ui.TxtDiff)
+}
+
+// ****************************************************************************
+// diffKeys()
+// ****************************************************************************
+func diffKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Up", Label: "Scroll"},
+		{Chord: "Down", Label: "Scroll"},
+		{Chord: "Esc", Label: "Close", Done: closeDiff},
+	}
+}
+
+// ****************************************************************************
+// closeDiff()
+// ****************************************************************************
+func closeDiff() {
+	ShowEditorScreen()
+	if diffOnClose != nil {
+		f := diffOnClose
+		diffOnClose = nil
+		f()
+	}
+}
+
+// ****************************************************************************
+// RenderDiff()
+// RenderDiff formats a line diff as a colored unified diff with some context
+// ****************************************************************************
+func RenderDiff(lines []utils.DiffLine, context int) string {
+	var sb strings.Builder
+	changed := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.Op != utils.DIFF_EQUAL {
+			for j := i - context; j <= i+context; j++ {
+				if j >= 0 && j < len(lines) {
+					changed[j] = true
+				}
+			}
+		}
+	}
+	inHunk := false
+	for i, l := range lines {
+		if !changed[i] {
+			inHunk = false
+			continue
+		}
+		if !inHunk {
+			oldN, newN := hunkStart(lines, i)
+			sb.WriteString(fmt.Sprintf("[aqua]@@ -%d +%d @@[-]\n", oldN+1, newN+1))
+			inHunk = true
+		}
+		text := tview.Escape(l.Text)
+		switch l.Op {
+		case utils.DIFF_INSERT:
+			sb.WriteString("[green]+" + text + "[-]\n")
+		case utils.DIFF_DELETE:
+			sb.WriteString("[red]-" + text + "[-]\n")
+		default:
+			sb.WriteString(" " + text + "\n")
+		}
+	}
+	if sb.Len() == 0 {
+		return "[green]No differences[-]"
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// hunkStart()
+// ****************************************************************************
+func hunkStart(lines []utils.DiffLine, i int) (int, int) {
+	oldN, newN := 0, 0
+	for _, l := range lines[:i] {
+		if l.Op != utils.DIFF_INSERT {
+			oldN++
+		}
+		if l.Op != utils.DIFF_DELETE {
+			newN++
+		}
+	}
+	return oldN, newN
+}
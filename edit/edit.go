@@ -15,6 +15,8 @@ package edit
 // IMPORTS
 // ****************************************************************************
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"lied/conf"
@@ -23,14 +25,15 @@ import (
 	"lied/utils"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/pgavlin/femto"
 	"github.com/pgavlin/femto/runtime"
 	"github.com/rivo/tview"
-	"github.com/saintfish/chardet"
 )
 
 // ****************************************************************************
@@ -41,6 +44,13 @@ type editfile struct {
 	View          *femto.View
 	FName         string
 	Encoding      string
+	BOM           bool
+	LineEnding    string
+	MixedEOL      bool
+	Disk          diskState // As last loaded or saved
+	Seen          diskState // As last noticed by the watcher
+	Follow        bool
+	Top           int // First line shown, when not the current file
 	GitCommit     string
 	GitStatus     string
 	GitBranch     string
@@ -65,6 +75,7 @@ var (
 	currentFlow      int
 	showHidden       bool
 	CurrentWorkspace string
+	BackupMode       = conf.BACKUP_NONE
 )
 
 // ****************************************************************************
@@ -93,11 +104,52 @@ func SwitchToEditor(fName string) {
 	ui.App.SetFocus(ui.EdtMain)
 }
 
+// ****************************************************************************
+// ShowEditorScreen()
+// ShowEditorScreen brings the editor screen back to the front
+// ****************************************************************************
+func ShowEditorScreen() {
+	idx := ui.GetScreenFromTitle(conf.APP_NAME)
+	if idx == "NIL" {
+		SwitchToEditor(CurrentFile.FName)
+		return
+	}
+	i, _ := strconv.Atoi(idx)
+	ui.ShowScreen(i)
+	ui.App.SetFocus(ui.EdtMain)
+}
+
+// ****************************************************************************
+// GotoLine()
+// GotoLine moves the cursor of the current file to the given (0-based) location
+// ****************************************************************************
+func GotoLine(y int, x int) {
+	if CurrentFile.Buffer == nil {
+		return
+	}
+	if y >= CurrentFile.Buffer.NumLines {
+		y = CurrentFile.Buffer.NumLines - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x > utf8.RuneCountInString(CurrentFile.Buffer.Line(y)) {
+		x = utf8.RuneCountInString(CurrentFile.Buffer.Line(y))
+	}
+	if x < 0 {
+		x = 0
+	}
+	CurrentFile.Buffer.Cursor.ResetSelection()
+	CurrentFile.Buffer.Cursor.GotoLoc(femto.Loc{X: x, Y: y})
+	ui.EdtMain.Relocate()
+	ui.EdtMain.Center()
+}
+
 // ****************************************************************************
 // OpenWorkspace()
 // ****************************************************************************
 func OpenWorkspace() {
-	// DO IT
+	ChooseWorkspace(nil)
 }
 
 // ****************************************************************************
@@ -109,6 +161,7 @@ func SetTheme(theme string) {
 			var colorscheme femto.Colorscheme
 			colorscheme = femto.ParseColorscheme(string(data))
 			ui.EdtMain.SetColorscheme(colorscheme)
+			Colorscheme = colorscheme
 		}
 	}
 }
@@ -128,48 +181,190 @@ func OpenFile(fName string) {
 			ui.SetStatus(fmt.Sprintf("%v", err))
 		} else {
 			// dat, _ := os.ReadFile(fName)
-			detector := chardet.NewTextDetector()
-			result, err := detector.DetectBest(content)
-			if err == nil {
-				// fmt.Printf("Detected charset is %s", result.Charset)
-				// ui.LblScreen.SetText(result.Charset)
-				CurrentFile.Encoding = result.Charset
-			} else {
-				CurrentFile.Encoding = "Unknown"
+			CurrentFile.Encoding, CurrentFile.BOM = detectEncoding(content)
+			text, err := decodeContent(content, CurrentFile.Encoding, CurrentFile.BOM)
+			if err != nil {
+				ui.SetStatus(fmt.Sprintf("Could not decode %s as %s : %v", fName, CurrentFile.Encoding, err))
 			}
+			CurrentFile.LineEnding, CurrentFile.MixedEOL = detectLineEndings(text)
+			text = normalizeNewlines(text)
 
 			CurrentFile.FName = fName
-			CurrentFile.Buffer = femto.NewBufferFromString(string(content), CurrentFile.FName)
+			CurrentFile.Disk = newDiskState(fName, content)
+			CurrentFile.Seen = CurrentFile.Disk
+			CurrentFile.Follow = false
+			CurrentFile.Buffer = newBuffer(text, CurrentFile.FName)
+			RefreshGitBase(fName)
 			CurrentFile.View = femto.NewView(CurrentFile.Buffer)
 			ui.EdtMain.OpenBuffer(CurrentFile.Buffer)
 			SetTheme("monokai")
 			ui.EdtMain.SetTitleAlign(tview.AlignRight)
-			ui.LblScreen.SetText(CurrentFile.Encoding)
+			ui.LblEncoding.SetText(encodingLabel(CurrentFile))
+			ui.LblEOL.SetText(eolLabel(CurrentFile))
 			CurrentFile = UpdateGITInfos(CurrentFile)
 			OpenFiles = append(OpenFiles, CurrentFile)
-			go UpdateStatus()
+			addRecentFile(fName)
 			go focusOpenFile(fName)
 			ui.SetStatus(fmt.Sprintf("Opening file %s", CurrentFile.FName))
 			ui.TblOpenFiles.SetTitle(fmt.Sprintf("Open Files (%d)", len(OpenFiles)))
 			ui.App.SetFocus(ui.EdtMain)
+			if CurrentFile.MixedEOL {
+				warnMixedLineEndings()
+			}
 		}
 	}
-	ShowTreeDir(CurrentWorkspace, showHidden)
+	ShowTreeDir(explorerRoot(), showHidden)
+}
+
+// ****************************************************************************
+// OpenAnyFile()
+// ****************************************************************************
+func OpenAnyFile(fName any) {
+	OpenFile(fName.(string))
 }
 
 // ****************************************************************************
 // SaveFile()
 // ****************************************************************************
 func SaveFile() {
-	err := ioutil.WriteFile(CurrentFile.FName, []byte(CurrentFile.Buffer.String()), 0600)
-	if err == nil {
-		ui.SetStatus(fmt.Sprintf("File %s successfully saved", CurrentFile.FName))
-		CurrentFile.Buffer.IsModified = false
-	} else {
+	saveFile(CurrentFile, CurrentFile.FName, nil)
+}
+
+// ****************************************************************************
+// SaveAndCloseFile()
+// SaveAndCloseFile closes the current file once it is saved
+// ****************************************************************************
+func SaveAndCloseFile() {
+	saveFile(CurrentFile, CurrentFile.FName, func(ok bool) {
+		if ok {
+			CloseCurrentFile()
+		}
+	})
+}
+
+// ****************************************************************************
+// saveFile()
+// saveFile writes the open file f to fName, once the user agreed to overwrite
+// it if another process changed it on disk. Every save goes through here.
+// done, if any, is told whether the file has been written.
+// ****************************************************************************
+func saveFile(f editfile, fName string, done func(ok bool)) {
+	write := func(ok bool) {
+		if ok {
+			ok = writeSavedFile(f, fName)
+		} else {
+			ui.SetStatus(fmt.Sprintf("File %s not saved", fName))
+		}
+		if done != nil {
+			done(ok)
+		}
+	}
+	if isChangedOnDisk(fName) {
+		proposeToOverwrite(fName, write)
+		return
+	}
+	write(true)
+}
+
+// ****************************************************************************
+// writeSavedFile()
+// writeSavedFile writes f to fName and marks its buffer as saved
+// ****************************************************************************
+func writeSavedFile(f editfile, fName string) bool {
+	if err := writeFile(fName, f); err != nil {
 		ui.SetStatus(err.Error())
+		return false
+	}
+	ui.SetStatus(fmt.Sprintf("File %s successfully saved", fName))
+	f.Buffer.IsModified = false
+	RemoveSwap(f.FName)
+	return true
+}
+
+// ****************************************************************************
+// writeFile()
+// writeFile encodes the buffer of the file into its charset and writes it to fName
+// ****************************************************************************
+func writeFile(fName string, f editfile) error {
+	data, err := encodeContent(applyLineEnding(f.Buffer.String(), f.LineEnding), f.Encoding, f.BOM)
+	if err != nil {
+		return err
+	}
+	err = utils.AtomicWriteFile(fName, data, conf.NEW_FILE_PERM, backupFile)
+	if err == nil {
+		setDiskState(fName, data)
+		touchRepo(fName)
+		touchIndex(fName)
+	}
+	return err
+}
+
+// ****************************************************************************
+// backupFile()
+// backupFile keeps a copy of the file about to be overwritten, as per BackupMode
+// ****************************************************************************
+func backupFile(target string) error {
+	switch BackupMode {
+	case conf.BACKUP_TILDE:
+		err := utils.CopyFile(target, target+"~")
+		touchIndex(target + "~")
+		return err
+	case conf.BACKUP_TIMESTAMP:
+		userDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(userDir, conf.APP_FOLDER, conf.DIR_BACKUP)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		sum := sha256.Sum256([]byte(filepath.Dir(target)))
+		name := fmt.Sprintf("%s.%s.%s", filepath.Base(target), hex.EncodeToString(sum[:4]), time.Now().Format("20060102-150405"))
+		return utils.CopyFile(target, filepath.Join(dir, name))
+	}
+	return nil
+}
+
+// ****************************************************************************
+// syncCurrentFile()
+// syncCurrentFile copies the current file's properties back into OpenFiles
+// ****************************************************************************
+func syncCurrentFile() {
+	for i, e := range OpenFiles {
+		if e.FName == CurrentFile.FName {
+			OpenFiles[i].Buffer = CurrentFile.Buffer
+			OpenFiles[i].Encoding = CurrentFile.Encoding
+			OpenFiles[i].BOM = CurrentFile.BOM
+			OpenFiles[i].LineEnding = CurrentFile.LineEnding
+			OpenFiles[i].MixedEOL = CurrentFile.MixedEOL
+			OpenFiles[i].Follow = CurrentFile.Follow
+			break
+		}
 	}
 }
 
+// ****************************************************************************
+// newBuffer()
+// newBuffer creates a femto buffer whose dirty flag is reset by our saves
+// ****************************************************************************
+func newBuffer(text string, fName string) *femto.Buffer {
+	buf := femto.NewBufferFromString(text, fName)
+	buf.Settings["fastdirty"] = true
+	return buf
+}
+
+// ****************************************************************************
+// replaceBuffer()
+// replaceBuffer swaps the buffer of the current file for a freshly loaded one
+// ****************************************************************************
+func replaceBuffer(buf *femto.Buffer) {
+	loc := CurrentFile.Buffer.Cursor.Loc
+	CurrentFile.Buffer = buf
+	syncCurrentFile()
+	ui.EdtMain.OpenBuffer(buf)
+	GotoLine(loc.Y, loc.X)
+}
+
 // ****************************************************************************
 // SaveAnyFile()
 // ****************************************************************************
@@ -216,6 +411,10 @@ func SaveAnyFileAs(f any) {
 func NewFile(dir string) {
 	f, err := os.CreateTemp(dir, conf.NEW_FILE_TEMPLATE)
 	if err == nil {
+		// CreateTemp makes owner-only files, new files get the usual permissions
+		f.Chmod(conf.NEW_FILE_PERM)
+		f.Close()
+		touchIndex(f.Name())
 		SwitchToEditor(f.Name())
 	} else {
 		ui.SetStatus(err.Error())
@@ -245,7 +444,6 @@ func NewFileOrLastFile(dir string) {
 // ****************************************************************************
 func UpdateStatus() {
 	var status string
-	var count int = 0
 	for {
 		time.Sleep(100 * time.Millisecond)
 		ui.App.QueueUpdateDraw(func() {
@@ -260,7 +458,6 @@ func UpdateStatus() {
 			}
 			x := CurrentFile.Buffer.Cursor.X + 1
 			y := CurrentFile.Buffer.Cursor.Y + 1
-			CurrentFile = UpdateGITInfos(CurrentFile)
 			ui.LblGITBranch.SetText("⎇  " + CurrentFile.GitBranch)
 			ui.LblCommit.SetText("⟟ " + CurrentFile.GitCommit)
 			ui.LblGITStatus.SetText("🗨  " + CurrentFile.GitStatus)
@@ -268,14 +465,10 @@ func UpdateStatus() {
 			ui.LblCursor.SetText(fmt.Sprintf("Ln %d, Col %d", y, x))
 			ui.LblPercent.SetText(fmt.Sprintf("%d%%", int((float32(CurrentFile.Buffer.Cursor.Y)/float32(CurrentFile.Buffer.NumLines))*100.0)))
 			ui.TblOpenFiles.Clear()
-			count++
 			for i, f := range OpenFiles {
-				if count%10 == 0 {
-					// Update GIT infos only 1 call upon 10
-					f = UpdateGITInfos(f)
-					OpenFiles[i] = f
-				}
-				if f.Buffer.Modified() {
+				if isUnmerged(f.GitFileStatus) {
+					ui.TblOpenFiles.SetCell(i, 0, tview.NewTableCell(conf.ICON_CONFLICT+f.GitFileStatus).SetTextColor(tcell.ColorRed))
+				} else if f.Buffer.Modified() {
 					ui.TblOpenFiles.SetCell(i, 0, tview.NewTableCell(conf.ICON_MODIFIED+f.GitFileStatus))
 				} else {
 					ui.TblOpenFiles.SetCell(i, 0, tview.NewTableCell(" "+f.GitFileStatus))
@@ -288,65 +481,42 @@ func UpdateStatus() {
 	}
 }
 
-// ****************************************************************************
-// UpdateGITInfos()
-// ****************************************************************************
-func UpdateGITInfos(f editfile) editfile {
-	ws := filepath.Dir(f.FName)
-	// Get GIT Commit
-	commit, err2 := utils.Xeq(ws, "git", "rev-parse", "--short", "HEAD")
-	if err2 != "" {
-		commit = "No GIT"
-	}
-	f.GitCommit = commit
-	// Get GIT Status
-	status, _ := utils.Xeq(ws, "git", "status", "-s")
-	if status != "" {
-		status = "Pending Commit"
-	} else {
-		status = "Up to date"
-	}
-	f.GitStatus = status
-	// Get GIT Branch
-	branch, err3 := utils.Xeq(ws, "git", "rev-parse", "--abbrev-ref", "HEAD")
-	if err3 != "" {
-		branch = "Unknown"
-	}
-	f.GitBranch = branch
-	// Get GIT File Status
-	fstatus, _ := utils.Xeq(ws, "git", "status", "-s", f.FName)
-	if fstatus != "" {
-		fstatus = fstatus[0:2]
-	} else {
-		fstatus = "  "
-	}
-	f.GitFileStatus = fstatus
-
-	return f
-}
-
 // ****************************************************************************
 // SwitchOpenFile()
 // ****************************************************************************
 func SwitchOpenFile(fName string) {
 	CurrentWorkspace = filepath.Dir(fName)
+	for i := range OpenFiles {
+		if OpenFiles[i].FName == CurrentFile.FName {
+			OpenFiles[i].Top = ui.EdtMain.Topline
+		}
+	}
 	for _, e := range OpenFiles {
 		if e.FName == fName {
 			CurrentFile.FName = e.FName
 			CurrentFile.Buffer = e.Buffer
 			CurrentFile.Encoding = e.Encoding
+			CurrentFile.BOM = e.BOM
+			CurrentFile.LineEnding = e.LineEnding
+			CurrentFile.MixedEOL = e.MixedEOL
+			CurrentFile.Follow = e.Follow
 			CurrentFile.GitCommit = e.GitCommit
 			CurrentFile.GitStatus = e.GitStatus
 			CurrentFile.GitBranch = e.GitBranch
+			CurrentFile.GitFileStatus = e.GitFileStatus
 			ui.EdtMain.OpenBuffer(CurrentFile.Buffer)
-			ui.LblEncoding.SetText(CurrentFile.Encoding)
+			if e.Top > 0 {
+				ui.EdtMain.Topline = e.Top
+			}
+			ui.LblEncoding.SetText(encodingLabel(CurrentFile))
+			ui.LblEOL.SetText(eolLabel(CurrentFile))
 			// FocusOnPath(fName)
 			ui.SetStatus(fmt.Sprintf("Switching to %s", CurrentFile.FName))
 			go focusOpenFile(fName)
 			break
 		}
 	}
-	ShowTreeDir(CurrentWorkspace, showHidden)
+	ShowTreeDir(explorerRoot(), showHidden)
 }
 
 // ****************************************************************************
@@ -447,21 +617,17 @@ func proposeToSaveFile(idx int, flow int) {
 // confirmSave()
 // ****************************************************************************
 func confirmSave(rc dialog.DlgButton, idx int) {
+	flow := currentFlow
 	if rc == dialog.BUTTON_YES {
-		err := ioutil.WriteFile(OpenFiles[idx].FName, []byte(OpenFiles[idx].Buffer.String()), 0600)
-		if err == nil {
-			ui.SetStatus(fmt.Sprintf("File %s successfully saved", OpenFiles[idx].FName))
-			OpenFiles[idx].Buffer.IsModified = false
-			if currentFlow == FLOW_CLOSE {
+		saveFile(OpenFiles[idx], OpenFiles[idx].FName, func(ok bool) {
+			if ok && flow == FLOW_CLOSE {
 				CloseCurrentFile()
 			}
-		} else {
-			ui.SetStatus(err.Error())
-		}
+		})
 	}
 	if rc == dialog.BUTTON_NO {
 		OpenFiles[idx].Buffer.IsModified = false
-		if currentFlow == FLOW_CLOSE {
+		if flow == FLOW_CLOSE {
 			CloseCurrentFile()
 		}
 	}
@@ -473,27 +639,14 @@ func confirmSave(rc dialog.DlgButton, idx int) {
 // ****************************************************************************
 func confirmSaveAs(rc dialog.DlgButton, idx int) {
 	if rc == dialog.BUTTON_OK {
-		newName := DlgSaveFileAs.Value
-		err := ioutil.WriteFile(newName, []byte(CurrentFile.Buffer.String()), 0600)
-		if err == nil {
-			ui.SetStatus(fmt.Sprintf("File %s successfully saved", CurrentFile.FName))
-			CurrentFile.Buffer.IsModified = false
-			if currentFlow == FLOW_CLOSE {
-				CloseCurrentFile()
-			} else {
-				var n = -1
-				for i, f := range OpenFiles {
-					if f.FName == CurrentFile.FName {
-						n = i
-						break
-					}
+		if currentFlow == FLOW_CLOSE {
+			saveFile(CurrentFile, DlgSaveFileAs.Value, func(ok bool) {
+				if ok {
+					CloseCurrentFile()
 				}
-				copy(OpenFiles[n:], OpenFiles[n+1:])
-				OpenFiles = OpenFiles[:len(OpenFiles)-1]
-				OpenFile(newName)
-			}
+			})
 		} else {
-			ui.SetStatus(err.Error())
+			SaveFileTo(DlgSaveFileAs.Value)
 		}
 	}
 	if rc == dialog.BUTTON_CANCEL {
@@ -505,6 +658,32 @@ func confirmSaveAs(rc dialog.DlgButton, idx int) {
 	currentFlow = FLOW_NONE
 }
 
+// ****************************************************************************
+// SaveFileTo()
+// SaveFileTo saves the current file under a new name and goes on editing it
+// under this name
+// ****************************************************************************
+func SaveFileTo(newName string) {
+	oldName := CurrentFile.FName
+	saveFile(CurrentFile, newName, func(ok bool) {
+		if !ok {
+			return
+		}
+		var n = -1
+		for i, f := range OpenFiles {
+			if f.FName == oldName {
+				n = i
+				break
+			}
+		}
+		if n >= 0 {
+			copy(OpenFiles[n:], OpenFiles[n+1:])
+			OpenFiles = OpenFiles[:len(OpenFiles)-1]
+		}
+		OpenFile(newName)
+	})
+}
+
 // ****************************************************************************
 // CheckOpenFilesForSaving()
 // ****************************************************************************
@@ -534,6 +713,7 @@ func CloseCurrentFile() {
 		if CurrentFile.Buffer.IsModified {
 			proposeToSaveFile(n, FLOW_CLOSE)
 		} else {
+			RemoveSwap(CurrentFile.FName)
 			copy(OpenFiles[n:], OpenFiles[n+1:])
 			OpenFiles = OpenFiles[:len(OpenFiles)-1]
 			if n > 0 {
@@ -600,6 +780,8 @@ func ShowTreeDir(rootDir string, sh bool) {
 
 	// Add the current directory to the root node.
 	addDirToNode(root, rootDir, showHidden)
+	restoreExpanded(root)
+	requestIndex(rootDir, showHidden)
 
 	// If a directory was selected, open it.
 	ui.TrvExplorer.SetSelectedFunc(selectNode)
@@ -618,9 +800,29 @@ func selectNode(node *tview.TreeNode) {
 		// Load and show files in this directory.
 		path := reference.(string)
 		addDirToNode(node, path, showHidden)
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			expandedDirs[path] = true
+		}
 	} else {
 		// Collapse if visible, expand if collapsed.
 		node.SetExpanded(!node.IsExpanded())
+		expandedDirs[reference.(string)] = node.IsExpanded()
+	}
+}
+
+// ****************************************************************************
+// restoreExpanded()
+// restoreExpanded reloads the directories which were expanded into the explorer
+// ****************************************************************************
+func restoreExpanded(node *tview.TreeNode) {
+	for _, child := range node.GetChildren() {
+		path, ok := child.GetReference().(string)
+		if ok && expandedDirs[path] {
+			if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+				addDirToNode(child, path, showHidden)
+				restoreExpanded(child)
+			}
+		}
 	}
 }
 
@@ -0,0 +1,310 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"lied/dialog"
+	"lied/ui"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	// EncodingNames lists the charsets offered by the encoding menus
+	EncodingNames = []string{
+		"UTF-8",
+		"UTF-16LE",
+		"UTF-16BE",
+		"UTF-32LE",
+		"UTF-32BE",
+		"ISO-8859-1",
+		"ISO-8859-2",
+		"ISO-8859-5",
+		"ISO-8859-6",
+		"ISO-8859-7",
+		"ISO-8859-8",
+		"ISO-8859-9",
+		"ISO-8859-15",
+		"windows-1250",
+		"windows-1251",
+		"windows-1252",
+		"windows-1256",
+		"KOI8-R",
+		"Shift_JIS",
+		"EUC-JP",
+		"ISO-2022-JP",
+		"EUC-KR",
+		"GB-18030",
+		"Big5",
+	}
+	charsets = map[string]encoding.Encoding{
+		"UTF-8":        unicode.UTF8,
+		"UTF-16LE":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+		"UTF-16BE":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+		"UTF-32LE":     utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM),
+		"UTF-32BE":     utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM),
+		"ISO-8859-1":   charmap.ISO8859_1,
+		"ISO-8859-2":   charmap.ISO8859_2,
+		"ISO-8859-5":   charmap.ISO8859_5,
+		"ISO-8859-6":   charmap.ISO8859_6,
+		"ISO-8859-7":   charmap.ISO8859_7,
+		"ISO-8859-8":   charmap.ISO8859_8,
+		"ISO-8859-9":   charmap.ISO8859_9,
+		"ISO-8859-15":  charmap.ISO8859_15,
+		"windows-1250": charmap.Windows1250,
+		"windows-1251": charmap.Windows1251,
+		"windows-1252": charmap.Windows1252,
+		"windows-1256": charmap.Windows1256,
+		"KOI8-R":       charmap.KOI8R,
+		"Shift_JIS":    japanese.ShiftJIS,
+		"EUC-JP":       japanese.EUCJP,
+		"ISO-2022-JP":  japanese.ISO2022JP,
+		"EUC-KR":       korean.EUCKR,
+		"GB-18030":     simplifiedchinese.GB18030,
+		"Big5":         traditionalchinese.Big5,
+	}
+	boms = []struct {
+		charset string
+		bom     []byte
+	}{
+		// UTF-32LE must be tested before UTF-16LE, they share the first bytes
+		{"UTF-32LE", []byte{0xFF, 0xFE, 0x00, 0x00}},
+		{"UTF-32BE", []byte{0x00, 0x00, 0xFE, 0xFF}},
+		{"UTF-8", []byte{0xEF, 0xBB, 0xBF}},
+		{"UTF-16LE", []byte{0xFF, 0xFE}},
+		{"UTF-16BE", []byte{0xFE, 0xFF}},
+	}
+	DlgEncoding *dialog.Dialog
+)
+
+// ****************************************************************************
+// getCharset()
+// ****************************************************************************
+func getCharset(name string) (encoding.Encoding, error) {
+	if enc, ok := charsets[name]; ok {
+		return enc, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err == nil && enc == nil {
+		err = fmt.Errorf("unsupported encoding %s", name)
+	}
+	return enc, err
+}
+
+// ****************************************************************************
+// bomFor()
+// ****************************************************************************
+func bomFor(charset string) []byte {
+	for _, b := range boms {
+		if b.charset == charset {
+			return b.bom
+		}
+	}
+	return nil
+}
+
+// ****************************************************************************
+// detectEncoding()
+// detectEncoding returns the charset of the content and whether it starts with a BOM
+// ****************************************************************************
+func detectEncoding(content []byte) (string, bool) {
+	for _, b := range boms {
+		if bytes.HasPrefix(content, b.bom) {
+			return b.charset, true
+		}
+	}
+	if utf8.Valid(content) {
+		return "UTF-8", false
+	}
+	detector := chardet.NewTextDetector()
+	result, err := detector.DetectBest(content)
+	if err != nil {
+		return "UTF-8", false
+	}
+	return result.Charset, false
+}
+
+// ****************************************************************************
+// decodeContent()
+// decodeContent converts the raw file content into UTF-8, dropping the BOM
+// ****************************************************************************
+func decodeContent(content []byte, charset string, bom bool) (string, error) {
+	if bom {
+		content = content[len(bomFor(charset)):]
+	}
+	if charset == "UTF-8" {
+		return string(content), nil
+	}
+	enc, err := getCharset(charset)
+	if err != nil {
+		return string(content), err
+	}
+	text, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return string(content), err
+	}
+	return string(text), nil
+}
+
+// ****************************************************************************
+// encodeContent()
+// encodeContent converts the UTF-8 text back into the charset, with its BOM
+// ****************************************************************************
+func encodeContent(text string, charset string, bom bool) ([]byte, error) {
+	var out []byte
+	if bom {
+		out = append(out, bomFor(charset)...)
+	}
+	if charset == "UTF-8" || charset == "" || charset == "Unknown" {
+		return append(out, text...), nil
+	}
+	enc, err := getCharset(charset)
+	if err != nil {
+		return nil, err
+	}
+	data, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("some characters can't be encoded in %s, use \"Save with encoding…\"", charset)
+	}
+	return append(out, data...), nil
+}
+
+// ****************************************************************************
+// encodingLabel()
+// ****************************************************************************
+func encodingLabel(f editfile) string {
+	if f.BOM {
+		return f.Encoding + " BOM"
+	}
+	return f.Encoding
+}
+
+// ****************************************************************************
+// ReopenWithEncoding()
+// ****************************************************************************
+func ReopenWithEncoding(f any) {
+	DlgEncoding = DlgEncoding.List("Reopen with encoding…", // Title
+		"Unsaved changes will be lost. Reopen this file as :", // Message
+		EncodingNames,
+		doReopenWithEncoding,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgEncoding", DlgEncoding.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgEncoding")
+}
+
+// ****************************************************************************
+// doReopenWithEncoding()
+// ****************************************************************************
+func doReopenWithEncoding(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	charset := DlgEncoding.Value
+	content, err := ioutil.ReadFile(CurrentFile.FName)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	bom := bytes.HasPrefix(content, bomFor(charset)) && bomFor(charset) != nil
+	text, err := decodeContent(content, charset, bom)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	CurrentFile.Encoding = charset
+	CurrentFile.BOM = bom
+	CurrentFile.LineEnding, CurrentFile.MixedEOL = detectLineEndings(text)
+	replaceBuffer(newBuffer(normalizeNewlines(text), CurrentFile.FName))
+	ui.LblEOL.SetText(eolLabel(CurrentFile))
+	ui.LblEncoding.SetText(encodingLabel(CurrentFile))
+	ui.SetStatus(fmt.Sprintf("File %s reopened as %s", CurrentFile.FName, charset))
+}
+
+// ****************************************************************************
+// SaveWithEncoding()
+// ****************************************************************************
+func SaveWithEncoding(f any) {
+	DlgEncoding = DlgEncoding.List("Save with encoding…", // Title
+		"Please, choose the encoding to save this file with :", // Message
+		EncodingNames,
+		doSaveWithEncoding,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgEncoding", DlgEncoding.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgEncoding")
+}
+
+// ****************************************************************************
+// doSaveWithEncoding()
+// ****************************************************************************
+func doSaveWithEncoding(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	fName, encoding, bom := CurrentFile.FName, CurrentFile.Encoding, CurrentFile.BOM
+	CurrentFile.Encoding = DlgEncoding.Value
+	// Keep a BOM only for the Unicode charsets which had one
+	CurrentFile.BOM = CurrentFile.BOM && bomFor(CurrentFile.Encoding) != nil
+	syncCurrentFile()
+	ui.LblEncoding.SetText(encodingLabel(CurrentFile))
+	saveFile(CurrentFile, fName, func(ok bool) {
+		if ok || CurrentFile.FName != fName {
+			return
+		}
+		// Not written, the file is still in its former charset
+		CurrentFile.Encoding, CurrentFile.BOM = encoding, bom
+		syncCurrentFile()
+		ui.LblEncoding.SetText(encodingLabel(CurrentFile))
+	})
+}
+
+// ****************************************************************************
+// ReadText()
+// ReadText loads a file the way OpenFile does : decoded and with LF endings
+// ****************************************************************************
+func ReadText(fName string) (string, error) {
+	content, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return "", err
+	}
+	return decodeText(content)
+}
+
+// ****************************************************************************
+// decodeText()
+// decodeText decodes content the way OpenFile does : detected and with LF endings
+// ****************************************************************************
+func decodeText(content []byte) (string, error) {
+	charset, bom := detectEncoding(content)
+	text, err := decodeContent(content, charset, bom)
+	return normalizeNewlines(text), err
+}
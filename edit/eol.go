@@ -0,0 +1,149 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/dialog"
+	"lied/ui"
+	"strings"
+)
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	EOL_LF   = "LF"
+	EOL_CRLF = "CRLF"
+	EOL_CR   = "CR"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	DlgMixedEOL *dialog.Dialog
+)
+
+// ****************************************************************************
+// detectLineEndings()
+// detectLineEndings returns the dominant line ending of the text and whether
+// several kinds are mixed
+// ****************************************************************************
+func detectLineEndings(text string) (string, bool) {
+	lf, crlf, cr := 0, 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		case '\n':
+			lf++
+		}
+	}
+	kinds := 0
+	for _, n := range []int{lf, crlf, cr} {
+		if n > 0 {
+			kinds++
+		}
+	}
+	eol := EOL_LF
+	if crlf > lf && crlf >= cr {
+		eol = EOL_CRLF
+	} else if cr > lf && cr > crlf {
+		eol = EOL_CR
+	}
+	return eol, kinds > 1
+}
+
+// ****************************************************************************
+// normalizeNewlines()
+// normalizeNewlines turns every line ending into LF, as femto expects
+// ****************************************************************************
+func normalizeNewlines(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// ****************************************************************************
+// applyLineEnding()
+// ****************************************************************************
+func applyLineEnding(text string, eol string) string {
+	switch eol {
+	case EOL_CRLF:
+		return strings.ReplaceAll(text, "\n", "\r\n")
+	case EOL_CR:
+		return strings.ReplaceAll(text, "\n", "\r")
+	}
+	return text
+}
+
+// ****************************************************************************
+// eolLabel()
+// ****************************************************************************
+func eolLabel(f editfile) string {
+	if f.MixedEOL {
+		return f.LineEnding + "!"
+	}
+	return f.LineEnding
+}
+
+// ****************************************************************************
+// SetLineEnding()
+// SetLineEnding converts the current file to the given line ending
+// ****************************************************************************
+func SetLineEnding(eol any) {
+	CurrentFile.LineEnding = eol.(string)
+	CurrentFile.MixedEOL = false
+	CurrentFile.Buffer.IsModified = true
+	syncCurrentFile()
+	ui.LblEOL.SetText(eolLabel(CurrentFile))
+	ui.SetStatus(fmt.Sprintf("Line endings of %s set to %s", CurrentFile.FName, CurrentFile.LineEnding))
+}
+
+// ****************************************************************************
+// warnMixedLineEndings()
+// ****************************************************************************
+func warnMixedLineEndings() {
+	msg := fmt.Sprintf("%s has mixed line endings.", CurrentFile.FName)
+	if len(ui.ArrScreens) == 0 {
+		// The UI is not ready yet (files reopened at startup)
+		ui.SetStatus(msg + " Use the menu to normalise them.")
+		return
+	}
+	DlgMixedEOL = DlgMixedEOL.YesNo("Mixed line endings", // Title
+		fmt.Sprintf("This file has mixed line endings, saving it turns them all into %s. Normalise them now ?", CurrentFile.LineEnding), // Message
+		confirmNormalize,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgMixedEOL", DlgMixedEOL.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgMixedEOL")
+}
+
+// ****************************************************************************
+// confirmNormalize()
+// ****************************************************************************
+func confirmNormalize(rc dialog.DlgButton, idx int) {
+	if rc == dialog.BUTTON_YES {
+		SetLineEnding(CurrentFile.LineEnding)
+	} else {
+		// femto only knows LF, the endings of each line can not survive a save
+		ui.SetStatus(fmt.Sprintf("Line endings left as they are on disk until %s is saved with %s", CurrentFile.FName, CurrentFile.LineEnding))
+	}
+}
@@ -0,0 +1,492 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/ui"
+	"lied/utils"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type SearchOptions struct {
+	Regex         bool
+	CaseSensitive bool
+	WholeWord     bool
+}
+
+type findMatch struct {
+	Y     int
+	X1    int
+	X2    int
+	line  string
+	index []int
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	FrmFind     *tview.Form
+	FindOptions SearchOptions
+	findText    string
+	replaceText string
+	findRegexp  *regexp.Regexp
+	findVisible bool
+)
+
+// ****************************************************************************
+// ShowFindBar()
+// ****************************************************************************
+func ShowFindBar(f any) {
+	if CurrentFile.Buffer == nil {
+		return
+	}
+	if CurrentFile.Buffer.Cursor.HasSelection() {
+		sel := CurrentFile.Buffer.Cursor.GetSelection()
+		if sel != "" && utf8.RuneCountInString(sel) < 256 && !containsNewline(sel) {
+			findText = sel
+		}
+	}
+	FrmFind = tview.NewForm().SetHorizontal(true)
+	FrmFind.SetBorder(true)
+	FrmFind.SetBorderPadding(0, 0, 1, 1)
+	FrmFind.SetTitleAlign(tview.AlignLeft)
+	FrmFind.AddInputField("Find", findText, 30, nil, func(text string) {
+		findText = text
+		compileFind()
+	})
+	FrmFind.AddInputField("Replace", replaceText, 30, nil, func(text string) {
+		replaceText = text
+	})
+	FrmFind.AddCheckbox("Regex", FindOptions.Regex, func(checked bool) {
+		FindOptions.Regex = checked
+		compileFind()
+	})
+	FrmFind.AddCheckbox("Case", FindOptions.CaseSensitive, func(checked bool) {
+		FindOptions.CaseSensitive = checked
+		compileFind()
+	})
+	FrmFind.AddCheckbox("Word", FindOptions.WholeWord, func(checked bool) {
+		FindOptions.WholeWord = checked
+		compileFind()
+	})
+	FrmFind.AddButton("Prev", FindPrevious)
+	FrmFind.AddButton("Next", FindNext)
+	FrmFind.AddButton("Replace", ReplaceCurrent)
+	FrmFind.AddButton("All", ReplaceAll)
+	FrmFind.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		item, _ := FrmFind.GetFocusedItemIndex()
+		if event.Modifiers()&tcell.ModAlt != 0 && event.Key() == tcell.KeyRune {
+			switch event.Rune() {
+			case 'r', 'R':
+				toggleFindCheckbox(2)
+				return nil
+			case 'c', 'C':
+				toggleFindCheckbox(3)
+				return nil
+			case 'w', 'W':
+				toggleFindCheckbox(4)
+				return nil
+			case 'a', 'A':
+				ReplaceAll()
+				return nil
+			}
+		}
+		switch event.Key() {
+		case tcell.KeyEsc:
+			HideFindBar()
+			return nil
+		case tcell.KeyUp:
+			FindPrevious()
+			return nil
+		case tcell.KeyDown:
+			FindNext()
+			return nil
+		case tcell.KeyEnter:
+			if item == 0 {
+				FindNext()
+				return nil
+			}
+			if item == 1 {
+				ReplaceCurrent()
+				return nil
+			}
+		}
+		return event
+	})
+	if !findVisible {
+		ui.FlxEditPane.AddItem(FrmFind, 4, 0, true)
+		findVisible = true
+	}
+	compileFind()
+	ui.SetDecorator("find", findDecorator)
+	ui.App.SetFocus(FrmFind)
+}
+
+// ****************************************************************************
+// HideFindBar()
+// ****************************************************************************
+func HideFindBar() {
+	if findVisible {
+		ui.FlxEditPane.RemoveItem(FrmFind)
+		findVisible = false
+	}
+	ui.SetDecorator("find", nil)
+	ui.App.SetFocus(ui.EdtMain)
+}
+
+// ****************************************************************************
+// toggleFindCheckbox()
+// ****************************************************************************
+func toggleFindCheckbox(idx int) {
+	chk := FrmFind.GetFormItem(idx).(*tview.Checkbox)
+	chk.SetChecked(!chk.IsChecked())
+	switch idx {
+	case 2:
+		FindOptions.Regex = chk.IsChecked()
+	case 3:
+		FindOptions.CaseSensitive = chk.IsChecked()
+	case 4:
+		FindOptions.WholeWord = chk.IsChecked()
+	}
+	compileFind()
+}
+
+// ****************************************************************************
+// CompileSearch()
+// CompileSearch turns a search string and its options into a regexp
+// ****************************************************************************
+func CompileSearch(pattern string, opt SearchOptions) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty search")
+	}
+	if !opt.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !opt.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// ****************************************************************************
+// compileFind()
+// ****************************************************************************
+func compileFind() {
+	var err error
+	findRegexp = nil
+	if findText == "" {
+		setFindTitle("")
+		return
+	}
+	findRegexp, err = CompileSearch(findText, FindOptions)
+	if err != nil {
+		setFindTitle("[red]" + err.Error())
+		return
+	}
+	setFindTitle(fmt.Sprintf("%d match(es)", len(findAll(CurrentFile.Buffer))))
+}
+
+// ****************************************************************************
+// setFindTitle()
+// ****************************************************************************
+func setFindTitle(msg string) {
+	if FrmFind == nil {
+		return
+	}
+	title := " Find & Replace [Enter/↓]Next [↑]Prev [Alt+R]egex [Alt+C]ase [Alt+W]ord [Alt+A]ll "
+	if msg != "" {
+		title = title + "─ " + msg + " "
+	}
+	FrmFind.SetTitle(title)
+}
+
+// ****************************************************************************
+// findInLine()
+// ****************************************************************************
+func findInLine(re *regexp.Regexp, y int, line string, opt SearchOptions) []findMatch {
+	var matches []findMatch
+	for _, idx := range re.FindAllStringSubmatchIndex(line, -1) {
+		if idx[0] == idx[1] {
+			continue
+		}
+		if opt.WholeWord && !isWholeWord(line, idx[0], idx[1]) {
+			continue
+		}
+		matches = append(matches, findMatch{
+			Y:     y,
+			X1:    utf8.RuneCountInString(line[:idx[0]]),
+			X2:    utf8.RuneCountInString(line[:idx[1]]),
+			line:  line,
+			index: idx,
+		})
+	}
+	return matches
+}
+
+// ****************************************************************************
+// findAll()
+// ****************************************************************************
+func findAll(buf *femto.Buffer) []findMatch {
+	var matches []findMatch
+	if findRegexp == nil || buf == nil {
+		return matches
+	}
+	for y := 0; y < buf.NumLines; y++ {
+		matches = append(matches, findInLine(findRegexp, y, buf.Line(y), FindOptions)...)
+	}
+	return matches
+}
+
+// ****************************************************************************
+// isWholeWord()
+// ****************************************************************************
+func isWholeWord(line string, start int, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(line[:start])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(line) {
+		r, _ := utf8.DecodeRuneInString(line[end:])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ****************************************************************************
+// isWordRune()
+// ****************************************************************************
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ****************************************************************************
+// containsNewline()
+// ****************************************************************************
+func containsNewline(s string) bool {
+	for _, r := range s {
+		if r == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+// ****************************************************************************
+// selectMatch()
+// ****************************************************************************
+func selectMatch(m findMatch) {
+	c := &CurrentFile.Buffer.Cursor
+	c.SetSelectionStart(femto.Loc{X: m.X1, Y: m.Y})
+	c.SetSelectionEnd(femto.Loc{X: m.X2, Y: m.Y})
+	c.GotoLoc(femto.Loc{X: m.X2, Y: m.Y})
+	ui.EdtMain.Relocate()
+}
+
+// ****************************************************************************
+// FindNext()
+// ****************************************************************************
+func FindNext() {
+	matches := findAll(CurrentFile.Buffer)
+	if len(matches) == 0 {
+		ui.SetStatus(fmt.Sprintf("'%s' not found", findText))
+		return
+	}
+	from := CurrentFile.Buffer.Cursor.Loc
+	for i, m := range matches {
+		if (femto.Loc{X: m.X1, Y: m.Y}).GreaterEqual(from) {
+			selectMatch(m)
+			setFindTitle(fmt.Sprintf("%d/%d", i+1, len(matches)))
+			return
+		}
+	}
+	selectMatch(matches[0])
+	setFindTitle(fmt.Sprintf("1/%d (wrapped)", len(matches)))
+}
+
+// ****************************************************************************
+// FindPrevious()
+// ****************************************************************************
+func FindPrevious() {
+	matches := findAll(CurrentFile.Buffer)
+	if len(matches) == 0 {
+		ui.SetStatus(fmt.Sprintf("'%s' not found", findText))
+		return
+	}
+	from := CurrentFile.Buffer.Cursor.Loc
+	if CurrentFile.Buffer.Cursor.HasSelection() {
+		from = CurrentFile.Buffer.Cursor.CurSelection[0]
+	}
+	for i := len(matches) - 1; i >= 0; i-- {
+		if (femto.Loc{X: matches[i].X1, Y: matches[i].Y}).LessThan(from) {
+			selectMatch(matches[i])
+			setFindTitle(fmt.Sprintf("%d/%d", i+1, len(matches)))
+			return
+		}
+	}
+	selectMatch(matches[len(matches)-1])
+	setFindTitle(fmt.Sprintf("%d/%d (wrapped)", len(matches), len(matches)))
+}
+
+// ****************************************************************************
+// expandReplacement()
+// ****************************************************************************
+func expandReplacement(m findMatch) string {
+	if !FindOptions.Regex {
+		return replaceText
+	}
+	return string(findRegexp.ExpandString(nil, replaceText, m.line, m.index))
+}
+
+// ****************************************************************************
+// ReplaceCurrent()
+// ReplaceCurrent replaces the selected match, if any, and jumps to the next one
+// ****************************************************************************
+func ReplaceCurrent() {
+	buf := CurrentFile.Buffer
+	if findRegexp == nil || buf == nil {
+		return
+	}
+	c := &buf.Cursor
+	if c.HasSelection() && c.CurSelection[0].Y == c.CurSelection[1].Y {
+		y := c.CurSelection[0].Y
+		for _, m := range findInLine(findRegexp, y, buf.Line(y), FindOptions) {
+			if m.X1 == c.CurSelection[0].X && m.X2 == c.CurSelection[1].X {
+				text := expandReplacement(m)
+				start := femto.Loc{X: m.X1, Y: m.Y}
+				c.ResetSelection()
+				buf.Replace(start, femto.Loc{X: m.X2, Y: m.Y}, text)
+				c.GotoLoc(start.Move(femto.Count(text), buf))
+				break
+			}
+		}
+	}
+	FindNext()
+	compileFind()
+}
+
+// ****************************************************************************
+// ReplaceAll()
+// ReplaceAll replaces every match as a single undoable operation
+// ****************************************************************************
+func ReplaceAll() {
+	buf := CurrentFile.Buffer
+	matches := findAll(buf)
+	if len(matches) == 0 {
+		ui.SetStatus(fmt.Sprintf("'%s' not found", findText))
+		return
+	}
+	buf.Cursor.ResetSelection()
+	before := buf.UndoStack.Len()
+	// Replace from the end so that the remaining locations stay valid
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		buf.Replace(femto.Loc{X: m.X1, Y: m.Y}, femto.Loc{X: m.X2, Y: m.Y}, expandReplacement(m))
+	}
+	groupUndo(buf, buf.UndoStack.Len()-before)
+	buf.Cursor.Relocate()
+	compileFind()
+	ui.SetStatus(fmt.Sprintf("%d occurrence(s) replaced", len(matches)))
+}
+
+// ****************************************************************************
+// Substitute()
+// Substitute replaces the matches of re by rep into the lines first to last,
+// only the first match of each line unless global is set. The replacement
+// expands $1 and ${name} like regexp.Expand. It returns the number of matches
+// replaced, which are undone in one go.
+// ****************************************************************************
+func Substitute(re *regexp.Regexp, rep string, first int, last int, global bool) int {
+	buf := CurrentFile.Buffer
+	if buf == nil {
+		return 0
+	}
+	if last >= buf.NumLines {
+		last = buf.NumLines - 1
+	}
+	n := 0
+	buf.Cursor.ResetSelection()
+	before := buf.UndoStack.Len()
+	// Replace from the end so that the remaining lines keep their number
+	for y := last; y >= first; y-- {
+		line := buf.Line(y)
+		matches := re.FindAllStringSubmatchIndex(line, utils.If(global, -1, 1))
+		if len(matches) == 0 {
+			continue
+		}
+		var sb strings.Builder
+		done := 0
+		for _, loc := range matches {
+			sb.WriteString(line[done:loc[0]])
+			sb.Write(re.ExpandString(nil, rep, line, loc))
+			done = loc[1]
+		}
+		n += len(matches)
+		sb.WriteString(line[done:])
+		if sb.String() != line {
+			replaceLines(buf, y, y+1, []string{sb.String()})
+		}
+	}
+	groupUndo(buf, buf.UndoStack.Len()-before)
+	buf.Cursor.Relocate()
+	return n
+}
+
+// ****************************************************************************
+// groupUndo()
+// groupUndo stamps the n last undo events with the same time so that femto
+// undoes (and redoes) them in one go
+// ****************************************************************************
+func groupUndo(buf *femto.Buffer, n int) {
+	now := time.Now()
+	e := buf.UndoStack.Top
+	for i := 0; i < n && e != nil; i++ {
+		e.Value.Time = now
+		e = e.Next
+	}
+}
+
+// ****************************************************************************
+// findDecorator()
+// ****************************************************************************
+func findDecorator(buf *femto.Buffer, top int, bottom int) ([]ui.Highlight, []ui.GutterMark) {
+	var hl []ui.Highlight
+	if findRegexp == nil {
+		return hl, nil
+	}
+	style := tcell.StyleDefault.Background(tcell.ColorOlive).Foreground(tcell.ColorBlack)
+	for y := top; y < bottom; y++ {
+		for _, m := range findInLine(findRegexp, y, buf.Line(y), FindOptions) {
+			hl = append(hl, ui.Highlight{Line: y, Start: m.X1, End: m.X2, Style: style})
+		}
+	}
+	return hl, nil
+}
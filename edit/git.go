@@ -0,0 +1,482 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"lied/dialog"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// gitChange is an entry of "git status --porcelain"
+type gitChange struct {
+	X        byte // Index status
+	Y        byte // Worktree status
+	Path     string
+	Orig     string // Source of a rename
+	Unmerged bool
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	DlgGit        *dialog.Dialog
+	gitRepo       string
+	gitChanges    []gitChange
+	pushOnCommit  bool
+	gitConfigName string
+)
+
+// ****************************************************************************
+// runGit()
+// runGit runs git into dir and returns its output, or its error message
+// ****************************************************************************
+func runGit(dir string, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Never let git wait for an answer on the terminal owned by the editor
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, askpassEnv()...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(errb.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return outb.String(), errors.New(msg)
+	}
+	return outb.String() + errb.String(), nil
+}
+
+// ****************************************************************************
+// repoOf()
+// repoOf returns the top directory of the repository holding dir
+// ****************************************************************************
+func repoOf(dir string) (string, error) {
+	out, err := runGit(dir, "", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%s is not into a GIT repository", dir)
+	}
+	return filepath.FromSlash(strings.TrimSpace(out)), nil
+}
+
+// ****************************************************************************
+// currentRepo()
+// currentRepo returns the repository of the current file, or of the workspace
+// ****************************************************************************
+func currentRepo() (string, error) {
+	dir := explorerRoot()
+	if CurrentFile.FName != "" {
+		dir = filepath.Dir(CurrentFile.FName)
+	}
+	return repoOf(dir)
+}
+
+// ****************************************************************************
+// lastLine()
+// ****************************************************************************
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// ****************************************************************************
+// gitChangesOf()
+// gitChangesOf parses "git status --porcelain=v2", with v1 like status letters
+// ****************************************************************************
+func gitChangesOf(repo string) ([]gitChange, error) {
+	out, err := runGit(repo, "", "status", "--porcelain=v2", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	_, changes := parseStatus(out)
+	return changes, nil
+}
+
+// ****************************************************************************
+// parseStatus()
+// parseStatus reads the output of "git status --porcelain=v2 -z", the headers
+// (given by --branch) are returned as a map
+// ****************************************************************************
+func parseStatus(out string) (map[string]string, []gitChange) {
+	headers := make(map[string]string)
+	var changes []gitChange
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if len(f) < 3 {
+			continue
+		}
+		var c gitChange
+		switch f[0] {
+		case '#':
+			// # branch.oid <commit> | # branch.head <branch> ...
+			if key, value, ok := strings.Cut(f[2:], " "); ok {
+				headers[key] = value
+			}
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			if parts := strings.SplitN(f, " ", 9); len(parts) == 9 {
+				c = gitChange{X: f[2], Y: f[3], Path: parts[8]}
+			}
+		case '2':
+			// 2 XY sub mH mI mW hH hI score path, then the source
+			if parts := strings.SplitN(f, " ", 10); len(parts) == 10 && i+1 < len(fields) {
+				c = gitChange{X: f[2], Y: f[3], Path: parts[9], Orig: fields[i+1]}
+				i++
+			}
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			if parts := strings.SplitN(f, " ", 11); len(parts) == 11 {
+				c = gitChange{X: f[2], Y: f[3], Path: parts[10], Unmerged: true}
+			}
+		case '?':
+			c = gitChange{X: '?', Y: '?', Path: f[2:]}
+		}
+		if c.Path == "" {
+			continue
+		}
+		if c.X == '.' {
+			c.X = ' '
+		}
+		if c.Y == '.' {
+			c.Y = ' '
+		}
+		changes = append(changes, c)
+	}
+	return headers, changes
+}
+
+// ****************************************************************************
+// isStaged()
+// ****************************************************************************
+func (c gitChange) isStaged() bool {
+	return c.X != ' ' && c.X != '?'
+}
+
+// ****************************************************************************
+// label()
+// ****************************************************************************
+func (c gitChange) label() string {
+	if c.Orig != "" {
+		return fmt.Sprintf("%c%c %s → %s", c.X, c.Y, c.Orig, c.Path)
+	}
+	return fmt.Sprintf("%c%c %s", c.X, c.Y, c.Path)
+}
+
+// ****************************************************************************
+// GitCommit()
+// GitCommit asks for the message and the files to commit
+// ****************************************************************************
+func GitCommit(f any) {
+	pushOnCommit = false
+	askCommit()
+}
+
+// ****************************************************************************
+// GitCommitPush()
+// ****************************************************************************
+func GitCommitPush(f any) {
+	pushOnCommit = true
+	askCommit()
+}
+
+// ****************************************************************************
+// askCommit()
+// ****************************************************************************
+func askCommit() {
+	repo, err := currentRepo()
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	gitRepo = repo
+	changes, err := gitChangesOf(repo)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	if len(changes) == 0 {
+		ui.SetStatus("Nothing to commit, working tree clean")
+		return
+	}
+	gitChanges = changes
+	var labels []string
+	var checked []bool
+	for _, c := range changes {
+		labels = append(labels, c.label())
+		checked = append(checked, c.isStaged())
+	}
+	DlgGit = DlgGit.Multiline(utils.If(pushOnCommit, "Commit & Push", "Commit"), // Title
+		fmt.Sprintf("Commit message for %s, then the files to commit :", filepath.Base(repo)), // Message
+		"",
+		labels,
+		checked,
+		doCommit,
+		0,
+		ui.GetCurrentScreen(), ui.App.GetFocus()) // Focus return
+	ui.PgsApp.AddPage("dlgGit", DlgGit.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgGit")
+}
+
+// ****************************************************************************
+// doCommit()
+// ****************************************************************************
+func doCommit(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	msg := strings.TrimSpace(DlgGit.Value)
+	if msg == "" {
+		ui.SetStatus("Commit aborted, the message is empty")
+		return
+	}
+	var add, reset []string
+	selected := false
+	for i, c := range gitChanges {
+		selected = selected || DlgGit.Checked[i]
+		switch {
+		case DlgGit.Checked[i] && !c.isStaged():
+			add = append(add, c.Path)
+		case !DlgGit.Checked[i] && c.isStaged():
+			reset = append(reset, c.Path)
+			if c.Orig != "" {
+				reset = append(reset, c.Orig)
+			}
+		}
+	}
+	if !selected {
+		ui.SetStatus("Commit aborted, no file selected")
+		return
+	}
+	if len(add) > 0 {
+		if _, err := runGit(gitRepo, "", append([]string{"add", "-A", "--"}, add...)...); err != nil {
+			showGitError("Commit", err)
+			return
+		}
+	}
+	if len(reset) > 0 {
+		if _, err := runGit(gitRepo, "", append([]string{"reset", "-q", "--"}, reset...)...); err != nil {
+			showGitError("Commit", err)
+			return
+		}
+	}
+	if _, err := runGit(gitRepo, msg+"\n", "commit", "-q", "-F", "-"); err != nil {
+		showGitError("Commit", err)
+		return
+	}
+	if ui.CurrentMode == ui.ModeGit {
+		refreshGitStatus()
+	}
+	touchRepo(gitRepo)
+	head, _ := runGit(gitRepo, "", "log", "-1", "--format=%h %s")
+	ui.SetStatus(fmt.Sprintf("Committed %s", lastLine(head)))
+	if pushOnCommit {
+		GitPush(nil)
+	}
+}
+
+// ****************************************************************************
+// runGitBackground()
+// runGitBackground runs a long git command (network...) and reports its result
+// ****************************************************************************
+func runGitBackground(what string, dir string, args ...string) {
+	ui.PleaseWait()
+	ui.SetStatus(fmt.Sprintf("%s into %s…", what, dir))
+	go func() {
+		out, err := runGit(dir, "", args...)
+		ui.App.QueueUpdateDraw(func() {
+			ui.JobsDone()
+			if err != nil {
+				if n := unmergedCount(dir); n > 0 {
+					refreshGitInfos()
+					ui.SetStatus(fmt.Sprintf("%s : %d file(s) in conflict, resolve them from the GIT menu", what, n))
+					return
+				}
+				showGitError(what, err)
+				return
+			}
+			if ui.CurrentMode == ui.ModeGit {
+				refreshGitStatus()
+			}
+			touchRepo(dir)
+			if res := lastLine(out); res != "" {
+				ui.SetStatus(fmt.Sprintf("%s : %s", what, res))
+			} else {
+				ui.SetStatus(fmt.Sprintf("%s done", what))
+			}
+		})
+	}()
+}
+
+// ****************************************************************************
+// unmergedCount()
+// ****************************************************************************
+func unmergedCount(dir string) int {
+	changes, err := gitChangesOf(dir)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, c := range changes {
+		if c.Unmerged {
+			n++
+		}
+	}
+	return n
+}
+
+// ****************************************************************************
+// showGitError()
+// ****************************************************************************
+func showGitError(what string, err error) {
+	ui.SetStatus(fmt.Sprintf("%s failed : %s", what, err.Error()))
+	DlgGit = DlgGit.OK(what+" failed", // Title
+		strings.SplitN(err.Error(), "\n", 2)[0], // Message
+		func(rc dialog.DlgButton, idx int) {},
+		0,
+		ui.GetCurrentScreen(), ui.App.GetFocus()) // Focus return
+	ui.PgsApp.AddPage("dlgGit", DlgGit.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgGit")
+}
+
+// ****************************************************************************
+// GitPush()
+// ****************************************************************************
+func GitPush(f any) {
+	if repo, err := currentRepo(); err != nil {
+		ui.SetStatus(err.Error())
+	} else {
+		withCredentials(func() { runGitBackground("Push", repo, "push") })
+	}
+}
+
+// ****************************************************************************
+// GitFetch()
+// ****************************************************************************
+func GitFetch(f any) {
+	if repo, err := currentRepo(); err != nil {
+		ui.SetStatus(err.Error())
+	} else {
+		withCredentials(func() { runGitBackground("Fetch", repo, "fetch", "--all", "--prune") })
+	}
+}
+
+// ****************************************************************************
+// GitPull()
+// ****************************************************************************
+func GitPull(f any) {
+	if repo, err := currentRepo(); err != nil {
+		ui.SetStatus(err.Error())
+	} else {
+		withCredentials(func() { runGitBackground("Pull", repo, "pull") })
+	}
+}
+
+// ****************************************************************************
+// GitInit()
+// GitInit creates a repository into the workspace
+// ****************************************************************************
+func GitInit(f any) {
+	root := explorerRoot()
+	if repo, err := repoOf(root); err == nil {
+		ui.SetStatus(fmt.Sprintf("%s is already into the GIT repository %s", root, repo))
+		return
+	}
+	out, err := runGit(root, "", "init")
+	if err != nil {
+		showGitError("Initialize", err)
+		return
+	}
+	forgetRepos()
+	touchAllRepos()
+	ui.SetStatus(lastLine(out))
+}
+
+// ****************************************************************************
+// GitConfigure()
+// GitConfigure sets the user name and email of the repository
+// ****************************************************************************
+func GitConfigure(f any) {
+	repo, err := currentRepo()
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	gitRepo = repo
+	name, _ := runGit(repo, "", "config", "user.name")
+	DlgGit = DlgGit.Input("Configure GIT", // Title
+		fmt.Sprintf("User name for %s :", filepath.Base(repo)), // Message
+		strings.TrimSpace(name),
+		askGitEmail,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgGit", DlgGit.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgGit")
+}
+
+// ****************************************************************************
+// askGitEmail()
+// ****************************************************************************
+func askGitEmail(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	gitConfigName = strings.TrimSpace(DlgGit.Value)
+	email, _ := runGit(gitRepo, "", "config", "user.email")
+	DlgGit = DlgGit.Input("Configure GIT", // Title
+		fmt.Sprintf("User email for %s :", filepath.Base(gitRepo)), // Message
+		strings.TrimSpace(email),
+		doGitConfigure,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgGit", DlgGit.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgGit")
+}
+
+// ****************************************************************************
+// doGitConfigure()
+// ****************************************************************************
+func doGitConfigure(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	for key, value := range map[string]string{"user.name": gitConfigName, "user.email": strings.TrimSpace(DlgGit.Value)} {
+		if value == "" {
+			continue
+		}
+		if _, err := runGit(gitRepo, "", "config", key, value); err != nil {
+			showGitError("Configure", err)
+			return
+		}
+	}
+	ui.SetStatus(fmt.Sprintf("GIT user set to %s <%s> for %s", gitConfigName, strings.TrimSpace(DlgGit.Value), gitRepo))
+}
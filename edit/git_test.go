@@ -0,0 +1,89 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ****************************************************************************
+// TestParseStatus()
+// ****************************************************************************
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		headers map[string]string
+		changes []gitChange
+	}{
+		{"empty", "", map[string]string{}, nil},
+		{
+			"headers",
+			"# branch.oid 0123456789abcdef\x00# branch.head main\x00# branch.ab +1 -2\x00",
+			map[string]string{"branch.oid": "0123456789abcdef", "branch.head": "main", "branch.ab": "+1 -2"},
+			nil,
+		},
+		{
+			"modified",
+			"1 .M N... 100644 100644 100644 aaaa bbbb edit/edit.go\x00",
+			map[string]string{},
+			[]gitChange{{X: ' ', Y: 'M', Path: "edit/edit.go"}},
+		},
+		{
+			"staged path with spaces",
+			"1 A. N... 000000 100644 100644 0000 bbbb my file.txt\x00",
+			map[string]string{},
+			[]gitChange{{X: 'A', Y: ' ', Path: "my file.txt"}},
+		},
+		{
+			"renamed",
+			"2 R. N... 100644 100644 100644 aaaa bbbb R100 new.go\x00old.go\x00",
+			map[string]string{},
+			[]gitChange{{X: 'R', Y: ' ', Path: "new.go", Orig: "old.go"}},
+		},
+		{
+			"unmerged",
+			"u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.go\x00",
+			map[string]string{},
+			[]gitChange{{X: 'U', Y: 'U', Path: "conflict.go", Unmerged: true}},
+		},
+		{
+			"untracked",
+			"? new dir/file.go\x00",
+			map[string]string{},
+			[]gitChange{{X: '?', Y: '?', Path: "new dir/file.go"}},
+		},
+		{
+			"mixed",
+			"# branch.head main\x00? b.go\x002 R. N... 100644 100644 100644 aaaa bbbb R90 c.go\x00a.go\x001 MM N... 100644 100644 100644 aaaa bbbb d.go\x00",
+			map[string]string{"branch.head": "main"},
+			[]gitChange{
+				{X: '?', Y: '?', Path: "b.go"},
+				{X: 'R', Y: ' ', Path: "c.go", Orig: "a.go"},
+				{X: 'M', Y: 'M', Path: "d.go"},
+			},
+		},
+		{"truncated", "1 .M N... 100644\x00", map[string]string{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, changes := parseStatus(tt.out)
+			if !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("headers = %v, want %v", headers, tt.headers)
+			}
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("changes = %+v, want %+v", changes, tt.changes)
+			}
+		})
+	}
+}
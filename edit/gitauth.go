@@ -0,0 +1,308 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"lied/conf"
+	"lied/dialog"
+	"lied/ui"
+	"lied/utils"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	GitUser     string
+	DlgUnlock   *dialog.Dialog
+	gitPassword string // Only kept in memory, see SaveCredentials
+	gitHost     string // The password is only given to this host, see answerAskpass
+	askpassSock string
+	askpassLn   net.Listener
+	onUnlock    func()
+)
+
+// ****************************************************************************
+// credentialsFile()
+// ****************************************************************************
+func credentialsFile() string {
+	userDir, _ := os.UserHomeDir()
+	return filepath.Join(userDir, conf.APP_FOLDER, conf.FILE_CREDENTIALS)
+}
+
+// ****************************************************************************
+// HasCredentials()
+// HasCredentials tells if an encrypted password has been stored
+// ****************************************************************************
+func HasCredentials() bool {
+	_, err := os.Stat(credentialsFile())
+	return err == nil
+}
+
+// ****************************************************************************
+// HostOf()
+// HostOf reduces an URL to its scheme and host, "" if it is not an URL git
+// asks a password for (an SSH remote for instance)
+// ****************************************************************************
+func HostOf(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// ****************************************************************************
+// RemoteHost()
+// RemoteHost returns the host of the origin of the current file's repository
+// ****************************************************************************
+func RemoteHost() string {
+	out, err := runGit(filepath.Dir(CurrentFile.FName), "", "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	return HostOf(out)
+}
+
+// ****************************************************************************
+// SaveCredentials()
+// SaveCredentials stores the GIT password and its host encrypted with a key
+// derived from the master passphrase, the password itself never goes to the
+// INI file
+// ****************************************************************************
+func SaveCredentials(host string, password string, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the master passphrase can't be empty")
+	}
+	if host = HostOf(host); host == "" {
+		return errors.New("the host must be an http(s) URL")
+	}
+	sealed, err := utils.Seal(passphrase, []byte(host+"\n"+password))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(credentialsFile(), sealed, 0600); err != nil {
+		return err
+	}
+	SetGitPassword(host, password)
+	return nil
+}
+
+// ****************************************************************************
+// UnlockCredentials()
+// UnlockCredentials decrypts the stored password for the session
+// ****************************************************************************
+func UnlockCredentials(passphrase string) error {
+	sealed, err := os.ReadFile(credentialsFile())
+	if err != nil {
+		return err
+	}
+	data, err := utils.Unseal(passphrase, sealed)
+	if err != nil {
+		return err
+	}
+	host, password, found := strings.Cut(string(data), "\n")
+	if !found {
+		return errors.New("stored without its host, please set the Git password again")
+	}
+	SetGitPassword(host, password)
+	return nil
+}
+
+// ****************************************************************************
+// SetGitPassword()
+// SetGitPassword keeps the password of host in memory and hides it from the
+// status bar and the log file
+// ****************************************************************************
+func SetGitPassword(host string, password string) {
+	gitHost = HostOf(host)
+	gitPassword = password
+	ui.AddSecret(password)
+}
+
+// ****************************************************************************
+// withCredentials()
+// withCredentials asks for the master passphrase if the stored password has
+// not been unlocked yet, then runs then
+// ****************************************************************************
+func withCredentials(then func()) {
+	if gitPassword != "" || !HasCredentials() {
+		then()
+		return
+	}
+	onUnlock = then
+	DlgUnlock = DlgUnlock.Password("Unlock credentials", // Title
+		"Please, enter the master passphrase :", // Message
+		doUnlock,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgUnlock", DlgUnlock.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgUnlock")
+}
+
+// ****************************************************************************
+// doUnlock()
+// ****************************************************************************
+func doUnlock(rc dialog.DlgButton, idx int) {
+	passphrase := DlgUnlock.Value
+	DlgUnlock.Value = ""
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	if err := UnlockCredentials(passphrase); err != nil {
+		ui.SetStatus(fmt.Sprintf("Unlock credentials : %s", err.Error()))
+		return
+	}
+	ui.SetStatus("Credentials unlocked")
+	if onUnlock != nil {
+		onUnlock()
+	}
+}
+
+// ****************************************************************************
+// ServeAskpass()
+// ServeAskpass answers the prompts of git through a socket only readable by
+// the user, git runs lied itself as its GIT_ASKPASS helper (see Askpass)
+// ****************************************************************************
+func ServeAskpass() error {
+	userDir, _ := os.UserHomeDir()
+	sock := filepath.Join(userDir, conf.APP_FOLDER, fmt.Sprintf("askpass-%s.sock", ui.SessionID))
+	os.Remove(sock)
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(sock, 0600); err != nil {
+		ln.Close()
+		return err
+	}
+	askpassLn = ln
+	askpassSock = sock
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go answerAskpass(c)
+		}
+	}()
+	return nil
+}
+
+// ****************************************************************************
+// promptHost()
+// promptHost returns the host of the URL quoted in a prompt of git, like
+// "Password for 'https://user@host': "
+// ****************************************************************************
+func promptHost(prompt string) string {
+	_, quoted, found := strings.Cut(prompt, "'")
+	if !found {
+		return ""
+	}
+	quoted, _, found = strings.Cut(quoted, "'")
+	if !found {
+		return ""
+	}
+	return HostOf(quoted)
+}
+
+// ****************************************************************************
+// answerAskpass()
+// answerAskpass only answers the prompts for the host of the credentials, git
+// fails on the others
+// ****************************************************************************
+func answerAskpass(c net.Conn) {
+	defer c.Close()
+	prompt, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil {
+		return
+	}
+	if gitHost == "" || promptHost(prompt) != gitHost {
+		return
+	}
+	prompt = strings.ToLower(prompt)
+	switch {
+	case strings.HasPrefix(prompt, "username"):
+		fmt.Fprintln(c, GitUser)
+	case strings.HasPrefix(prompt, "password"):
+		fmt.Fprintln(c, gitPassword)
+	}
+}
+
+// ****************************************************************************
+// StopAskpass()
+// ****************************************************************************
+func StopAskpass() {
+	if askpassLn != nil {
+		askpassLn.Close()
+		os.Remove(askpassSock)
+		askpassLn = nil
+		askpassSock = ""
+	}
+}
+
+// ****************************************************************************
+// askpassEnv()
+// askpassEnv returns the environment making git ask lied for the credentials
+// ****************************************************************************
+func askpassEnv() []string {
+	if askpassSock == "" {
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	return []string{"GIT_ASKPASS=" + exe, conf.ASKPASS_ENV + "=" + askpassSock}
+}
+
+// ****************************************************************************
+// Askpass()
+// Askpass is run when git calls lied as its GIT_ASKPASS helper, it relays the
+// prompt to the editor and prints its answer
+// ****************************************************************************
+func Askpass(sock string) int {
+	if len(os.Args) < 2 {
+		return 1
+	}
+	c, err := net.Dial("unix", sock)
+	if err != nil {
+		return 1
+	}
+	defer c.Close()
+	fmt.Fprintln(c, strings.ReplaceAll(os.Args[1], "\n", " "))
+	answer, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil {
+		return 1
+	}
+	fmt.Print(answer)
+	return 0
+}
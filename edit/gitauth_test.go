@@ -0,0 +1,37 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+import "testing"
+
+// ****************************************************************************
+// TestPromptHost()
+// ****************************************************************************
+func TestPromptHost(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"Password for 'https://user@github.com': ", "https://github.com"},
+		{"Username for 'https://GitHub.com': ", "https://github.com"},
+		{"Password for 'https://host:8443/path': ", "https://host:8443"},
+		{"Password for 'http://host': ", "http://host"},
+		{"Password for 'ssh://git@host': ", ""},
+		{"Enter passphrase for key '/home/u/.ssh/id_ed25519': ", ""},
+		{"Password: ", ""},
+	}
+	for _, tt := range tests {
+		if got := promptHost(tt.prompt); got != tt.want {
+			t.Errorf("promptHost(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
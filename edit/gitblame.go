@@ -0,0 +1,486 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/keys"
+	"lied/ui"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// blameCommit is what "git blame --porcelain" tells about a commit
+type blameCommit struct {
+	Hash    string
+	Author  string
+	When    time.Time
+	Summary string
+}
+
+// logEntry is a commit touching the file of the history screen, Path being
+// its name at that commit
+type logEntry struct {
+	Hash    string
+	Author  string
+	When    time.Time
+	Subject string
+	Path    string
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	blameRepo   string
+	blameFile   string // Absolute name of the blamed file
+	historyRoot string
+	historyPath string // Absolute name of the file whose history is shown
+	history     []logEntry
+)
+
+// ****************************************************************************
+// fileInRepo()
+// fileInRepo returns the repository of fName and its name relative to it
+// ****************************************************************************
+func fileInRepo(fName string) (string, string, error) {
+	fName, _ = filepath.Abs(fName)
+	repo, err := repoOf(filepath.Dir(fName))
+	if err != nil {
+		return "", "", err
+	}
+	rel, err := filepath.Rel(repo, fName)
+	if err != nil {
+		return "", "", err
+	}
+	return repo, filepath.ToSlash(rel), nil
+}
+
+// ****************************************************************************
+// showGitScreen()
+// showGitScreen shows the screen of the given mode, creating it if needed
+// ****************************************************************************
+func showGitScreen(mode ui.Mode, title string, selfInit ui.FnAny) {
+	idx := ui.GetScreenFromTitle(title)
+	if idx == "NIL" {
+		ui.AddNewScreen(mode, selfInit, nil)
+		return
+	}
+	i, _ := strconv.Atoi(idx)
+	ui.ShowScreen(i)
+}
+
+// ****************************************************************************
+// shortHash()
+// ****************************************************************************
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+// ****************************************************************************
+// colorGitOutput()
+// colorGitOutput colors the output of "git show" for the diff screen
+// ****************************************************************************
+func colorGitOutput(out string) string {
+	var sb strings.Builder
+	for _, l := range strings.Split(out, "\n") {
+		e := tview.Escape(l)
+		switch {
+		case strings.HasPrefix(l, "commit "):
+			sb.WriteString("[yellow]" + e + "[-]\n")
+		case strings.HasPrefix(l, "+++") || strings.HasPrefix(l, "---") || strings.HasPrefix(l, "diff --git"):
+			sb.WriteString("[::b]" + e + "[::-]\n")
+		case strings.HasPrefix(l, "@@"):
+			sb.WriteString("[aqua]" + e + "[-]\n")
+		case strings.HasPrefix(l, "+"):
+			sb.WriteString("[green]" + e + "[-]\n")
+		case strings.HasPrefix(l, "-"):
+			sb.WriteString("[red]" + e + "[-]\n")
+		default:
+			sb.WriteString(e + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// showCommit()
+// showCommit displays a commit, limited to the given paths if any, then
+// returns to the screen it was called from
+// ****************************************************************************
+func showCommit(repo string, hash string, back string, paths ...string) {
+	args := append([]string{"show", "--no-color", "--no-ext-diff", "--stat", "-p", hash, "--"}, paths...)
+	out, err := runGit(repo, "", args...)
+	if err != nil {
+		showGitError("Show", err)
+		return
+	}
+	ShowText("Commit "+shortHash(hash), colorGitOutput(out), func() {
+		if idx := ui.GetScreenFromTitle(back); idx != "NIL" {
+			i, _ := strconv.Atoi(idx)
+			ui.ShowScreen(i)
+		}
+	})
+}
+
+// ****************************************************************************
+// parseBlame()
+// parseBlame returns the commit and the text of each line of a porcelain blame
+// ****************************************************************************
+func parseBlame(out string) ([]*blameCommit, []string) {
+	commits := make(map[string]*blameCommit)
+	var lines []*blameCommit
+	var texts []string
+	var cur *blameCommit
+	for _, l := range strings.Split(out, "\n") {
+		if strings.HasPrefix(l, "\t") {
+			lines = append(lines, cur)
+			texts = append(texts, l[1:])
+			continue
+		}
+		fields := strings.Fields(l)
+		if len(fields) >= 3 && len(fields[0]) == 40 {
+			if c, ok := commits[fields[0]]; ok {
+				cur = c
+			} else {
+				cur = &blameCommit{Hash: fields[0]}
+				commits[fields[0]] = cur
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		key, value, _ := strings.Cut(l, " ")
+		switch key {
+		case "author":
+			cur.Author = value
+		case "author-time":
+			t, _ := strconv.ParseInt(value, 10, 64)
+			cur.When = time.Unix(t, 0)
+		case "summary":
+			cur.Summary = value
+		}
+	}
+	return lines, texts
+}
+
+// ****************************************************************************
+// GitBlame()
+// GitBlame annotates each line of the current buffer with its last commit
+// ****************************************************************************
+func GitBlame(f any) {
+	if CurrentFile.Buffer == nil || CurrentFile.FName == "" {
+		ui.SetStatus("No file to blame")
+		return
+	}
+	repo, rel, err := fileInRepo(CurrentFile.FName)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	blameRepo = repo
+	blameFile, _ = filepath.Abs(CurrentFile.FName)
+	line := CurrentFile.Buffer.Cursor.Y
+	// The buffer is blamed as it would be saved, its unsaved lines are "Not
+	// Committed Yet"
+	data, err := encodeContent(applyLineEnding(CurrentFile.Buffer.String(), CurrentFile.LineEnding), CurrentFile.Encoding, CurrentFile.BOM)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	out, err := runGit(repo, string(data), "blame", "--porcelain", "--contents", "-", "--", rel)
+	if err != nil {
+		showGitError("Blame", err)
+		return
+	}
+	showGitScreen(ui.ModeBlame, "Blame", BlameSelfInit)
+	commits, texts := parseBlame(out)
+	ui.TblBlame.Clear()
+	ui.TblBlame.SetTitle(fmt.Sprintf("Blame of %s", rel))
+	for i, c := range commits {
+		hash, author, date := "", "", ""
+		if c != nil {
+			hash, author, date = shortHash(c.Hash), c.Author, c.When.Format(ui.MyConfig.FormatDate)
+			if strings.Trim(c.Hash, "0") == "" {
+				hash, date = "--------", ""
+			}
+		}
+		ui.TblBlame.SetCell(i, 0, tview.NewTableCell(hash).SetTextColor(tcell.ColorYellow).SetReference(c))
+		ui.TblBlame.SetCell(i, 1, tview.NewTableCell(tview.Escape(author)).SetMaxWidth(20).SetTextColor(tcell.ColorAqua))
+		ui.TblBlame.SetCell(i, 2, tview.NewTableCell(date).SetTextColor(tcell.ColorGray))
+		ui.TblBlame.SetCell(i, 3, tview.NewTableCell(strconv.Itoa(i+1)).SetAlign(tview.AlignRight).SetTextColor(tcell.ColorGray))
+		// The lines given back by git are in the charset of the file
+		text := texts[i]
+		if i < CurrentFile.Buffer.NumLines {
+			text = CurrentFile.Buffer.Line(i)
+		}
+		ui.TblBlame.SetCell(i, 4, tview.NewTableCell(tview.Escape(strings.ReplaceAll(text, "\t", "    "))).SetExpansion(1))
+	}
+	ui.TblBlame.Select(line, 0)
+	ui.App.SetFocus(ui.TblBlame)
+}
+
+// ****************************************************************************
+// BlameSelfInit()
+// ****************************************************************************
+func BlameSelfInit(a any) {
+	ui.TblBlame.SetSelectionChangedFunc(func(row int, column int) {
+		if c, ok := ui.TblBlame.GetCell(row, 0).GetReference().(*blameCommit); ok && c != nil {
+			ui.SetStatus(fmt.Sprintf("%s %s", shortHash(c.Hash), c.Summary))
+		}
+	})
+	ui.TblBlame.SetSelectedFunc(func(row int, column int) {
+		showBlameCommit(row)
+	})
+	ui.TblBlame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(blameKeys(), event)
+	})
+}
+
+// ****************************************************************************
+// blameKeys()
+// ****************************************************************************
+func blameKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Enter", Label: "Show commit", Done: func() {
+			row, _ := ui.TblBlame.GetSelection()
+			showBlameCommit(row)
+		}},
+		{Chord: "Alt+H", Label: "File history", Done: func() { showHistory(blameFile) }},
+		{Chord: "Ctrl+R", Label: "Refresh", Done: func() {
+			ShowEditorScreen()
+			GitBlame(nil)
+		}},
+		{Chord: "Esc", Label: "Editor", Done: func() {
+			row, _ := ui.TblBlame.GetSelection()
+			ShowEditorScreen()
+			if CurrentFile.FName == blameFile {
+				GotoLine(row, 0)
+			}
+		}},
+	}
+}
+
+// ****************************************************************************
+// showBlameCommit()
+// ****************************************************************************
+func showBlameCommit(row int) {
+	c, ok := ui.TblBlame.GetCell(row, 0).GetReference().(*blameCommit)
+	if !ok || c == nil {
+		return
+	}
+	if strings.Trim(c.Hash, "0") == "" {
+		ui.SetStatus("Not committed yet")
+		return
+	}
+	showCommit(blameRepo, c.Hash, "Blame")
+}
+
+// ****************************************************************************
+// GitHistory()
+// GitHistory lists the commits which touched the current file
+// ****************************************************************************
+func GitHistory(f any) {
+	if CurrentFile.FName == "" {
+		ui.SetStatus("No file")
+		return
+	}
+	showHistory(CurrentFile.FName)
+}
+
+// ****************************************************************************
+// showHistory()
+// ****************************************************************************
+func showHistory(fName string) {
+	repo, rel, err := fileInRepo(fName)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	out, err := runGit(repo, "", "log", "--follow", "--name-only", "--format=%x00%H%x00%an%x00%at%x00%s", "--", rel)
+	if err != nil {
+		showGitError("History", err)
+		return
+	}
+	historyRoot = repo
+	historyPath, _ = filepath.Abs(fName)
+	history = nil
+	// Each commit is "\0hash\0author\0time\0subject\n\npath\n"
+	parts := strings.Split(out, "\x00")
+	for i := 1; i+3 < len(parts); i += 4 {
+		e := logEntry{Hash: parts[i], Author: parts[i+1]}
+		t, _ := strconv.ParseInt(parts[i+2], 10, 64)
+		e.When = time.Unix(t, 0)
+		lines := strings.Split(strings.TrimSpace(parts[i+3]), "\n")
+		e.Subject = lines[0]
+		e.Path = rel
+		if p := strings.TrimSpace(lines[len(lines)-1]); len(lines) > 1 && p != "" {
+			e.Path = p
+		}
+		history = append(history, e)
+	}
+	showGitScreen(ui.ModeHistory, "History", HistorySelfInit)
+	ui.TblHistory.Clear()
+	ui.TblHistory.SetTitle(fmt.Sprintf("History of %s (%d commits)", rel, len(history)))
+	for i, e := range history {
+		ui.TblHistory.SetCell(i, 0, tview.NewTableCell(shortHash(e.Hash)).SetTextColor(tcell.ColorYellow))
+		ui.TblHistory.SetCell(i, 1, tview.NewTableCell(e.When.Format(ui.MyConfig.FormatDate+" "+ui.MyConfig.FormatTime)).SetTextColor(tcell.ColorGray))
+		ui.TblHistory.SetCell(i, 2, tview.NewTableCell(tview.Escape(e.Author)).SetMaxWidth(20).SetTextColor(tcell.ColorAqua))
+		subject := e.Subject
+		if e.Path != rel {
+			subject += " (" + e.Path + ")"
+		}
+		ui.TblHistory.SetCell(i, 3, tview.NewTableCell(tview.Escape(subject)).SetExpansion(1))
+	}
+	if len(history) == 0 {
+		ui.SetStatus(fmt.Sprintf("%s has never been committed", rel))
+	}
+	ui.TblHistory.Select(0, 0)
+	ui.App.SetFocus(ui.TblHistory)
+}
+
+// ****************************************************************************
+// HistorySelfInit()
+// ****************************************************************************
+func HistorySelfInit(a any) {
+	ui.TblHistory.SetSelectedFunc(func(row int, column int) {
+		showHistoryEntry(row)
+	})
+	ui.TblHistory.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(historyKeys(), event)
+	})
+}
+
+// ****************************************************************************
+// historyKeys()
+// ****************************************************************************
+func historyKeys() []keys.ScreenKey {
+	selected := func(do func(row int)) func() {
+		return func() {
+			row, _ := ui.TblHistory.GetSelection()
+			do(row)
+		}
+	}
+	return []keys.ScreenKey{
+		{Chord: "Enter", Label: "Show changes", Done: selected(showHistoryEntry)},
+		{Chord: "Alt+O", Label: "Open revision", Done: selected(openRevision)},
+		{Chord: "Alt+D", Label: "Diff with working copy", Done: selected(diffRevision)},
+		{Chord: "Esc", Label: "Editor", Done: ShowEditorScreen},
+	}
+}
+
+// ****************************************************************************
+// showHistoryEntry()
+// ****************************************************************************
+func showHistoryEntry(row int) {
+	if e, ok := historyEntry(row); ok {
+		showCommit(historyRoot, e.Hash, "History", e.Path)
+	}
+}
+
+// ****************************************************************************
+// historyEntry()
+// ****************************************************************************
+func historyEntry(row int) (logEntry, bool) {
+	if row < 0 || row >= len(history) {
+		return logEntry{}, false
+	}
+	return history[row], true
+}
+
+// ****************************************************************************
+// revisionText()
+// revisionText returns the content of the file at the given commit
+// ****************************************************************************
+func revisionText(e logEntry) (string, bool) {
+	out, err := runGit(historyRoot, "", "show", e.Hash+":"+e.Path)
+	if err != nil {
+		showGitError("Show", err)
+		return "", false
+	}
+	text, _ := decodeText([]byte(out))
+	return text, true
+}
+
+// ****************************************************************************
+// backToHistory()
+// ****************************************************************************
+func backToHistory() {
+	if idx := ui.GetScreenFromTitle("History"); idx != "NIL" {
+		i, _ := strconv.Atoi(idx)
+		ui.ShowScreen(i)
+	}
+}
+
+// ****************************************************************************
+// openRevision()
+// openRevision shows, read-only, the file as it was at the selected commit
+// ****************************************************************************
+func openRevision(row int) {
+	e, ok := historyEntry(row)
+	if !ok {
+		return
+	}
+	text, ok := revisionText(e)
+	if !ok {
+		return
+	}
+	ShowText(fmt.Sprintf("%s @ %s (read-only)", e.Path, shortHash(e.Hash)), tview.Escape(text), backToHistory)
+}
+
+// ****************************************************************************
+// diffRevision()
+// diffRevision compares the selected revision with the working copy, the
+// buffer if the file is open
+// ****************************************************************************
+func diffRevision(row int) {
+	e, ok := historyEntry(row)
+	if !ok {
+		return
+	}
+	old, ok := revisionText(e)
+	if !ok {
+		return
+	}
+	current := ""
+	found := false
+	for _, f := range OpenFiles {
+		if abs, _ := filepath.Abs(f.FName); abs == historyPath && f.Buffer != nil {
+			current, found = f.Buffer.String(), true
+			break
+		}
+	}
+	if !found {
+		text, err := ReadText(historyPath)
+		if err != nil {
+			ui.SetStatus(err.Error())
+			return
+		}
+		current = text
+	}
+	ShowDiff(fmt.Sprintf("%s @ %s ⯈ working copy", e.Path, shortHash(e.Hash)), old, current, backToHistory)
+}
@@ -0,0 +1,453 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/dialog"
+	"lied/menu"
+	"lied/ui"
+	"os"
+	"strings"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// gitBranch is a local or remote branch of the repository
+type gitBranch struct {
+	Name     string // Short name, "origin/main" for a remote one
+	Remote   bool
+	Current  bool
+	Upstream string
+	Track    string // "↑1 ↓2", relative to the upstream (or HEAD for a remote)
+	Subject  string
+}
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const NEW_BRANCH = "+ New branch from HEAD…"
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	DlgBranch    *dialog.Dialog
+	MnuBranch    *menu.Menu
+	branches     []gitBranch
+	branchTarget gitBranch
+)
+
+// ****************************************************************************
+// trackLabel()
+// trackLabel turns "ahead 1, behind 2" into "↑1 ↓2"
+// ****************************************************************************
+func trackLabel(track string) string {
+	if track == "gone" {
+		return "upstream gone"
+	}
+	r := strings.NewReplacer("ahead ", "↑", "behind ", "↓", ",", "")
+	return r.Replace(track)
+}
+
+// ****************************************************************************
+// listBranches()
+// ****************************************************************************
+func listBranches(repo string) ([]gitBranch, error) {
+	out, err := runGit(repo, "", "for-each-ref",
+		"--format=%(refname)%00%(refname:short)%00%(HEAD)%00%(upstream:short)%00%(upstream:track,nobracket)%00%(contents:subject)",
+		"refs/heads", "refs/remotes")
+	if err != nil {
+		return nil, err
+	}
+	var list []gitBranch
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(l, "\x00")
+		if len(f) < 6 || strings.HasSuffix(f[0], "/HEAD") {
+			continue
+		}
+		b := gitBranch{
+			Name:     f[1],
+			Remote:   strings.HasPrefix(f[0], "refs/remotes/"),
+			Current:  f[2] == "*",
+			Upstream: f[3],
+			Track:    trackLabel(f[4]),
+			Subject:  f[5],
+		}
+		list = append(list, b)
+	}
+	return list, nil
+}
+
+// ****************************************************************************
+// countRemoteBranches()
+// countRemoteBranches tells, in the background, how far the remote branches
+// of list are from where we are. A git rev-list being run by branch, the
+// list is shown first and updated once they are all counted.
+// ****************************************************************************
+func countRemoteBranches(repo string, list []gitBranch) {
+	names := make([]string, len(list))
+	for i, b := range list {
+		if b.Remote {
+			names[i] = b.Name
+		}
+	}
+	go func() {
+		tracks := make([]string, len(names))
+		for i, name := range names {
+			if name == "" {
+				continue
+			}
+			counts, err := runGit(repo, "", "rev-list", "--left-right", "--count", "HEAD..."+name)
+			if n := strings.Fields(counts); err == nil && len(n) == 2 && (n[0] != "0" || n[1] != "0") {
+				tracks[i] = fmt.Sprintf("↑%s ↓%s vs HEAD", n[1], n[0])
+			}
+		}
+		ui.App.QueueUpdateDraw(func() {
+			// A list replaced by a newer one is not shown any more, updating it is harmless
+			for i, t := range tracks {
+				if t != "" {
+					list[i].Track = t
+				}
+			}
+			DlgBranch.Refilter()
+		})
+	}()
+}
+
+// ****************************************************************************
+// label()
+// ****************************************************************************
+func (b gitBranch) label() string {
+	mark := "  "
+	if b.Current {
+		mark = "* "
+	}
+	s := mark + b.Name
+	if b.Upstream != "" {
+		s += " ⯈ " + b.Upstream
+	}
+	if b.Track != "" {
+		s += " [" + b.Track + "]"
+	}
+	return s + "  " + b.Subject
+}
+
+// ****************************************************************************
+// GitBranches()
+// GitBranches pops up the branches of the repository, to pick one to act on
+// ****************************************************************************
+func GitBranches(f any) {
+	repo, err := currentRepo()
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	gitRepo = repo
+	branches, err = listBranches(repo)
+	if err != nil {
+		showGitError("Branches", err)
+		return
+	}
+	countRemoteBranches(repo, branches)
+	DlgBranch = DlgBranch.Filter(fmt.Sprintf(" Branches of %s ", repo), // Title
+		"Branch : ", // Message
+		filterBranches,
+		pickBranch,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgBranch", DlgBranch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgBranch")
+}
+
+// ****************************************************************************
+// filterBranches()
+// ****************************************************************************
+func filterBranches(query string) []string {
+	values := []string{NEW_BRANCH}
+	query = strings.ToLower(query)
+	for _, b := range branches {
+		if strings.Contains(strings.ToLower(b.Name), query) {
+			values = append(values, b.label())
+		}
+	}
+	return values
+}
+
+// ****************************************************************************
+// pickBranch()
+// pickBranch shows what can be done with the chosen branch
+// ****************************************************************************
+func pickBranch(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK || DlgBranch.Value == "" {
+		return
+	}
+	if DlgBranch.Value == NEW_BRANCH {
+		askNewBranch(nil)
+		return
+	}
+	for _, b := range branches {
+		if b.label() == DlgBranch.Value {
+			branchTarget = b
+		}
+	}
+	b := branchTarget
+	MnuBranch = MnuBranch.New(" "+b.Name+" ", ui.GetCurrentScreen(), ui.EdtMain)
+	MnuBranch.AddItem("mnuBranchCheckout", "Checkout", CheckoutBranch, nil, !b.Current, false)
+	MnuBranch.AddItem("mnuBranchRename", "Rename…", askRenameBranch, nil, !b.Remote, false)
+	MnuBranch.AddItem("mnuBranchDelete", "Delete…", askDeleteBranch, nil, !b.Remote && !b.Current, false)
+	MnuBranch.AddSeparator()
+	MnuBranch.AddItem("mnuBranchNew", "New branch from HEAD…", askNewBranch, nil, true, false)
+	ui.PgsApp.AddPage("dlgBranchMenu", MnuBranch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgBranchMenu")
+}
+
+// ****************************************************************************
+// askNewBranch()
+// ****************************************************************************
+func askNewBranch(f any) {
+	DlgBranch = DlgBranch.Input("New branch", // Title
+		"Name of the branch, created from HEAD :", // Message
+		"",
+		doNewBranch,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgBranch", DlgBranch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgBranch")
+}
+
+// ****************************************************************************
+// doNewBranch()
+// doNewBranch creates the branch and switches to it, the files are unchanged
+// ****************************************************************************
+func doNewBranch(rc dialog.DlgButton, idx int) {
+	name := strings.TrimSpace(DlgBranch.Value)
+	if rc != dialog.BUTTON_OK || name == "" {
+		return
+	}
+	if _, err := runGit(gitRepo, "", "checkout", "-q", "-b", name); err != nil {
+		showGitError("New branch", err)
+		return
+	}
+	refreshGitInfos()
+	ui.SetStatus(fmt.Sprintf("Switched to the new branch %s", name))
+}
+
+// ****************************************************************************
+// askRenameBranch()
+// ****************************************************************************
+func askRenameBranch(f any) {
+	DlgBranch = DlgBranch.Input("Rename branch", // Title
+		fmt.Sprintf("New name of %s :", branchTarget.Name), // Message
+		branchTarget.Name,
+		doRenameBranch,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgBranch", DlgBranch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgBranch")
+}
+
+// ****************************************************************************
+// doRenameBranch()
+// ****************************************************************************
+func doRenameBranch(rc dialog.DlgButton, idx int) {
+	name := strings.TrimSpace(DlgBranch.Value)
+	if rc != dialog.BUTTON_OK || name == "" || name == branchTarget.Name {
+		return
+	}
+	if _, err := runGit(gitRepo, "", "branch", "-m", branchTarget.Name, name); err != nil {
+		showGitError("Rename branch", err)
+		return
+	}
+	refreshGitInfos()
+	ui.SetStatus(fmt.Sprintf("Branch %s renamed to %s", branchTarget.Name, name))
+}
+
+// ****************************************************************************
+// askDeleteBranch()
+// ****************************************************************************
+func askDeleteBranch(f any) {
+	DlgBranch = DlgBranch.YesNo("Delete branch", // Title
+		fmt.Sprintf("Delete the branch %s ?", branchTarget.Name), // Message
+		doDeleteBranch,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgBranch", DlgBranch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgBranch")
+}
+
+// ****************************************************************************
+// doDeleteBranch()
+// doDeleteBranch deletes a merged branch, idx is 1 to force the deletion
+// ****************************************************************************
+func doDeleteBranch(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_YES {
+		return
+	}
+	flag := "-d"
+	if idx == 1 {
+		flag = "-D"
+	}
+	_, err := runGit(gitRepo, "", "branch", flag, branchTarget.Name)
+	if err != nil && idx == 0 && strings.Contains(err.Error(), "not fully merged") {
+		DlgBranch = DlgBranch.YesNo("Delete branch", // Title
+			fmt.Sprintf("%s is not fully merged, delete it anyway ?", branchTarget.Name), // Message
+			doDeleteBranch,
+			1,
+			ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+		ui.PgsApp.AddPage("dlgBranch", DlgBranch.Popup(), true, false)
+		ui.PgsApp.ShowPage("dlgBranch")
+		return
+	}
+	if err != nil {
+		showGitError("Delete branch", err)
+		return
+	}
+	refreshGitInfos()
+	ui.SetStatus(fmt.Sprintf("Branch %s deleted", branchTarget.Name))
+}
+
+// ****************************************************************************
+// CheckoutBranch()
+// CheckoutBranch switches to the picked branch, once the modified buffers
+// are saved (and stashed if asked)
+// ****************************************************************************
+func CheckoutBranch(f any) {
+	syncCurrentFile()
+	modified := 0
+	for _, f := range OpenFiles {
+		if f.Buffer.Modified() {
+			modified++
+		}
+	}
+	if modified == 0 {
+		doCheckout(false)
+		return
+	}
+	DlgBranch = DlgBranch.Choice(fmt.Sprintf("Checkout %s", branchTarget.Name), // Title
+		fmt.Sprintf("%d file(s) modified, they are saved first. Stash the changes of the working tree ?", modified), // Message
+		"Save", "Save+Stash",
+		confirmCheckout,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgBranch", DlgBranch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgBranch")
+}
+
+// ****************************************************************************
+// confirmCheckout()
+// ****************************************************************************
+func confirmCheckout(rc dialog.DlgButton, idx int) {
+	switch rc {
+	case dialog.BUTTON_YES, dialog.BUTTON_NO:
+		stash := rc == dialog.BUTTON_NO
+		saveModifiedFiles(func(ok bool) {
+			if ok {
+				doCheckout(stash)
+			}
+		})
+	default:
+		ui.SetStatus("Checkout cancelled")
+	}
+}
+
+// ****************************************************************************
+// saveModifiedFiles()
+// saveModifiedFiles saves the modified buffers one after the other, done is
+// told whether all of them have been written
+// ****************************************************************************
+func saveModifiedFiles(done func(ok bool)) {
+	for _, f := range OpenFiles {
+		if !f.Buffer.Modified() {
+			continue
+		}
+		saveFile(f, f.FName, func(ok bool) {
+			if ok {
+				saveModifiedFiles(done)
+			} else {
+				done(false)
+			}
+		})
+		return
+	}
+	done(true)
+}
+
+// ****************************************************************************
+// doCheckout()
+// ****************************************************************************
+func doCheckout(stash bool) {
+	b := branchTarget
+	msg := fmt.Sprintf("Switched to %s", b.Name)
+	if stash {
+		out, err := runGit(gitRepo, "", "stash", "push", "--include-untracked", "-m", "lied : before switching to "+b.Name)
+		if err != nil {
+			showGitError("Stash", err)
+			return
+		}
+		if !strings.Contains(out, "No local changes") {
+			msg += ", changes stashed"
+		}
+	}
+	var err error
+	if b.Remote {
+		// A local branch following the remote one is created if needed
+		_, local, _ := strings.Cut(b.Name, "/")
+		if _, err = runGit(gitRepo, "", "checkout", "-q", "--track", b.Name); err != nil {
+			_, err = runGit(gitRepo, "", "checkout", "-q", local)
+		}
+	} else {
+		_, err = runGit(gitRepo, "", "checkout", "-q", b.Name)
+	}
+	if err != nil {
+		showGitError("Checkout", err)
+		return
+	}
+	if gone := reloadOpenFiles(); len(gone) > 0 {
+		msg += fmt.Sprintf(", not on this branch : %s", strings.Join(gone, ", "))
+	}
+	refreshGitInfos()
+	ui.SetStatus(msg)
+}
+
+// ****************************************************************************
+// reloadOpenFiles()
+// reloadOpenFiles loads again from disk all the files, after a checkout. It
+// returns the ones which are not there any more.
+// ****************************************************************************
+func reloadOpenFiles() []string {
+	current := CurrentFile.FName
+	var gone []string
+	for _, f := range OpenFiles {
+		if _, err := os.Stat(f.FName); err != nil {
+			gone = append(gone, f.FName)
+			continue
+		}
+		ReloadFile(f.FName)
+	}
+	SwitchOpenFile(current)
+	return gone
+}
+
+// ****************************************************************************
+// refreshGitInfos()
+// refreshGitInfos updates what is shown of GIT, once HEAD or the branches
+// have changed
+// ****************************************************************************
+func refreshGitInfos() {
+	touchRepo(gitRepo)
+	touchAllRepos()
+}
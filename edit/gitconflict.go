@@ -0,0 +1,352 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/ui"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// conflict gives the lines of the markers of a merge conflict, Base is -1
+// when there is no "|||||||" section (diff3 style)
+type conflict struct {
+	Start int
+	Base  int
+	Mid   int
+	End   int
+}
+
+// conflictCache keeps the conflicts of a buffer until it is edited
+type conflictCache struct {
+	buf       *femto.Buffer
+	undo      int
+	redo      int
+	lines     int
+	conflicts []conflict
+}
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	ACCEPT_OURS = iota
+	ACCEPT_THEIRS
+	ACCEPT_BOTH
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	conflicts conflictCache
+	// Backgrounds of the sections of a conflict
+	styleOurs   = tcell.StyleDefault.Background(tcell.NewRGBColor(0, 64, 0))
+	styleBase   = tcell.StyleDefault.Background(tcell.NewRGBColor(64, 64, 64))
+	styleTheirs = tcell.StyleDefault.Background(tcell.NewRGBColor(0, 32, 96))
+	styleMarker = tcell.StyleDefault.Background(tcell.NewRGBColor(96, 0, 0))
+)
+
+// ****************************************************************************
+// isUnmerged()
+// isUnmerged tells if a "git status -s" code is the one of a conflicted file
+// ****************************************************************************
+func isUnmerged(status string) bool {
+	switch status {
+	case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
+		return true
+	}
+	return false
+}
+
+// ****************************************************************************
+// isMarker()
+// ****************************************************************************
+func isMarker(line string, c byte) bool {
+	marker := strings.Repeat(string(c), 7)
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+// ****************************************************************************
+// parseConflicts()
+// ****************************************************************************
+func parseConflicts(lines []string) []conflict {
+	var list []conflict
+	cur := conflict{Start: -1, Base: -1, Mid: -1}
+	for i, l := range lines {
+		switch {
+		case isMarker(l, '<'):
+			cur = conflict{Start: i, Base: -1, Mid: -1}
+		case cur.Start < 0:
+			continue
+		case isMarker(l, '|') && cur.Mid < 0:
+			cur.Base = i
+		case isMarker(l, '=') && cur.Mid < 0:
+			cur.Mid = i
+		case isMarker(l, '>') && cur.Mid >= 0:
+			cur.End = i
+			list = append(list, cur)
+			cur = conflict{Start: -1, Base: -1, Mid: -1}
+		}
+	}
+	return list
+}
+
+// ****************************************************************************
+// bufferConflicts()
+// bufferConflicts returns the conflicts of buf, parsed again once it has changed
+// ****************************************************************************
+func bufferConflicts(buf *femto.Buffer) []conflict {
+	if buf == nil {
+		return nil
+	}
+	if conflicts.buf == buf && conflicts.undo == buf.UndoStack.Len() && conflicts.redo == buf.RedoStack.Len() &&
+		conflicts.lines == buf.NumLines {
+		return conflicts.conflicts
+	}
+	conflicts = conflictCache{
+		buf:       buf,
+		undo:      buf.UndoStack.Len(),
+		redo:      buf.RedoStack.Len(),
+		lines:     buf.NumLines,
+		conflicts: parseConflicts(buf.Lines(0, buf.NumLines)),
+	}
+	return conflicts.conflicts
+}
+
+// ****************************************************************************
+// isConflicted()
+// isConflicted tells if GIT reported the file of buf as unmerged
+// ****************************************************************************
+func isConflicted(buf *femto.Buffer) bool {
+	for _, f := range OpenFiles {
+		if f.Buffer == buf {
+			return isUnmerged(f.GitFileStatus)
+		}
+	}
+	return false
+}
+
+// ****************************************************************************
+// conflictsDecorator()
+// conflictsDecorator paints the "ours", base and "theirs" sections
+// ****************************************************************************
+func conflictsDecorator(buf *femto.Buffer, top int, bottom int) ([]ui.Highlight, []ui.GutterMark) {
+	if !isConflicted(buf) {
+		return nil, nil
+	}
+	var highlights []ui.Highlight
+	var marks []ui.GutterMark
+	paint := func(from int, to int, style tcell.Style) {
+		for y := from; y < to; y++ {
+			if y >= top && y < bottom {
+				highlights = append(highlights, ui.Highlight{Line: y, Style: style, Full: true})
+			}
+		}
+	}
+	for _, c := range bufferConflicts(buf) {
+		if c.End < top || c.Start >= bottom {
+			continue
+		}
+		ours := c.Mid
+		if c.Base >= 0 {
+			ours = c.Base
+			paint(c.Base+1, c.Mid, styleBase)
+		}
+		paint(c.Start+1, ours, styleOurs)
+		paint(c.Mid+1, c.End, styleTheirs)
+		for _, y := range []int{c.Start, c.Base, c.Mid, c.End} {
+			if y >= 0 {
+				paint(y, y+1, styleMarker)
+			}
+		}
+		marks = append(marks, ui.GutterMark{Line: c.Start, Symbol: '⚡', Style: tcell.StyleDefault.Foreground(tcell.ColorRed)})
+	}
+	return highlights, marks
+}
+
+// ****************************************************************************
+// NextConflict()
+// ****************************************************************************
+func NextConflict(f any) {
+	list := bufferConflicts(CurrentFile.Buffer)
+	if len(list) == 0 {
+		ui.SetStatus("No conflict")
+		return
+	}
+	y := CurrentFile.Buffer.Cursor.Y
+	for i, c := range list {
+		if c.Start > y {
+			gotoConflict(i, list)
+			return
+		}
+	}
+	gotoConflict(0, list)
+}
+
+// ****************************************************************************
+// PreviousConflict()
+// ****************************************************************************
+func PreviousConflict(f any) {
+	list := bufferConflicts(CurrentFile.Buffer)
+	if len(list) == 0 {
+		ui.SetStatus("No conflict")
+		return
+	}
+	y := CurrentFile.Buffer.Cursor.Y
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Start < y {
+			gotoConflict(i, list)
+			return
+		}
+	}
+	gotoConflict(len(list)-1, list)
+}
+
+// ****************************************************************************
+// gotoConflict()
+// ****************************************************************************
+func gotoConflict(i int, list []conflict) {
+	GotoLine(list[i].Start, 0)
+	ui.SetStatus(fmt.Sprintf("Conflict %d/%d at line %d", i+1, len(list), list[i].Start+1))
+}
+
+// ****************************************************************************
+// AcceptOurs()
+// ****************************************************************************
+func AcceptOurs(f any) {
+	acceptConflict(ACCEPT_OURS)
+}
+
+// ****************************************************************************
+// AcceptTheirs()
+// ****************************************************************************
+func AcceptTheirs(f any) {
+	acceptConflict(ACCEPT_THEIRS)
+}
+
+// ****************************************************************************
+// AcceptBoth()
+// ****************************************************************************
+func AcceptBoth(f any) {
+	acceptConflict(ACCEPT_BOTH)
+}
+
+// ****************************************************************************
+// acceptConflict()
+// acceptConflict replaces the conflict under the cursor by the chosen side(s)
+// ****************************************************************************
+func acceptConflict(side int) {
+	buf := CurrentFile.Buffer
+	if buf == nil {
+		return
+	}
+	y := buf.Cursor.Y
+	for _, c := range bufferConflicts(buf) {
+		if y < c.Start || y > c.End {
+			continue
+		}
+		ours := c.Mid
+		if c.Base >= 0 {
+			ours = c.Base
+		}
+		var lines []string
+		if side != ACCEPT_THEIRS {
+			lines = append(lines, buf.Lines(c.Start+1, ours)...)
+		}
+		if side != ACCEPT_OURS {
+			lines = append(lines, buf.Lines(c.Mid+1, c.End)...)
+		}
+		replaceLines(buf, c.Start, c.End+1, lines)
+		GotoLine(c.Start, 0)
+		left := len(bufferConflicts(buf))
+		ui.SetStatus(fmt.Sprintf("Conflict resolved, %d left", left))
+		return
+	}
+	ui.SetStatus("No conflict at the cursor")
+}
+
+// ****************************************************************************
+// replaceLines()
+// replaceLines puts lines in place of the lines [start, end) of buf
+// ****************************************************************************
+func replaceLines(buf *femto.Buffer, start int, end int, lines []string) {
+	text := strings.Join(lines, "\n")
+	switch {
+	case end < buf.NumLines:
+		if len(lines) > 0 {
+			text += "\n"
+		}
+		buf.Replace(femto.Loc{X: 0, Y: start}, femto.Loc{X: 0, Y: end}, text)
+	case start > 0:
+		// The lines end the buffer, which has no final newline
+		if len(lines) > 0 {
+			text = "\n" + text
+		}
+		prev := femto.Loc{X: utf8.RuneCountInString(buf.Line(start - 1)), Y: start - 1}
+		buf.Replace(prev, buf.End(), text)
+	default:
+		buf.Replace(buf.Start(), buf.End(), text)
+	}
+}
+
+// ****************************************************************************
+// MarkResolved()
+// MarkResolved saves the current file and stages it, once no marker is left
+// ****************************************************************************
+func MarkResolved(f any) {
+	buf := CurrentFile.Buffer
+	if buf == nil {
+		return
+	}
+	if left := len(bufferConflicts(buf)); left > 0 {
+		ui.SetStatus(fmt.Sprintf("%d conflict(s) left into %s", left, CurrentFile.FName))
+		return
+	}
+	repo, rel, err := fileInRepo(CurrentFile.FName)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	if !buf.Modified() {
+		stageResolved(repo, rel)
+		return
+	}
+	saveFile(CurrentFile, CurrentFile.FName, func(ok bool) {
+		if ok {
+			stageResolved(repo, rel)
+		}
+	})
+}
+
+// ****************************************************************************
+// stageResolved()
+// ****************************************************************************
+func stageResolved(repo string, rel string) {
+	if _, err := runGit(repo, "", "add", "--", rel); err != nil {
+		showGitError("Mark resolved", err)
+		return
+	}
+	refreshGitInfos()
+	ui.SetStatus(fmt.Sprintf("%s marked as resolved", filepath.Base(rel)))
+}
@@ -0,0 +1,78 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ****************************************************************************
+// TestParseConflicts()
+// ****************************************************************************
+func TestParseConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []conflict
+	}{
+		{"none", "a\nb\n", nil},
+		{
+			"merge",
+			"a\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> branch\nb",
+			[]conflict{{Start: 1, Base: -1, Mid: 3, End: 5}},
+		},
+		{
+			"diff3",
+			"<<<<<<< HEAD\nours\n||||||| base\nbase\n=======\ntheirs\n>>>>>>> branch",
+			[]conflict{{Start: 0, Base: 2, Mid: 4, End: 6}},
+		},
+		{
+			"two conflicts",
+			"<<<<<<<\n=======\n>>>>>>>\nx\n<<<<<<< HEAD\no\n=======\nt\n>>>>>>> b",
+			[]conflict{{Start: 0, Base: -1, Mid: 1, End: 2}, {Start: 4, Base: -1, Mid: 6, End: 8}},
+		},
+		{
+			"unfinished",
+			"<<<<<<< HEAD\nours\n=======\ntheirs",
+			nil,
+		},
+		{
+			"end before the middle",
+			"<<<<<<< HEAD\nours\n>>>>>>> branch\n=======",
+			nil,
+		},
+		{
+			"restarted",
+			"<<<<<<< HEAD\nstray\n<<<<<<< HEAD\no\n=======\nt\n>>>>>>> b",
+			[]conflict{{Start: 2, Base: -1, Mid: 4, End: 6}},
+		},
+		{
+			"not markers",
+			"<<<<<<<< eight\n<<<<<<<x\n=======\n>>>>>>>",
+			nil,
+		},
+		{
+			"separator into theirs",
+			"<<<<<<< HEAD\no\n=======\nt\n=======\n>>>>>>> b",
+			[]conflict{{Start: 0, Base: -1, Mid: 2, End: 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseConflicts(strings.Split(tt.text, "\n")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseConflicts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/ui"
+	"lied/utils"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// gitHunk is a group of changed lines, [Start, End) in the buffer, Old being
+// the replaced lines of the base. A deletion has Start == End.
+type gitHunk struct {
+	Start int
+	End   int
+	Old   []string
+	Kind  byte
+}
+
+// gutterCache keeps the hunks of a buffer until it is edited. Every edit, its
+// undo and its redo change the length or the top of the undo stack.
+type gutterCache struct {
+	buf   *femto.Buffer
+	undo  int
+	top   *femto.TextEvent
+	base  string
+	hunks []gitHunk
+}
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	HUNK_ADDED    = 'A'
+	HUNK_MODIFIED = 'M'
+	HUNK_DELETED  = 'D'
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	gitBases   = make(map[string]string) // Index (or HEAD) text of the tracked files
+	gitBasesMu sync.Mutex
+	gutter     gutterCache
+)
+
+// ****************************************************************************
+// loadGitBase()
+// loadGitBase reads the staged (or committed) version of fName
+// ****************************************************************************
+func loadGitBase(fName string) (string, bool) {
+	repo, err := repoOf(filepath.Dir(fName))
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(repo, fName)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	content, err := runGit(repo, "", "show", ":"+rel)
+	if err != nil {
+		content, err = runGit(repo, "", "show", "HEAD:"+rel)
+		if err != nil {
+			return "", false
+		}
+	}
+	text, _ := decodeText([]byte(content))
+	return text, true
+}
+
+// ****************************************************************************
+// RefreshGitBase()
+// RefreshGitBase reloads the base of fName in the background
+// ****************************************************************************
+func RefreshGitBase(fName string) {
+	fName, _ = filepath.Abs(fName)
+	go func() {
+		base, ok := loadGitBase(fName)
+		gitBasesMu.Lock()
+		if ok {
+			gitBases[fName] = base
+		} else {
+			delete(gitBases, fName)
+		}
+		gitBasesMu.Unlock()
+		ui.App.QueueUpdateDraw(func() {})
+	}()
+}
+
+// ****************************************************************************
+// GitGutter()
+// GitGutter registers the decorators of the changes and of the merge
+// conflicts, the bases being reloaded by WatchRepos when the index moves
+// ****************************************************************************
+func GitGutter() {
+	ui.SetDecorator("gitgutter", gitGutterDecorator)
+	ui.SetDecorator("conflicts", conflictsDecorator)
+}
+
+// ****************************************************************************
+// computeHunks()
+// ****************************************************************************
+func computeHunks(base string, text string) []gitHunk {
+	if base != "" && !strings.HasSuffix(base, "\n") {
+		base += "\n"
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	var hunks []gitHunk
+	var h *gitHunk
+	newN := 0
+	for _, l := range utils.DiffLines(base, text) {
+		if l.Op == utils.DIFF_EQUAL {
+			if h != nil {
+				hunks = append(hunks, *h)
+				h = nil
+			}
+			newN++
+			continue
+		}
+		if h == nil {
+			h = &gitHunk{Start: newN, End: newN}
+		}
+		if l.Op == utils.DIFF_DELETE {
+			h.Old = append(h.Old, l.Text)
+		} else {
+			h.End++
+			newN++
+		}
+	}
+	if h != nil {
+		hunks = append(hunks, *h)
+	}
+	for i := range hunks {
+		switch {
+		case len(hunks[i].Old) == 0:
+			hunks[i].Kind = HUNK_ADDED
+		case hunks[i].Start == hunks[i].End:
+			hunks[i].Kind = HUNK_DELETED
+		default:
+			hunks[i].Kind = HUNK_MODIFIED
+		}
+	}
+	return hunks
+}
+
+// ****************************************************************************
+// currentHunks()
+// currentHunks returns the hunks of buf, computed again once it has changed
+// ****************************************************************************
+func currentHunks(buf *femto.Buffer) []gitHunk {
+	if buf == nil {
+		return nil
+	}
+	path, _ := filepath.Abs(buf.Path)
+	gitBasesMu.Lock()
+	base, ok := gitBases[path]
+	gitBasesMu.Unlock()
+	if !ok {
+		return nil
+	}
+	if gutter.buf == buf && gutter.undo == buf.UndoStack.Len() && gutter.top == buf.UndoStack.Peek() && gutter.base == base {
+		return gutter.hunks
+	}
+	gutter = gutterCache{
+		buf:   buf,
+		undo:  buf.UndoStack.Len(),
+		top:   buf.UndoStack.Peek(),
+		base:  base,
+		hunks: computeHunks(base, buf.String()),
+	}
+	return gutter.hunks
+}
+
+// ****************************************************************************
+// gitGutterDecorator()
+// gitGutterDecorator marks the added, modified and deleted lines
+// ****************************************************************************
+func gitGutterDecorator(buf *femto.Buffer, top int, bottom int) ([]ui.Highlight, []ui.GutterMark) {
+	var marks []ui.GutterMark
+	for _, h := range currentHunks(buf) {
+		switch h.Kind {
+		case HUNK_DELETED:
+			line := h.Start
+			if line > 0 {
+				line--
+			}
+			marks = append(marks, ui.GutterMark{Line: line, Symbol: '▁', Style: tcell.StyleDefault.Foreground(tcell.ColorRed)})
+		default:
+			color := utils.If(h.Kind == HUNK_ADDED, tcell.ColorGreen, tcell.ColorDodgerBlue)
+			for y := h.Start; y < h.End; y++ {
+				if y >= top && y < bottom {
+					marks = append(marks, ui.GutterMark{Line: y, Symbol: '▎', Style: tcell.StyleDefault.Foreground(color)})
+				}
+			}
+		}
+	}
+	return nil, marks
+}
+
+// ****************************************************************************
+// NextChange()
+// ****************************************************************************
+func NextChange(f any) {
+	hunks := currentHunks(CurrentFile.Buffer)
+	if len(hunks) == 0 {
+		ui.SetStatus("No change")
+		return
+	}
+	y := CurrentFile.Buffer.Cursor.Y
+	for _, h := range hunks {
+		if h.Start > y {
+			gotoHunk(h)
+			return
+		}
+	}
+	gotoHunk(hunks[0])
+}
+
+// ****************************************************************************
+// PreviousChange()
+// ****************************************************************************
+func PreviousChange(f any) {
+	hunks := currentHunks(CurrentFile.Buffer)
+	if len(hunks) == 0 {
+		ui.SetStatus("No change")
+		return
+	}
+	y := CurrentFile.Buffer.Cursor.Y
+	for i := len(hunks) - 1; i >= 0; i-- {
+		if hunks[i].Start < y {
+			gotoHunk(hunks[i])
+			return
+		}
+	}
+	gotoHunk(hunks[len(hunks)-1])
+}
+
+// ****************************************************************************
+// gotoHunk()
+// ****************************************************************************
+func gotoHunk(h gitHunk) {
+	GotoLine(h.Start, 0)
+	ui.SetStatus(fmt.Sprintf("%s at line %d", hunkLabel(h), h.Start+1))
+}
+
+// ****************************************************************************
+// hunkLabel()
+// ****************************************************************************
+func hunkLabel(h gitHunk) string {
+	switch h.Kind {
+	case HUNK_ADDED:
+		return fmt.Sprintf("%d line(s) added", h.End-h.Start)
+	case HUNK_DELETED:
+		return fmt.Sprintf("%d line(s) deleted", len(h.Old))
+	}
+	return fmt.Sprintf("%d line(s) modified", h.End-h.Start)
+}
+
+// ****************************************************************************
+// hunkAtCursor()
+// ****************************************************************************
+func hunkAtCursor() (gitHunk, bool) {
+	y := CurrentFile.Buffer.Cursor.Y
+	for _, h := range currentHunks(CurrentFile.Buffer) {
+		if (y >= h.Start && y < h.End) || (h.Kind == HUNK_DELETED && (y == h.Start || y == h.Start-1)) {
+			return h, true
+		}
+	}
+	return gitHunk{}, false
+}
+
+// ****************************************************************************
+// ShowHunk()
+// ShowHunk pops up the original text of the hunk under the cursor
+// ****************************************************************************
+func ShowHunk(f any) {
+	if CurrentFile.Buffer == nil {
+		return
+	}
+	h, ok := hunkAtCursor()
+	if !ok {
+		ui.SetStatus("No change at the cursor")
+		return
+	}
+	var sb strings.Builder
+	for _, l := range h.Old {
+		sb.WriteString("[red]-" + tview.Escape(l) + "[-]\n")
+	}
+	for _, l := range CurrentFile.Buffer.Lines(h.Start, h.End) {
+		sb.WriteString("[green]+" + tview.Escape(l) + "[-]\n")
+	}
+	parent := ui.GetCurrentScreen()
+	closeHunk := func() {
+		ui.PgsApp.SwitchToPage(parent)
+		ui.App.SetFocus(ui.EdtMain)
+	}
+	txt := tview.NewTextView().SetDynamicColors(true).SetWrap(false).SetText(sb.String())
+	form := tview.NewForm().
+		AddButton("Revert", func() {
+			closeHunk()
+			revertHunk(h)
+		}).
+		AddButton("Close", closeHunk)
+	form.SetButtonsAlign(tview.AlignCenter)
+	frame := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(txt, 0, 1, false).
+		AddItem(form, 3, 0, true)
+	frame.SetBorder(true).SetTitle(fmt.Sprintf(" %s at line %d ", hunkLabel(h), h.Start+1))
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeHunk()
+			return nil
+		}
+		return event
+	})
+	_, _, sw, sh := ui.PgsApp.GetRect()
+	height := len(h.Old) + h.End - h.Start + 6
+	if height > sh*3/4 {
+		height = sh * 3 / 4
+	}
+	popup := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(frame, height, 1, true).
+			AddItem(nil, 0, 1, false), sw*3/4, 1, true).
+		AddItem(nil, 0, 1, false)
+	ui.PgsApp.AddPage("dlgHunk", popup, true, false)
+	ui.PgsApp.ShowPage("dlgHunk")
+}
+
+// ****************************************************************************
+// revertHunk()
+// revertHunk puts the original lines of the hunk back into the buffer
+// ****************************************************************************
+func revertHunk(h gitHunk) {
+	replaceLines(CurrentFile.Buffer, h.Start, h.End, h.Old)
+	GotoLine(h.Start, 0)
+	ui.SetStatus(fmt.Sprintf("Reverted %s at line %d", hunkLabel(h), h.Start+1))
+}
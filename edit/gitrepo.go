@@ -0,0 +1,272 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"io/fs"
+	"lied/conf"
+	"lied/ui"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// repoState is the cached status of a repository, refreshed by WatchRepos
+// once its HEAD, index or refs have changed (or when it is touched)
+type repoState struct {
+	Root    string
+	GitDir  string
+	Branch  string
+	Commit  string
+	Files   map[string]string // "git status -s" code of the changed files, by relative path
+	Loaded  bool
+	stamp   string    // Signature of HEAD, index and refs
+	due     time.Time // The status is run once this deadline is over
+	pending bool
+	gen     int  // Bumped on every change, a refresh clears pending only if none came during its run
+	bases   bool // The index moved, the gutter bases are to be reloaded
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	repos    = make(map[string]*repoState) // By root
+	dirRoots = make(map[string]string)     // Root of a directory, "" if none
+	reposMu  sync.Mutex
+)
+
+// ****************************************************************************
+// rootOf()
+// rootOf returns the state of the repository holding dir, nil if none. A new
+// repository is registered and its status is asked for.
+// ****************************************************************************
+func rootOf(dir string) *repoState {
+	reposMu.Lock()
+	root, known := dirRoots[dir]
+	reposMu.Unlock()
+	if !known {
+		out, err := runGit(dir, "", "rev-parse", "--show-toplevel", "--absolute-git-dir")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if err == nil && len(lines) == 2 {
+			root = filepath.FromSlash(lines[0])
+		}
+		reposMu.Lock()
+		dirRoots[dir] = root
+		if root != "" && repos[root] == nil {
+			repos[root] = &repoState{Root: root, GitDir: filepath.FromSlash(lines[1]), pending: true, bases: true}
+		}
+		reposMu.Unlock()
+	}
+	if root == "" {
+		return nil
+	}
+	reposMu.Lock()
+	defer reposMu.Unlock()
+	return repos[root]
+}
+
+// ****************************************************************************
+// forgetRepos()
+// forgetRepos drops what is known of the directories, when a repository has
+// been created
+// ****************************************************************************
+func forgetRepos() {
+	reposMu.Lock()
+	dirRoots = make(map[string]string)
+	reposMu.Unlock()
+}
+
+// ****************************************************************************
+// touchRepo()
+// touchRepo asks for the status of the repository holding fName (a file or a
+// directory) to be refreshed, after a save or a GIT command
+// ****************************************************************************
+func touchRepo(fName string) {
+	if fName == "" {
+		return
+	}
+	dir := fName
+	if fi, err := os.Stat(fName); err != nil || !fi.IsDir() {
+		dir = filepath.Dir(fName)
+	}
+	r := rootOf(dir)
+	if r == nil {
+		return
+	}
+	reposMu.Lock()
+	r.pending = true
+	r.gen++
+	r.due = time.Now().Add(conf.GIT_DEBOUNCE * time.Millisecond)
+	reposMu.Unlock()
+}
+
+// ****************************************************************************
+// touchAllRepos()
+// ****************************************************************************
+func touchAllRepos() {
+	for _, f := range OpenFiles {
+		touchRepo(f.FName)
+	}
+}
+
+// ****************************************************************************
+// stampOf()
+// stampOf tells when HEAD, the index and the refs were last written
+// ****************************************************************************
+func stampOf(gitDir string) string {
+	var sb strings.Builder
+	add := func(path string, fi fs.FileInfo) {
+		sb.WriteString(fmt.Sprintf("%s:%d:%d;", path, fi.ModTime().UnixNano(), fi.Size()))
+	}
+	for _, name := range []string{"HEAD", "index", "packed-refs", "MERGE_HEAD"} {
+		if fi, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			add(name, fi)
+		}
+	}
+	filepath.WalkDir(filepath.Join(gitDir, "refs", "heads"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				add(path, fi)
+			}
+		}
+		return nil
+	})
+	return sb.String()
+}
+
+// ****************************************************************************
+// WatchRepos()
+// WatchRepos is the go routine which keeps the status of the repositories up
+// to date. Only the .git files are watched, a single "git status" is run once
+// they have settled down, then the open files are updated.
+// ****************************************************************************
+func WatchRepos() {
+	for {
+		time.Sleep(conf.GIT_WATCH_INTERVAL * time.Millisecond)
+		reposMu.Lock()
+		list := make([]*repoState, 0, len(repos))
+		for _, r := range repos {
+			list = append(list, r)
+		}
+		reposMu.Unlock()
+		for _, r := range list {
+			stamp := stampOf(r.GitDir)
+			now := time.Now()
+			reposMu.Lock()
+			if stamp != r.stamp {
+				r.stamp = stamp
+				r.pending = true
+				r.gen++
+				r.bases = true
+				r.due = now.Add(conf.GIT_DEBOUNCE * time.Millisecond)
+			}
+			due := r.pending && !now.Before(r.due)
+			reposMu.Unlock()
+			if due {
+				refreshRepo(r)
+			}
+		}
+	}
+}
+
+// ****************************************************************************
+// refreshRepo()
+// ****************************************************************************
+func refreshRepo(r *repoState) {
+	// What happens from now on is seen by the next refresh
+	reposMu.Lock()
+	gen := r.gen
+	bases := r.bases
+	r.bases = false
+	r.stamp = stampOf(r.GitDir)
+	reposMu.Unlock()
+	out, err := runGit(r.Root, "", "status", "--porcelain=v2", "-z", "--branch", "--untracked-files=all")
+	headers, changes := parseStatus(out)
+	files := make(map[string]string)
+	for _, c := range changes {
+		files[c.Path] = string([]byte{c.X, c.Y})
+	}
+	reposMu.Lock()
+	if r.gen == gen {
+		r.pending = false
+	}
+	if err == nil {
+		r.Files = files
+		r.Branch = headers["branch.head"]
+		if r.Branch == "(detached)" {
+			r.Branch = "HEAD"
+		}
+		r.Commit = shortHash(headers["branch.oid"])
+		if headers["branch.oid"] == "(initial)" {
+			r.Commit = "No commit"
+		}
+		r.Loaded = true
+	}
+	reposMu.Unlock()
+	root := r.Root
+	ui.App.QueueUpdateDraw(func() {
+		for i := range OpenFiles {
+			OpenFiles[i] = UpdateGITInfos(OpenFiles[i])
+			if abs, _ := filepath.Abs(OpenFiles[i].FName); bases && strings.HasPrefix(abs, root+string(os.PathSeparator)) {
+				RefreshGitBase(OpenFiles[i].FName)
+			}
+		}
+		CurrentFile = UpdateGITInfos(CurrentFile)
+	})
+}
+
+// ****************************************************************************
+// UpdateGITInfos()
+// UpdateGITInfos copies the cached status of its repository into f
+// ****************************************************************************
+func UpdateGITInfos(f editfile) editfile {
+	fName, _ := filepath.Abs(f.FName)
+	r := rootOf(filepath.Dir(fName))
+	if r == nil {
+		f.GitCommit = "No GIT"
+		f.GitStatus = "Up to date"
+		f.GitBranch = "Unknown"
+		f.GitFileStatus = "  "
+		return f
+	}
+	reposMu.Lock()
+	defer reposMu.Unlock()
+	if !r.Loaded {
+		return f
+	}
+	f.GitCommit = r.Commit
+	f.GitBranch = r.Branch
+	f.GitStatus = "Up to date"
+	if len(r.Files) > 0 {
+		f.GitStatus = "Pending Commit"
+	}
+	f.GitFileStatus = "  "
+	rel, err := filepath.Rel(r.Root, fName)
+	if err != nil {
+		return f
+	}
+	rel = filepath.ToSlash(rel)
+	if status, ok := r.Files[rel]; ok {
+		f.GitFileStatus = status
+	}
+	return f
+}
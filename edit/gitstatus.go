@@ -0,0 +1,744 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/dialog"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// gitEntry is a row of the status screen : a change, staged or not
+type gitEntry struct {
+	gitChange
+	Staged bool
+}
+
+// diffHunk is a "@@ -a,b +c,d @@" block of a unified diff
+type diffHunk struct {
+	OldStart int
+	NewStart int
+	Head     string // What follows the second @@
+	Lines    []string
+}
+
+// diffPatch is the unified diff of a single file
+type diffPatch struct {
+	Header []string
+	Hunks  []diffHunk
+}
+
+// diffRow tells what a row of the diff table shows, Line is -1 on a hunk header
+type diffRow struct {
+	Hunk int
+	Line int
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	gitRows     []*gitEntry // Rows of the files table, nil on the titles
+	gitShown    *gitEntry   // Entry whose diff is shown
+	gitPatch    diffPatch
+	markedLines = make(map[diffRow]bool)
+	reHunk      = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@(.*)$`)
+	DlgDiscard  *dialog.Dialog
+	discardDo   func() // What the discard dialog confirms
+)
+
+// ****************************************************************************
+// GitStatus()
+// GitStatus opens the screen listing the staged and unstaged files
+// ****************************************************************************
+func GitStatus(f any) {
+	repo, err := currentRepo()
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	gitRepo = repo
+	idx := ui.GetScreenFromTitle("Git")
+	if idx == "NIL" {
+		ui.AddNewScreen(ui.ModeGit, GitSelfInit, nil)
+	} else {
+		i, _ := strconv.Atoi(idx)
+		ui.ShowScreen(i)
+	}
+	refreshGitStatus()
+	ui.App.SetFocus(ui.TblGit)
+}
+
+// ****************************************************************************
+// GitSelfInit()
+// ****************************************************************************
+func GitSelfInit(a any) {
+	ui.TblGit.SetSelectionChangedFunc(func(row int, column int) {
+		showEntryDiff()
+	})
+	ui.TblGit.SetSelectedFunc(func(row int, column int) {
+		openEntry()
+	})
+	ui.TblGit.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(gitFilesKeys(), event)
+	})
+	ui.TblGitDiff.SetSelectedFunc(func(row int, column int) {
+		applyHunk(row)
+	})
+	ui.TblGitDiff.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(gitDiffKeys(), event)
+	})
+}
+
+// ****************************************************************************
+// gitFilesKeys()
+// ****************************************************************************
+func gitFilesKeys() []keys.ScreenKey {
+	return append([]keys.ScreenKey{
+		{Chord: "Space", Label: "Stage file", Done: toggleStaged},
+		{Chord: "Enter", Label: "Open file", Done: openEntry},
+		{Chord: "Alt+D", Label: "Discard file", Done: discardFile},
+		{Chord: "Tab", Label: "Diff", Done: func() { ui.App.SetFocus(ui.TblGitDiff) }},
+	}, gitKeys()...)
+}
+
+// ****************************************************************************
+// gitDiffKeys()
+// ****************************************************************************
+func gitDiffKeys() []keys.ScreenKey {
+	return append([]keys.ScreenKey{
+		{Chord: "Space", Label: "Mark line", Done: markLine},
+		{Chord: "Enter", Label: "Stage hunk", Done: func() {
+			row, _ := ui.TblGitDiff.GetSelection()
+			applyHunk(row)
+		}},
+		{Chord: "Alt+L", Label: "Stage marked lines", Done: applyLines},
+		{Chord: "Alt+D", Label: "Discard hunk or marked lines", Done: discardLines},
+		{Chord: "Tab", Label: "Files", Done: func() { ui.App.SetFocus(ui.TblGit) }},
+	}, gitKeys()...)
+}
+
+// ****************************************************************************
+// gitKeys()
+// gitKeys are the keys shared by the files and the diff of the status screen
+// ****************************************************************************
+func gitKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Alt+C", Label: "Commit…", Done: func() { GitCommit(nil) }},
+		{Chord: "Ctrl+R", Label: "Refresh", Done: refreshGitStatus},
+		{Chord: "Esc", Label: "Editor", Done: ShowEditorScreen},
+	}
+}
+
+// ****************************************************************************
+// openEntry()
+// openEntry opens the file of the selected entry into the editor
+// ****************************************************************************
+func openEntry() {
+	e := selectedEntry()
+	if e == nil {
+		return
+	}
+	if e.X == 'D' || e.Y == 'D' {
+		ui.SetStatus(fmt.Sprintf("%s is deleted", e.Path))
+		return
+	}
+	ShowEditorScreen()
+	OpenFile(filepath.Join(gitRepo, filepath.FromSlash(e.Path)))
+}
+
+// ****************************************************************************
+// refreshGitStatus()
+// refreshGitStatus lists the changes again, keeping the selected entry if any
+// ****************************************************************************
+func refreshGitStatus() {
+	var keep gitEntry
+	if e := selectedEntry(); e != nil {
+		keep = *e
+	}
+	row, _ := ui.TblGit.GetSelection()
+	changes, err := gitChangesOf(gitRepo)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	var staged, unstaged []*gitEntry
+	for _, c := range changes {
+		if c.isStaged() && !c.Unmerged {
+			staged = append(staged, &gitEntry{c, true})
+		}
+		if c.Y != ' ' || c.Unmerged {
+			unstaged = append(unstaged, &gitEntry{c, false})
+		}
+	}
+	gitRows = nil
+	ui.TblGit.Clear()
+	addSection := func(title string, entries []*gitEntry) {
+		r := len(gitRows)
+		ui.TblGit.SetCell(r, 0, tview.NewTableCell(fmt.Sprintf("%s (%d)", title, len(entries))).
+			SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		gitRows = append(gitRows, nil)
+		for _, e := range entries {
+			r = len(gitRows)
+			status := entryStatus(e)
+			color := tcell.ColorRed
+			if e.Staged {
+				color = tcell.ColorGreen
+			}
+			name := e.Path
+			if e.Orig != "" {
+				name = e.Orig + " → " + e.Path
+			}
+			ui.TblGit.SetCell(r, 0, tview.NewTableCell("  "+status).SetTextColor(color))
+			ui.TblGit.SetCell(r, 1, tview.NewTableCell(tview.Escape(name)).SetExpansion(1))
+			gitRows = append(gitRows, e)
+			if e.Path == keep.Path && e.Staged == keep.Staged {
+				row = r
+			}
+		}
+	}
+	addSection("Staged changes", staged)
+	addSection("Changes", unstaged)
+	ui.TblGit.SetTitle(fmt.Sprintf("Status of %s", gitRepo))
+	if row >= len(gitRows) {
+		row = len(gitRows) - 1
+	}
+	for row > 0 && gitRows[row] == nil {
+		row--
+	}
+	if row <= 0 || gitRows[row] == nil {
+		row = firstEntryRow()
+	}
+	ui.TblGit.Select(row, 0)
+	showEntryDiff()
+	if len(changes) == 0 {
+		ui.SetStatus("Nothing to commit, working tree clean")
+	}
+}
+
+// ****************************************************************************
+// entryStatus()
+// entryStatus returns the letter of the side shown by the entry
+// ****************************************************************************
+func entryStatus(e *gitEntry) string {
+	switch {
+	case e.Unmerged:
+		return string([]byte{e.X, e.Y})
+	case e.Staged:
+		return string(e.X)
+	}
+	return string(e.Y)
+}
+
+// ****************************************************************************
+// firstEntryRow()
+// ****************************************************************************
+func firstEntryRow() int {
+	for i, e := range gitRows {
+		if e != nil {
+			return i
+		}
+	}
+	return 0
+}
+
+// ****************************************************************************
+// selectedEntry()
+// ****************************************************************************
+func selectedEntry() *gitEntry {
+	row, _ := ui.TblGit.GetSelection()
+	if row < 0 || row >= len(gitRows) {
+		return nil
+	}
+	return gitRows[row]
+}
+
+// ****************************************************************************
+// toggleStaged()
+// toggleStaged stages or unstages the whole selected file
+// ****************************************************************************
+func toggleStaged() {
+	e := selectedEntry()
+	if e == nil {
+		return
+	}
+	var err error
+	if e.Staged {
+		paths := []string{"reset", "-q", "--", e.Path}
+		if e.Orig != "" {
+			paths = append(paths, e.Orig)
+		}
+		_, err = runGit(gitRepo, "", paths...)
+	} else {
+		_, err = runGit(gitRepo, "", "add", "-A", "--", e.Path)
+	}
+	gitChanged(err)
+}
+
+// ****************************************************************************
+// gitChanged()
+// gitChanged refreshes whatever shows the index, once it has been modified
+// ****************************************************************************
+func gitChanged(err error) {
+	if err != nil {
+		ui.SetStatus(err.Error())
+	}
+	refreshGitStatus()
+	touchRepo(gitRepo)
+}
+
+// ****************************************************************************
+// parseDiff()
+// ****************************************************************************
+func parseDiff(text string) diffPatch {
+	var p diffPatch
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for _, l := range lines {
+		if m := reHunk.FindStringSubmatch(l); m != nil {
+			h := diffHunk{Head: m[3]}
+			h.OldStart, _ = strconv.Atoi(m[1])
+			h.NewStart, _ = strconv.Atoi(m[2])
+			p.Hunks = append(p.Hunks, h)
+			continue
+		}
+		if len(p.Hunks) == 0 {
+			if l != "" {
+				p.Header = append(p.Header, l)
+			}
+			continue
+		}
+		h := &p.Hunks[len(p.Hunks)-1]
+		h.Lines = append(h.Lines, l)
+	}
+	return p
+}
+
+// ****************************************************************************
+// showEntryDiff()
+// ****************************************************************************
+func showEntryDiff() {
+	e := selectedEntry()
+	ui.TblGitDiff.Clear()
+	markedLines = make(map[diffRow]bool)
+	gitShown = e
+	gitPatch = diffPatch{}
+	if e == nil {
+		ui.TblGitDiff.SetTitle("Diff")
+		return
+	}
+	ui.TblGitDiff.SetTitle(fmt.Sprintf("%s %s", utils.If(e.Staged, "Staged", "Unstaged"), e.Path))
+	if e.X == '?' {
+		// Untracked, the whole file is new
+		path := filepath.Join(gitRepo, filepath.FromSlash(e.Path))
+		if utils.IsBinaryFile(path) {
+			ui.TblGitDiff.SetCell(0, 0, tview.NewTableCell("Binary file").SetTextColor(tcell.ColorGray))
+			return
+		}
+		text, err := ReadText(path)
+		if err != nil {
+			ui.SetStatus(err.Error())
+			return
+		}
+		for i, l := range highlightLines(e.Path, strings.Split(strings.TrimSuffix(text, "\n"), "\n")) {
+			ui.TblGitDiff.SetCell(i, 0, diffCell("+", l))
+		}
+		return
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", "-M"}
+	if e.Staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--", e.Path)
+	if e.Orig != "" {
+		args = append(args, e.Orig)
+	}
+	out, err := runGit(gitRepo, "", args...)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	gitPatch = parseDiff(out)
+	renderPatch()
+}
+
+// ****************************************************************************
+// renderPatch()
+// ****************************************************************************
+func renderPatch() {
+	row, _ := ui.TblGitDiff.GetSelection()
+	ui.TblGitDiff.Clear()
+	r := 0
+	for _, l := range gitPatch.Header {
+		ui.TblGitDiff.SetCell(r, 0, tview.NewTableCell(tview.Escape(l)).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+		r++
+	}
+	for hi, h := range gitPatch.Hunks {
+		ui.TblGitDiff.SetCell(r, 0, tview.NewTableCell(tview.Escape(fmt.Sprintf("@@ -%d +%d @@%s", h.OldStart, h.NewStart, h.Head))).
+			SetTextColor(tcell.ColorAqua).SetReference(diffRow{hi, -1}))
+		r++
+		code := make([]string, len(h.Lines))
+		for li, l := range h.Lines {
+			if l != "" && l[0] != '\\' {
+				code[li] = l[1:]
+			}
+		}
+		code = highlightLines(gitShown.Path, code)
+		for li, l := range h.Lines {
+			ref := diffRow{hi, li}
+			var cell *tview.TableCell
+			if l == "" || l[0] == '\\' {
+				cell = tview.NewTableCell(tview.Escape(l)).SetTextColor(tcell.ColorGray)
+			} else {
+				cell = diffCell(l[:1], code[li])
+			}
+			cell.SetReference(ref)
+			if markedLines[ref] {
+				cell.SetBackgroundColor(tcell.ColorDarkSlateGray)
+			}
+			ui.TblGitDiff.SetCell(r, 0, cell)
+			r++
+		}
+	}
+	if len(gitPatch.Hunks) == 0 {
+		ui.TblGitDiff.SetCell(r, 0, tview.NewTableCell(utils.If(isBinaryPatch(gitPatch), "Binary file", "No differences")).
+			SetTextColor(tcell.ColorGray))
+	}
+	ui.TblGitDiff.Select(row, 0)
+}
+
+// ****************************************************************************
+// diffCell()
+// diffCell makes the cell of a line of the diff, its code being already
+// highlighted, on the background of an added or removed line
+// ****************************************************************************
+func diffCell(op string, code string) *tview.TableCell {
+	switch op {
+	case "+":
+		return tview.NewTableCell("[green]+" + code).SetBackgroundColor(tcell.NewRGBColor(0x00, 0x30, 0x00))
+	case "-":
+		return tview.NewTableCell("[red]-" + code).SetBackgroundColor(tcell.NewRGBColor(0x40, 0x00, 0x00))
+	}
+	return tview.NewTableCell(tview.Escape(op) + code)
+}
+
+// ****************************************************************************
+// isBinaryPatch()
+// ****************************************************************************
+func isBinaryPatch(p diffPatch) bool {
+	for _, l := range p.Header {
+		if strings.HasPrefix(l, "Binary files ") || l == "GIT binary patch" {
+			return true
+		}
+	}
+	return false
+}
+
+// ****************************************************************************
+// diffRowAt()
+// ****************************************************************************
+func diffRowAt(row int) (diffRow, bool) {
+	if row < 0 || row >= ui.TblGitDiff.GetRowCount() {
+		return diffRow{}, false
+	}
+	ref := ui.TblGitDiff.GetCell(row, 0).GetReference()
+	if ref == nil {
+		return diffRow{}, false
+	}
+	return ref.(diffRow), true
+}
+
+// ****************************************************************************
+// markLine()
+// markLine toggles the selection of the changed line under the cursor
+// ****************************************************************************
+func markLine() {
+	row, _ := ui.TblGitDiff.GetSelection()
+	d, ok := diffRowAt(row)
+	if !ok || d.Line < 0 {
+		return
+	}
+	l := gitPatch.Hunks[d.Hunk].Lines[d.Line]
+	if !strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "-") {
+		return
+	}
+	markedLines[d] = !markedLines[d]
+	renderPatch()
+	if row+1 < ui.TblGitDiff.GetRowCount() {
+		ui.TblGitDiff.Select(row+1, 0)
+	}
+}
+
+// ****************************************************************************
+// buildPatch()
+// buildPatch keeps the selected lines of the hunk, the other ones are turned
+// into context or dropped, so that the patch still applies. When reverse,
+// the patch is to be applied with -R.
+// ****************************************************************************
+func buildPatch(p diffPatch, hunk int, selected func(int) bool, reverse bool) (string, bool) {
+	h := p.Hunks[hunk]
+	var body []string
+	oldN, newN := 0, 0
+	changed, kept := false, true
+	for i, l := range h.Lines {
+		if l == "" {
+			l = " "
+		}
+		switch l[0] {
+		case '+', '-':
+			keepOp := selected(i)
+			// The side which is in the target file becomes context when not selected
+			context := (l[0] == '-') != reverse
+			switch {
+			case keepOp:
+				changed = true
+			case context:
+				l = " " + l[1:]
+			default:
+				kept = false
+				continue
+			}
+		case '\\':
+			if !kept {
+				continue
+			}
+		}
+		kept = true
+		body = append(body, l)
+		if l[0] == ' ' || l[0] == '-' {
+			oldN++
+		}
+		if l[0] == ' ' || l[0] == '+' {
+			newN++
+		}
+	}
+	if !changed {
+		return "", false
+	}
+	var sb strings.Builder
+	for _, l := range p.Header {
+		sb.WriteString(l + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@%s\n", h.OldStart, oldN, h.NewStart, newN, h.Head))
+	for _, l := range body {
+		sb.WriteString(l + "\n")
+	}
+	return sb.String(), true
+}
+
+// ****************************************************************************
+// applyPatch()
+// applyPatch stages (or unstages, for a staged entry) a part of the diff
+// ****************************************************************************
+func applyPatch(hunk int, selected func(int) bool) {
+	e := gitShown
+	if e == nil {
+		return
+	}
+	if e.X == '?' || e.Unmerged {
+		ui.SetStatus(fmt.Sprintf("Stage or unstage %s as a whole, with Space on the files list", e.Path))
+		return
+	}
+	patch, ok := buildPatch(gitPatch, hunk, selected, e.Staged)
+	if !ok {
+		ui.SetStatus("No changed line selected")
+		return
+	}
+	args := []string{"apply", "--cached", "--recount", "--whitespace=nowarn"}
+	if e.Staged {
+		args = append(args, "-R")
+	}
+	_, err := runGit(gitRepo, patch, append(args, "-")...)
+	if err == nil {
+		ui.SetStatus(fmt.Sprintf("%s %s", utils.If(e.Staged, "Unstaged a part of", "Staged a part of"), e.Path))
+	}
+	gitChanged(err)
+}
+
+// ****************************************************************************
+// applyHunk()
+// ****************************************************************************
+func applyHunk(row int) {
+	d, ok := diffRowAt(row)
+	if !ok {
+		return
+	}
+	applyPatch(d.Hunk, func(int) bool { return true })
+}
+
+// ****************************************************************************
+// applyLines()
+// applyLines stages (or unstages) the marked lines, or the one under the cursor
+// ****************************************************************************
+func applyLines() {
+	row, _ := ui.TblGitDiff.GetSelection()
+	d, ok := diffRowAt(row)
+	if !ok {
+		return
+	}
+	hunk := -1
+	for m, on := range markedLines {
+		if on {
+			if hunk >= 0 && m.Hunk != hunk {
+				ui.SetStatus("The marked lines must belong to a single hunk")
+				return
+			}
+			hunk = m.Hunk
+		}
+	}
+	if hunk < 0 {
+		if d.Line < 0 {
+			return
+		}
+		hunk = d.Hunk
+		markedLines[d] = true
+	}
+	applyPatch(hunk, func(i int) bool { return markedLines[diffRow{hunk, i}] })
+}
+
+// ****************************************************************************
+// proposeDiscard()
+// proposeDiscard asks before throwing away changes of the working tree, they
+// can not be got back
+// ****************************************************************************
+func proposeDiscard(msg string, do func()) {
+	discardDo = do
+	DlgDiscard = DlgDiscard.YesNo("Discard changes", // Title
+		msg, // Message
+		confirmDiscard,
+		0,
+		ui.GetCurrentScreen(), ui.App.GetFocus()) // Focus return
+	ui.PgsApp.AddPage("dlgDiscard", DlgDiscard.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgDiscard")
+}
+
+// ****************************************************************************
+// confirmDiscard()
+// ****************************************************************************
+func confirmDiscard(rc dialog.DlgButton, idx int) {
+	do := discardDo
+	discardDo = nil
+	if rc == dialog.BUTTON_YES && do != nil {
+		do()
+	}
+}
+
+// ****************************************************************************
+// discardFile()
+// discardFile throws away the unstaged changes of the selected file, or the
+// file itself when it is untracked
+// ****************************************************************************
+func discardFile() {
+	e := selectedEntry()
+	if e == nil {
+		return
+	}
+	switch {
+	case e.Staged:
+		ui.SetStatus(fmt.Sprintf("Unstage %s before discarding its changes", e.Path))
+	case e.Unmerged:
+		ui.SetStatus(fmt.Sprintf("%s has conflicts, resolve them first", e.Path))
+	case e.X == '?':
+		proposeDiscard(fmt.Sprintf("Delete the untracked file %s ?", e.Path), func() {
+			err := os.Remove(filepath.Join(gitRepo, filepath.FromSlash(e.Path)))
+			if err == nil {
+				ui.SetStatus(fmt.Sprintf("%s deleted", e.Path))
+			}
+			gitChanged(err)
+		})
+	default:
+		proposeDiscard(fmt.Sprintf("Discard all the changes of %s ?", e.Path), func() {
+			_, err := runGit(gitRepo, "", "checkout", "-q", "--", e.Path)
+			discarded(e.Path, err)
+		})
+	}
+}
+
+// ****************************************************************************
+// discardLines()
+// discardLines throws away the marked lines, or the hunk under the cursor
+// ****************************************************************************
+func discardLines() {
+	e := gitShown
+	row, _ := ui.TblGitDiff.GetSelection()
+	d, ok := diffRowAt(row)
+	if e == nil || !ok {
+		return
+	}
+	if e.Staged || e.X == '?' || e.Unmerged {
+		ui.SetStatus(fmt.Sprintf("Discard the changes of %s as a whole, with Alt+D on the files list", e.Path))
+		return
+	}
+	hunk, what := d.Hunk, "this hunk"
+	selected := func(int) bool { return true }
+	marked := 0
+	for m, on := range markedLines {
+		if on {
+			if marked > 0 && m.Hunk != hunk {
+				ui.SetStatus("The marked lines must belong to a single hunk")
+				return
+			}
+			hunk = m.Hunk
+			marked++
+		}
+	}
+	if marked > 0 {
+		what = fmt.Sprintf("%d marked line(s)", marked)
+		selected = func(i int) bool { return markedLines[diffRow{hunk, i}] }
+	}
+	// Applied in reverse to the working tree
+	patch, ok := buildPatch(gitPatch, hunk, selected, true)
+	if !ok {
+		ui.SetStatus("No changed line selected")
+		return
+	}
+	proposeDiscard(fmt.Sprintf("Discard %s of %s ?", what, e.Path), func() {
+		_, err := runGit(gitRepo, patch, "apply", "-R", "--recount", "--whitespace=nowarn", "-")
+		discarded(e.Path, err)
+	})
+}
+
+// ****************************************************************************
+// discarded()
+// discarded loads again the file whose changes were discarded, if it is open
+// and not modified, the watcher asks for the other ones
+// ****************************************************************************
+func discarded(path string, err error) {
+	if err == nil {
+		fName := filepath.Join(gitRepo, filepath.FromSlash(path))
+		for _, f := range OpenFiles {
+			if f.FName == fName && !f.Buffer.Modified() {
+				current := CurrentFile.FName
+				ReloadFile(fName)
+				SwitchOpenFile(current)
+				break
+			}
+		}
+		ui.SetStatus(fmt.Sprintf("Changes of %s discarded", path))
+	}
+	gitChanged(err)
+}
@@ -0,0 +1,87 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+import "testing"
+
+// ****************************************************************************
+// TestBuildPatch()
+// ****************************************************************************
+func TestBuildPatch(t *testing.T) {
+	const header = "diff --git a/f b/f\nindex 1111111..2222222 100644\n--- a/f\n+++ b/f\n"
+	twoChanges := parseDiff(header + "@@ -1,4 +1,4 @@ func main() {\n a\n-b\n+B\n c\n-d\n+D\n")
+	noEOL := parseDiff(header + "@@ -1 +1 @@\n-b\n\\ No newline at end of file\n+B\n\\ No newline at end of file\n")
+	lines := func(list ...int) func(int) bool {
+		return func(i int) bool {
+			for _, l := range list {
+				if l == i {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	tests := []struct {
+		name     string
+		patch    diffPatch
+		selected func(int) bool
+		reverse  bool
+		want     string
+		ok       bool
+	}{
+		{
+			"whole hunk",
+			twoChanges, lines(1, 2, 4, 5), false,
+			header + "@@ -1,4 +1,4 @@ func main() {\n a\n-b\n+B\n c\n-d\n+D\n",
+			true,
+		},
+		{
+			"first change only",
+			twoChanges, lines(1, 2), false,
+			header + "@@ -1,4 +1,4 @@ func main() {\n a\n-b\n+B\n c\n d\n",
+			true,
+		},
+		{
+			"removal only",
+			twoChanges, lines(4), false,
+			header + "@@ -1,4 +1,3 @@ func main() {\n a\n b\n c\n-d\n",
+			true,
+		},
+		{
+			"first change only, reversed",
+			twoChanges, lines(1, 2), true,
+			header + "@@ -1,4 +1,4 @@ func main() {\n a\n-b\n+B\n c\n D\n",
+			true,
+		},
+		{
+			"addition only, reversed",
+			twoChanges, lines(5), true,
+			header + "@@ -1,3 +1,4 @@ func main() {\n a\n B\n c\n+D\n",
+			true,
+		},
+		{"nothing selected", twoChanges, lines(0, 3), false, "", false},
+		{
+			"no newline of a dropped line",
+			noEOL, lines(0), false,
+			header + "@@ -1,1 +1,0 @@\n-b\n\\ No newline at end of file\n",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := buildPatch(tt.patch, 0, tt.selected, tt.reverse)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("buildPatch() = %q, %v\nwant %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
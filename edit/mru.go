@@ -0,0 +1,156 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"fmt"
+	"lied/conf"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// MRUEntry is a file path or a workspace name, with its last use
+type MRUEntry struct {
+	Time time.Time
+	Path string
+}
+
+// ****************************************************************************
+// mruFile()
+// ****************************************************************************
+func mruFile(name string) string {
+	userDir, _ := os.UserHomeDir()
+	return filepath.Join(userDir, conf.APP_FOLDER, name)
+}
+
+// ****************************************************************************
+// readMRU()
+// ****************************************************************************
+func readMRU(name string) []MRUEntry {
+	var entries []MRUEntry
+	f, err := os.Open(mruFile(name))
+	if err != nil {
+		return entries
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, MRUEntry{t, fields[1]})
+	}
+	return entries
+}
+
+// ****************************************************************************
+// writeMRU()
+// ****************************************************************************
+func writeMRU(name string, entries []MRUEntry) {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("%s\t%s\n", e.Time.Format(time.RFC3339), e.Path))
+	}
+	utils.AtomicWriteFile(mruFile(name), []byte(sb.String()), 0600, nil)
+}
+
+// ****************************************************************************
+// addMRU()
+// addMRU puts path on top of the list, once, and keeps at most MRU_MAX entries
+// ****************************************************************************
+func addMRU(name string, path string) {
+	entries := []MRUEntry{{time.Now(), path}}
+	for _, e := range readMRU(name) {
+		if e.Path != path && len(entries) < conf.MRU_MAX {
+			entries = append(entries, e)
+		}
+	}
+	writeMRU(name, entries)
+}
+
+// ****************************************************************************
+// pruneMRU()
+// pruneMRU drops the entries which no more exist
+// ****************************************************************************
+func pruneMRU(name string, exists func(string) bool) []MRUEntry {
+	var kept []MRUEntry
+	entries := readMRU(name)
+	for _, e := range entries {
+		if exists(e.Path) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) != len(entries) {
+		writeMRU(name, kept)
+	}
+	return kept
+}
+
+// ****************************************************************************
+// addRecentFile()
+// ****************************************************************************
+func addRecentFile(fName string) {
+	if strings.HasPrefix(filepath.Base(fName), conf.NEW_FILE_TEMPLATE) {
+		// Don't remember the temporary files
+		return
+	}
+	addMRU(conf.FILE_MRU_FILES, fName)
+}
+
+// ****************************************************************************
+// addRecentWorkspace()
+// ****************************************************************************
+func addRecentWorkspace(name string) {
+	addMRU(conf.FILE_MRU_WORKSPACES, name)
+}
+
+// ****************************************************************************
+// RecentFiles()
+// ****************************************************************************
+func RecentFiles() []MRUEntry {
+	return pruneMRU(conf.FILE_MRU_FILES, utils.IsFileExist)
+}
+
+// ****************************************************************************
+// RecentWorkspaces()
+// ****************************************************************************
+func RecentWorkspaces() []MRUEntry {
+	return pruneMRU(conf.FILE_MRU_WORKSPACES, func(name string) bool {
+		ws, err := LoadWorkspace(name)
+		return err == nil && utils.IsFileExist(ws.Root)
+	})
+}
+
+// ****************************************************************************
+// OpenRecentWorkspace()
+// ****************************************************************************
+func OpenRecentWorkspace(name any) {
+	if err := OpenNamedWorkspace(name.(string)); err != nil {
+		ui.SetStatus(err.Error())
+	}
+}
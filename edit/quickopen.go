@@ -0,0 +1,253 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/conf"
+	"lied/dialog"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type fileIndex struct {
+	sync.Mutex
+	root   string
+	hidden bool
+	files  []string             // Relative to root
+	dirs   map[string]time.Time // Modification time of the directories walked into
+	stop   chan struct{}
+}
+
+type scoredFile struct {
+	path  string
+	score int
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	index        fileIndex
+	DlgQuickOpen *dialog.Dialog
+)
+
+// ****************************************************************************
+// IndexWorkspace()
+// IndexWorkspace (re)builds the index of the files under root in the background
+// ****************************************************************************
+func IndexWorkspace(root string, hidden bool) {
+	index.Lock()
+	if index.stop != nil {
+		close(index.stop)
+	}
+	stop := make(chan struct{})
+	index.stop = stop
+	if index.root != root {
+		index.root = root
+		index.files = nil
+	}
+	index.hidden = hidden
+	index.Unlock()
+
+	go func() {
+		var files []string
+		dirs := make(map[string]time.Time)
+		utils.WalkTree(root, hidden, stop, func(path string, d os.DirEntry) bool {
+			if d.IsDir() {
+				if fi, err := d.Info(); err == nil {
+					dirs[path] = fi.ModTime()
+				}
+				return true
+			}
+			if rel, err := filepath.Rel(root, path); err == nil {
+				files = append(files, rel)
+			}
+			return len(files) < conf.INDEX_MAX_FILES
+		})
+		sort.Strings(files)
+		index.Lock()
+		if index.stop != stop {
+			// Superseded by another indexing
+			index.Unlock()
+			return
+		}
+		index.files = files
+		index.dirs = dirs
+		index.stop = nil
+		index.Unlock()
+		ui.App.QueueUpdateDraw(func() {
+			DlgQuickOpen.Refilter()
+		})
+	}()
+}
+
+// ****************************************************************************
+// requestIndex()
+// requestIndex indexes root, unless it is already the indexed one
+// ****************************************************************************
+func requestIndex(root string, hidden bool) {
+	index.Lock()
+	same := index.root == root && index.hidden == hidden
+	index.Unlock()
+	if !same {
+		IndexWorkspace(root, hidden)
+	}
+}
+
+// ****************************************************************************
+// RefreshIndex()
+// RefreshIndex is the go routine which keeps the index up to date with the
+// disk : lied updates it itself through touchIndex, so the tree is only walked
+// again when one of its directories has been changed by another process
+// ****************************************************************************
+func RefreshIndex() {
+	for {
+		time.Sleep(conf.INDEX_REFRESH * time.Second)
+		index.Lock()
+		root, hidden, busy := index.root, index.hidden, index.stop != nil
+		dirs := make(map[string]time.Time, len(index.dirs))
+		for dir, t := range index.dirs {
+			dirs[dir] = t
+		}
+		index.Unlock()
+		if root != "" && !busy && dirsChanged(dirs) {
+			IndexWorkspace(root, hidden)
+		}
+	}
+}
+
+// ****************************************************************************
+// dirsChanged()
+// dirsChanged tells if an entry has been added to, removed from or renamed
+// into one of the directories since they were walked
+// ****************************************************************************
+func dirsChanged(dirs map[string]time.Time) bool {
+	for dir, t := range dirs {
+		fi, err := os.Stat(dir)
+		if err != nil || !fi.ModTime().Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ****************************************************************************
+// touchIndex()
+// touchIndex adds or removes path from the index once lied itself created,
+// saved or deleted it, so that the change of its directory does not make the
+// whole tree walked again
+// ****************************************************************************
+func touchIndex(path string) {
+	index.Lock()
+	defer index.Unlock()
+	if index.root == "" || index.stop != nil {
+		// A walk is running, the next check will see what it missed
+		return
+	}
+	dir := filepath.Dir(path)
+	if _, ok := index.dirs[dir]; !ok {
+		return
+	}
+	if fi, err := os.Stat(dir); err == nil {
+		index.dirs[dir] = fi.ModTime()
+	}
+	rel, err := filepath.Rel(index.root, path)
+	if err != nil {
+		return
+	}
+	_, err = os.Stat(path)
+	exists := err == nil
+	i := sort.SearchStrings(index.files, rel)
+	found := i < len(index.files) && index.files[i] == rel
+	// filterIndex reads the slice without the lock, a new one is made
+	switch {
+	case exists && !found && len(index.files) < conf.INDEX_MAX_FILES && !utils.IsSkipped(index.root, path, index.hidden):
+		files := make([]string, 0, len(index.files)+1)
+		files = append(files, index.files[:i]...)
+		files = append(files, rel)
+		index.files = append(files, index.files[i:]...)
+	case !exists && found:
+		files := make([]string, 0, len(index.files)-1)
+		files = append(files, index.files[:i]...)
+		index.files = append(files, index.files[i+1:]...)
+	}
+}
+
+// ****************************************************************************
+// filterIndex()
+// ****************************************************************************
+func filterIndex(query string) []string {
+	index.Lock()
+	files := index.files
+	index.Unlock()
+	pattern := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	var hits []scoredFile
+	for _, f := range files {
+		if score, ok := utils.FuzzyScore(pattern, f); ok {
+			hits = append(hits, scoredFile{f, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return len(hits[i].path) < len(hits[j].path)
+	})
+	var out []string
+	for i := 0; i < len(hits) && i < conf.QUICKOPEN_MAX; i++ {
+		out = append(out, hits[i].path)
+	}
+	return out
+}
+
+// ****************************************************************************
+// QuickOpen()
+// QuickOpen pops up the fuzzy "Go to file" of the workspace
+// ****************************************************************************
+func QuickOpen(f any) {
+	requestIndex(explorerRoot(), showHidden)
+	DlgQuickOpen = DlgQuickOpen.Filter(fmt.Sprintf(" Go to file in %s ", explorerRoot()), // Title
+		"> ", // Message
+		filterIndex,
+		doQuickOpen,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgQuickOpen", DlgQuickOpen.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgQuickOpen")
+}
+
+// ****************************************************************************
+// doQuickOpen()
+// ****************************************************************************
+func doQuickOpen(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK || DlgQuickOpen.Value == "" {
+		return
+	}
+	index.Lock()
+	fName := filepath.Join(index.root, DlgQuickOpen.Value)
+	index.Unlock()
+	OpenFile(fName)
+	ShowEditorScreen()
+}
@@ -0,0 +1,42 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"lied/conf"
+	"lied/keys"
+)
+
+// ****************************************************************************
+// RegisterScreenKeys()
+// RegisterScreenKeys registers the keys of the screens, their key bars are
+// made from the same tables as their input captures
+// ****************************************************************************
+func RegisterScreenKeys() {
+	keys.AddScreen("Search", searchInputKeys, searchHitsKeys)
+	keys.AddScreen("Shell", shellInputKeys, shellOutputKeys)
+	keys.AddScreen("Problems", problemsListKeys, problemsOutputKeys)
+	keys.AddScreen("Git", gitFilesKeys, gitDiffKeys)
+	keys.AddScreen("Blame", blameKeys)
+	keys.AddScreen("History", historyKeys)
+	keys.AddScreen("Diff", diffKeys)
+	conf.SKEY_LABELS = keys.ScreenLabels("Search")
+	conf.HKEY_LABELS = keys.ScreenLabels("Shell")
+	conf.PKEY_LABELS = keys.ScreenLabels("Problems")
+	conf.GKEY_LABELS = keys.ScreenLabels("Git")
+	conf.BKEY_LABELS = keys.ScreenLabels("Blame")
+	conf.YKEY_LABELS = keys.ScreenLabels("History")
+	conf.DKEY_LABELS = keys.ScreenLabels("Diff")
+}
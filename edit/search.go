@@ -0,0 +1,279 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"fmt"
+	"lied/conf"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type searchHit struct {
+	path string
+	line int
+	col  int
+	text string
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	searchStop chan struct{}
+)
+
+// ****************************************************************************
+// SwitchSearch()
+// SwitchSearch toggles between the editor and the workspace search screen
+// ****************************************************************************
+func SwitchSearch(f any) {
+	if ui.CurrentMode == ui.ModeSearch {
+		ShowEditorScreen()
+		return
+	}
+	idx := ui.GetScreenFromTitle("Search")
+	if idx == "NIL" {
+		ui.AddNewScreen(ui.ModeSearch, SearchSelfInit, nil)
+	} else {
+		i, _ := strconv.Atoi(idx)
+		ui.ShowScreen(i)
+		setSearchTitle()
+	}
+	ui.App.SetFocus(ui.InpSearch)
+}
+
+// ****************************************************************************
+// SearchSelfInit()
+// ****************************************************************************
+func SearchSelfInit(a any) {
+	ui.InpSearch.SetText(findText)
+	ui.InpSearch.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(searchInputKeys(), event)
+	})
+	ui.TblSearch.SetSelectedFunc(func(row int, column int) {
+		openSearchHit(row)
+	})
+	ui.TblSearch.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(searchHitsKeys(), event)
+	})
+	setSearchTitle()
+}
+
+// ****************************************************************************
+// searchInputKeys()
+// ****************************************************************************
+func searchInputKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Enter", Label: "Search", Done: func() { RunSearch(ui.InpSearch.GetText()) }},
+		{Chord: "Alt+R", Label: "Regex", Done: func() { toggleSearchOption(&FindOptions.Regex) }},
+		{Chord: "Alt+C", Label: "Case", Done: func() { toggleSearchOption(&FindOptions.CaseSensitive) }},
+		{Chord: "Alt+W", Label: "Word", Done: func() { toggleSearchOption(&FindOptions.WholeWord) }},
+		{Chord: "Down", Label: "Results", Done: func() { ui.App.SetFocus(ui.TblSearch) }},
+		{Chord: "Tab", Label: "Results", Done: func() { ui.App.SetFocus(ui.TblSearch) }},
+		{Chord: "Esc", Label: "Editor", Done: ShowEditorScreen},
+	}
+}
+
+// ****************************************************************************
+// searchHitsKeys()
+// ****************************************************************************
+func searchHitsKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Enter", Label: "Open", Done: func() {
+			row, _ := ui.TblSearch.GetSelection()
+			openSearchHit(row)
+		}},
+		{Chord: "Tab", Label: "Search field", Done: func() { ui.App.SetFocus(ui.InpSearch) }},
+		{Chord: "Esc", Label: "Search field", Done: func() { ui.App.SetFocus(ui.InpSearch) }},
+	}
+}
+
+// ****************************************************************************
+// toggleSearchOption()
+// ****************************************************************************
+func toggleSearchOption(option *bool) {
+	*option = !*option
+	setSearchTitle()
+}
+
+// ****************************************************************************
+// setSearchTitle()
+// ****************************************************************************
+func setSearchTitle() {
+	ui.InpSearch.SetTitle(fmt.Sprintf(" %s  [Regex %s] [Case %s] [Word %s] ",
+		CurrentWorkspace,
+		utils.If(FindOptions.Regex, "✓", "✗"),
+		utils.If(FindOptions.CaseSensitive, "✓", "✗"),
+		utils.If(FindOptions.WholeWord, "✓", "✗")))
+}
+
+// ****************************************************************************
+// RunSearch()
+// RunSearch greps every text file of the workspace, streaming the hits
+// ****************************************************************************
+func RunSearch(pattern string) {
+	re, err := CompileSearch(pattern, FindOptions)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	if searchStop != nil {
+		close(searchStop)
+	}
+	stop := make(chan struct{})
+	searchStop = stop
+	opt := FindOptions
+	root := CurrentWorkspace
+	findText = pattern
+
+	ui.TblSearch.Clear()
+	ui.TblSearch.SetFixed(1, 0)
+	for i, h := range []string{"File", "Line", "Match"} {
+		ui.TblSearch.SetCell(0, i, tview.NewTableCell(h).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	ui.TblSearch.SetTitle("Results")
+	ui.PleaseWait()
+
+	go func() {
+		var found int64
+		var wg sync.WaitGroup
+		paths := make(chan string, 64)
+		for i := 0; i < runtime.NumCPU(); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for p := range paths {
+					hits := grepFile(p, re, opt, stop)
+					if len(hits) == 0 {
+						continue
+					}
+					atomic.AddInt64(&found, int64(len(hits)))
+					ui.App.QueueUpdateDraw(func() {
+						if searchStop == stop {
+							appendSearchHits(root, hits)
+						}
+					})
+				}
+			}()
+		}
+		utils.WalkFiles(root, showHidden, stop, func(p string) bool {
+			select {
+			case paths <- p:
+				return atomic.LoadInt64(&found) < conf.SEARCH_MAX_RESULTS
+			case <-stop:
+				return false
+			}
+		})
+		close(paths)
+		wg.Wait()
+		ui.App.QueueUpdateDraw(func() {
+			if searchStop != stop {
+				return
+			}
+			ui.JobsDone()
+			n := ui.TblSearch.GetRowCount() - 1
+			ui.SetStatus(fmt.Sprintf("%d match(es) for '%s' in %s", n, pattern, root))
+			if n > 0 {
+				ui.TblSearch.Select(1, 0)
+			}
+		})
+	}()
+}
+
+// ****************************************************************************
+// grepFile()
+// ****************************************************************************
+func grepFile(path string, re *regexp.Regexp, opt SearchOptions, stop chan struct{}) []searchHit {
+	var hits []searchHit
+	if utils.IsBinaryFile(path) {
+		return hits
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return hits
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for y := 0; scanner.Scan(); y++ {
+		if y%1000 == 0 {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+		}
+		line := scanner.Text()
+		if matches := findInLine(re, y, line, opt); len(matches) > 0 {
+			text := strings.TrimSpace(line)
+			if r := []rune(text); len(r) > 200 {
+				text = string(r[:200]) + "…"
+			}
+			hits = append(hits, searchHit{path: path, line: y, col: matches[0].X1, text: text})
+		}
+	}
+	return hits
+}
+
+// ****************************************************************************
+// appendSearchHits()
+// ****************************************************************************
+func appendSearchHits(root string, hits []searchHit) {
+	for _, h := range hits {
+		row := ui.TblSearch.GetRowCount()
+		if row > conf.SEARCH_MAX_RESULTS {
+			break
+		}
+		rel, err := filepath.Rel(root, h.path)
+		if err != nil {
+			rel = h.path
+		}
+		ui.TblSearch.SetCell(row, 0, tview.NewTableCell(tview.Escape(rel)).SetTextColor(tcell.ColorGreen).SetReference(h))
+		ui.TblSearch.SetCell(row, 1, tview.NewTableCell(strconv.Itoa(h.line+1)).SetAlign(tview.AlignRight))
+		ui.TblSearch.SetCell(row, 2, tview.NewTableCell(tview.Escape(h.text)))
+	}
+	ui.TblSearch.SetTitle(fmt.Sprintf("Results (%d)", ui.TblSearch.GetRowCount()-1))
+}
+
+// ****************************************************************************
+// openSearchHit()
+// ****************************************************************************
+func openSearchHit(row int) {
+	ref := ui.TblSearch.GetCell(row, 0).GetReference()
+	if ref == nil {
+		return
+	}
+	h := ref.(searchHit)
+	ShowEditorScreen()
+	OpenFile(h.path)
+	GotoLine(h.line, h.col)
+}
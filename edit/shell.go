@@ -0,0 +1,379 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"lied/conf"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	ShellHistory []string
+	historyPos   int
+	shellCancel  context.CancelFunc
+	shellOutput  strings.Builder
+)
+
+// ****************************************************************************
+// SwitchShell()
+// SwitchShell toggles between the editor and the shell screen
+// ****************************************************************************
+func SwitchShell(f any) {
+	if ui.CurrentMode == ui.ModeShell {
+		ShowEditorScreen()
+		return
+	}
+	showShellScreen()
+}
+
+// ****************************************************************************
+// showShellScreen()
+// ****************************************************************************
+func showShellScreen() {
+	idx := ui.GetScreenFromTitle("Shell")
+	if idx == "NIL" {
+		ui.AddNewScreen(ui.ModeShell, ShellSelfInit, nil)
+	} else {
+		i, _ := strconv.Atoi(idx)
+		ui.ShowScreen(i)
+	}
+	ui.InpShell.SetTitle(" " + CurrentWorkspace + " ")
+	ui.App.SetFocus(ui.InpShell)
+}
+
+// ****************************************************************************
+// ShellSelfInit()
+// ****************************************************************************
+func ShellSelfInit(a any) {
+	loadShellHistory()
+	ui.InpShell.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(shellInputKeys(), event)
+	})
+	ui.TxtShell.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(shellOutputKeys(), event)
+	})
+}
+
+// ****************************************************************************
+// shellInputKeys()
+// ****************************************************************************
+func shellInputKeys() []keys.ScreenKey {
+	return append([]keys.ScreenKey{
+		{Chord: "Enter", Label: "Run", Done: func() {
+			if cmdline := strings.TrimSpace(ui.InpShell.GetText()); cmdline != "" {
+				ui.InpShell.SetText("")
+				RunShell(cmdline)
+			}
+		}},
+		{Chord: "Up", Label: "History", Done: func() { browseHistory(-1) }},
+		{Chord: "Down", Label: "History", Done: func() { browseHistory(1) }},
+		{Chord: "Tab", Label: "Output", Done: func() { ui.App.SetFocus(ui.TxtShell) }},
+		{Chord: "Esc", Label: "Editor", Done: ShowEditorScreen},
+	}, shellKeys()...)
+}
+
+// ****************************************************************************
+// shellOutputKeys()
+// ****************************************************************************
+func shellOutputKeys() []keys.ScreenKey {
+	return append([]keys.ScreenKey{
+		{Chord: "Tab", Label: "Command", Done: func() { ui.App.SetFocus(ui.InpShell) }},
+		{Chord: "Esc", Label: "Command", Done: func() { ui.App.SetFocus(ui.InpShell) }},
+	}, shellKeys()...)
+}
+
+// ****************************************************************************
+// shellKeys()
+// shellKeys are the keys shared by the input and the output of the shell
+// ****************************************************************************
+func shellKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Ctrl+K", Label: "Kill", Done: func() { KillShell(nil) }},
+		{Chord: "Alt+I", Label: "Insert output", Done: func() { InsertShellOutput(nil) }},
+		{Chord: "Alt+B", Label: "Output to buffer", Done: func() { ShellOutputToBuffer(nil) }},
+	}
+}
+
+// ****************************************************************************
+// RunShell()
+// RunShell runs cmdline into the current workspace, streaming its output
+// ****************************************************************************
+func RunShell(cmdline string) {
+	runShellIn(cmdline, CurrentWorkspace)
+}
+
+// ****************************************************************************
+// runShellIn()
+// ****************************************************************************
+func runShellIn(cmdline string, dir string) {
+	if ui.CurrentMode != ui.ModeShell {
+		showShellScreen()
+	}
+	if shellCancel != nil {
+		ui.SetStatus("A command is already running, Ctrl+K to kill it")
+		return
+	}
+	addShellHistory(cmdline)
+	shellOutput.Reset()
+	ui.TxtShell.Clear()
+	ui.TxtShell.SetTitle(" $ " + cmdline + " ")
+	ui.LblRC.SetText("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := utils.ShellCommand(ctx, cmdline)
+	cmd.Dir = dir
+	stdout, err1 := cmd.StdoutPipe()
+	stderr, err2 := cmd.StderrPipe()
+	if err := firstError(err1, err2, cmd.Start()); err != nil {
+		cancel()
+		appendShell(err.Error(), true)
+		shellDone(-1, false)
+		return
+	}
+	shellCancel = cancel
+	ui.LblPID.SetText(fmt.Sprintf("PID %d", cmd.Process.Pid))
+	ui.PleaseWait()
+	ui.SetStatus(fmt.Sprintf("Running %s", cmdline))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go streamShell(stdout, false, &wg)
+	go streamShell(stderr, true, &wg)
+	go func() {
+		wg.Wait()
+		err := cmd.Wait()
+		rc := cmd.ProcessState.ExitCode()
+		if err != nil && rc == 0 {
+			rc = -1
+		}
+		killed := ctx.Err() != nil
+		cancel()
+		ui.App.QueueUpdateDraw(func() {
+			shellDone(rc, killed)
+		})
+	}()
+}
+
+// ****************************************************************************
+// firstError()
+// ****************************************************************************
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ****************************************************************************
+// streamShell()
+// ****************************************************************************
+func streamShell(r io.Reader, isErr bool, wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ui.App.QueueUpdateDraw(func() {
+			appendShell(line, isErr)
+		})
+	}
+}
+
+// ****************************************************************************
+// appendShell()
+// ****************************************************************************
+func appendShell(line string, isErr bool) {
+	shellOutput.WriteString(line + "\n")
+	text := tview.Escape(line)
+	if isErr {
+		text = "[red]" + text + "[-]"
+	}
+	fmt.Fprintln(ui.TxtShell, text)
+	ui.TxtShell.ScrollToEnd()
+}
+
+// ****************************************************************************
+// shellDone()
+// ****************************************************************************
+func shellDone(rc int, killed bool) {
+	shellCancel = nil
+	ui.LblPID.SetText("")
+	ui.LblRC.SetText(fmt.Sprintf("%sRC=%d", utils.If(rc == 0, "[green]", "[red]"), rc))
+	ui.JobsDone()
+	if killed {
+		ui.SetStatus("Command killed")
+	} else {
+		ui.SetStatus(fmt.Sprintf("Command ended with RC=%d", rc))
+	}
+}
+
+// ****************************************************************************
+// KillShell()
+// ****************************************************************************
+func KillShell(f any) {
+	if shellCancel == nil {
+		ui.SetStatus("No command is running")
+		return
+	}
+	shellCancel()
+}
+
+// ****************************************************************************
+// InsertShellOutput()
+// InsertShellOutput inserts the output of the last command at the cursor
+// ****************************************************************************
+func InsertShellOutput(f any) {
+	if shellOutput.Len() == 0 {
+		ui.SetStatus("No output to insert")
+		return
+	}
+	ShowEditorScreen()
+	CurrentFile.Buffer.Insert(CurrentFile.Buffer.Cursor.Loc, shellOutput.String())
+	ui.SetStatus("Output inserted")
+}
+
+// ****************************************************************************
+// ReadShell()
+// ReadShell runs cmdline into the current workspace and inserts its output at
+// the cursor of the current file
+// ****************************************************************************
+func ReadShell(cmdline string) {
+	buf := CurrentFile.Buffer
+	if buf == nil {
+		return
+	}
+	ui.PleaseWait()
+	ui.SetStatus(fmt.Sprintf("Running %s", cmdline))
+	cmd := utils.ShellCommand(context.Background(), cmdline)
+	cmd.Dir = CurrentWorkspace
+	go func() {
+		out, err := cmd.Output()
+		ui.App.QueueUpdateDraw(func() {
+			ui.JobsDone()
+			if err != nil && len(out) == 0 {
+				ui.SetStatus(fmt.Sprintf("%s : %s", cmdline, err.Error()))
+				return
+			}
+			buf.Insert(buf.Cursor.Loc, string(out))
+			ui.SetStatus(fmt.Sprintf("Output of %s inserted", cmdline))
+		})
+	}()
+}
+
+// ****************************************************************************
+// ShellOutputToBuffer()
+// ShellOutputToBuffer opens the output of the last command as a new file
+// ****************************************************************************
+func ShellOutputToBuffer(f any) {
+	if shellOutput.Len() == 0 {
+		ui.SetStatus("No output to open")
+		return
+	}
+	out := shellOutput.String()
+	ShowEditorScreen()
+	NewFile(CurrentWorkspace)
+	CurrentFile.Buffer.Insert(CurrentFile.Buffer.Start(), out)
+	GotoLine(0, 0)
+}
+
+// ****************************************************************************
+// browseHistory()
+// ****************************************************************************
+func browseHistory(delta int) {
+	if len(ShellHistory) == 0 {
+		return
+	}
+	historyPos += delta
+	if historyPos < 0 {
+		historyPos = 0
+	}
+	if historyPos >= len(ShellHistory) {
+		historyPos = len(ShellHistory)
+		ui.InpShell.SetText("")
+		return
+	}
+	ui.InpShell.SetText(ShellHistory[historyPos])
+}
+
+// ****************************************************************************
+// historyFile()
+// ****************************************************************************
+func historyFile() string {
+	userDir, _ := os.UserHomeDir()
+	return filepath.Join(userDir, conf.APP_FOLDER, conf.FILE_SHELL_HISTORY)
+}
+
+// ****************************************************************************
+// loadShellHistory()
+// ****************************************************************************
+func loadShellHistory() {
+	ShellHistory = nil
+	f, err := os.Open(historyFile())
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			ShellHistory = append(ShellHistory, scanner.Text())
+		}
+	}
+	historyPos = len(ShellHistory)
+}
+
+// ****************************************************************************
+// addShellHistory()
+// ****************************************************************************
+func addShellHistory(cmdline string) {
+	if len(ShellHistory) == 0 || ShellHistory[len(ShellHistory)-1] != cmdline {
+		ShellHistory = append(ShellHistory, cmdline)
+	}
+	if len(ShellHistory) > conf.SHELL_HISTORY_MAX {
+		ShellHistory = ShellHistory[len(ShellHistory)-conf.SHELL_HISTORY_MAX:]
+	}
+	historyPos = len(ShellHistory)
+	data := strings.Join(ShellHistory, "\n") + "\n"
+	utils.AtomicWriteFile(historyFile(), []byte(data), 0600, nil)
+}
+
+// ****************************************************************************
+// LastShellCommand()
+// ****************************************************************************
+func LastShellCommand() string {
+	if ShellHistory == nil {
+		loadShellHistory()
+	}
+	if len(ShellHistory) == 0 {
+		return ""
+	}
+	return ShellHistory[len(ShellHistory)-1]
+}
@@ -0,0 +1,262 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"lied/conf"
+	"lied/dialog"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type swapFile struct {
+	swap    string
+	path    string
+	text    string
+	modTime time.Time
+}
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	SWAP_MAGIC = "LIED-SWAP-1"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	pendingSwaps []swapFile
+	swapEdits    = make(map[string]int)
+	swapTime     = make(map[string]time.Time)
+	DlgRecover   *dialog.Dialog
+	// The swap writers run in the background : each write gets a sequence
+	// number and only the last one asked for a file is done, under swapMu
+	swapMu    sync.Mutex
+	swapSeq   = make(map[string]int)
+	swapCount int
+)
+
+// ****************************************************************************
+// swapDir()
+// ****************************************************************************
+func swapDir() string {
+	userDir, _ := os.UserHomeDir()
+	return filepath.Join(userDir, conf.APP_FOLDER, conf.DIR_SWAP)
+}
+
+// ****************************************************************************
+// swapName()
+// ****************************************************************************
+func swapName(fName string) string {
+	sum := sha256.Sum256([]byte(fName))
+	return filepath.Join(swapDir(), hex.EncodeToString(sum[:]))
+}
+
+// ****************************************************************************
+// AutoSave()
+// AutoSave is the go routine which writes the modified buffers to their swap
+// file every SWAP_INTERVAL seconds, or sooner after SWAP_EDITS edits
+// ****************************************************************************
+func AutoSave() {
+	for {
+		time.Sleep(time.Second)
+		ui.App.QueueUpdate(func() {
+			for _, f := range OpenFiles {
+				checkSwap(f)
+			}
+		})
+	}
+}
+
+// ****************************************************************************
+// checkSwap()
+// ****************************************************************************
+func checkSwap(f editfile) {
+	if f.Buffer == nil || !f.Buffer.Modified() {
+		return
+	}
+	edits := f.Buffer.UndoStack.Len() - swapEdits[f.FName]
+	if edits < 0 {
+		edits = -edits
+	}
+	if edits == 0 {
+		return
+	}
+	if edits >= conf.SWAP_EDITS || time.Since(swapTime[f.FName]) >= conf.SWAP_INTERVAL*time.Second {
+		swapEdits[f.FName] = f.Buffer.UndoStack.Len()
+		swapTime[f.FName] = time.Now()
+		swapMu.Lock()
+		swapCount++
+		seq := swapCount
+		swapSeq[f.FName] = seq
+		swapMu.Unlock()
+		go writeSwap(f.FName, f.Buffer.String(), seq)
+	}
+}
+
+// ****************************************************************************
+// writeSwap()
+// writeSwap writes the swap file, unless a newer write was asked for since
+// or the swap has been removed
+// ****************************************************************************
+func writeSwap(fName string, text string, seq int) {
+	swapMu.Lock()
+	defer swapMu.Unlock()
+	if swapSeq[fName] != seq {
+		return
+	}
+	if err := os.MkdirAll(swapDir(), 0700); err != nil {
+		return
+	}
+	data := fmt.Sprintf("%s\n%s\n%d\n%s", SWAP_MAGIC, fName, os.Getpid(), text)
+	utils.AtomicWriteFile(swapName(fName), []byte(data), 0600, nil)
+}
+
+// ****************************************************************************
+// RemoveSwap()
+// RemoveSwap deletes the swap file of fName, once saved or closed
+// ****************************************************************************
+func RemoveSwap(fName string) {
+	swapMu.Lock()
+	// The writes still pending for fName are dropped
+	delete(swapSeq, fName)
+	os.Remove(swapName(fName))
+	swapMu.Unlock()
+	delete(swapEdits, fName)
+	delete(swapTime, fName)
+}
+
+// ****************************************************************************
+// FindSwapFiles()
+// FindSwapFiles looks for swap files left over by a crashed session
+// ****************************************************************************
+func FindSwapFiles() int {
+	pendingSwaps = nil
+	entries, err := os.ReadDir(swapDir())
+	if err != nil {
+		return 0
+	}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		s, pid, err := readSwap(filepath.Join(swapDir(), e.Name()))
+		if err != nil {
+			continue
+		}
+		if pid != os.Getpid() && isProcessAlive(pid) {
+			// Still owned by another running session
+			continue
+		}
+		pendingSwaps = append(pendingSwaps, s)
+	}
+	return len(pendingSwaps)
+}
+
+// ****************************************************************************
+// readSwap()
+// ****************************************************************************
+func readSwap(swap string) (swapFile, int, error) {
+	var s swapFile
+	data, err := os.ReadFile(swap)
+	if err != nil {
+		return s, 0, err
+	}
+	parts := strings.SplitN(string(data), "\n", 4)
+	if len(parts) < 4 || parts[0] != SWAP_MAGIC {
+		return s, 0, fmt.Errorf("%s is not a swap file", swap)
+	}
+	pid, _ := strconv.Atoi(parts[2])
+	s.swap = swap
+	s.path = parts[1]
+	s.text = parts[3]
+	if fi, err := os.Stat(swap); err == nil {
+		s.modTime = fi.ModTime()
+	}
+	return s, pid, nil
+}
+
+// ****************************************************************************
+// isProcessAlive()
+// ****************************************************************************
+func isProcessAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// ****************************************************************************
+// ProposeRecovery()
+// ProposeRecovery asks what to do with each leftover swap file, one by one
+// ****************************************************************************
+func ProposeRecovery() {
+	if len(pendingSwaps) == 0 {
+		return
+	}
+	s := pendingSwaps[0]
+	DlgRecover = DlgRecover.YesNoCancel(fmt.Sprintf("Recover %s (%s)", filepath.Base(s.path), s.modTime.Format(ui.MyConfig.FormatDate+" "+ui.MyConfig.FormatTime)), // Title
+		"Recover the unsaved changes ? (No=Discard, Cancel=Diff)", // Message
+		confirmRecovery,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgRecover", DlgRecover.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgRecover")
+}
+
+// ****************************************************************************
+// confirmRecovery()
+// ****************************************************************************
+func confirmRecovery(rc dialog.DlgButton, idx int) {
+	s := pendingSwaps[0]
+	switch rc {
+	case dialog.BUTTON_YES:
+		pendingSwaps = pendingSwaps[1:]
+		if !utils.IsFileExist(s.path) {
+			os.WriteFile(s.path, nil, conf.NEW_FILE_PERM)
+			touchIndex(s.path)
+		}
+		OpenFile(s.path)
+		if CurrentFile.FName == s.path {
+			CurrentFile.Buffer.ApplyDiff(s.text)
+			ui.SetStatus(fmt.Sprintf("Unsaved changes of %s recovered", s.path))
+		}
+		ProposeRecovery()
+	case dialog.BUTTON_NO:
+		pendingSwaps = pendingSwaps[1:]
+		os.Remove(s.swap)
+		ui.SetStatus(fmt.Sprintf("Unsaved changes of %s discarded", s.path))
+		ProposeRecovery()
+	case dialog.BUTTON_CANCEL:
+		disk, _ := ReadText(s.path)
+		ShowDiff(fmt.Sprintf("%s : on disk ⯈ recovered", s.path), disk, s.text, ProposeRecovery)
+	}
+}
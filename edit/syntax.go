@@ -0,0 +1,149 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+	"github.com/pgavlin/femto/runtime"
+	"github.com/rivo/tview"
+	"github.com/zyedidia/micro/cmd/micro/highlight"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// syntaxFile is a syntax definition of the runtime files, parsed once
+type syntaxFile struct {
+	file     *highlight.File
+	ftdetect [2]*regexp.Regexp
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	syntaxFiles []syntaxFile
+	syntaxDefs  = make(map[string]*highlight.Def) // By file type
+	Colorscheme femto.Colorscheme                 // Of the editor, for the text shown outside of it
+)
+
+// ****************************************************************************
+// loadSyntaxFiles()
+// ****************************************************************************
+func loadSyntaxFiles() {
+	if syntaxFiles != nil {
+		return
+	}
+	syntaxFiles = []syntaxFile{}
+	for _, f := range runtime.Files.ListRuntimeFiles(femto.RTSyntax) {
+		data, err := f.Data()
+		if err != nil {
+			continue
+		}
+		file, err := highlight.ParseFile(data)
+		if err != nil {
+			continue
+		}
+		ftdetect, err := highlight.ParseFtDetect(file)
+		if err != nil {
+			continue
+		}
+		syntaxFiles = append(syntaxFiles, syntaxFile{file, ftdetect})
+	}
+}
+
+// ****************************************************************************
+// syntaxOf()
+// syntaxOf returns the syntax definition of fName, as femto detects it, or
+// nil if there is none
+// ****************************************************************************
+func syntaxOf(fName string, firstLine string) *highlight.Def {
+	loadSyntaxFiles()
+	for _, s := range syntaxFiles {
+		if !highlight.MatchFiletype(s.ftdetect, filepath.Base(fName), []byte(firstLine)) {
+			continue
+		}
+		if def, ok := syntaxDefs[s.file.FileType]; ok {
+			return def
+		}
+		def, err := highlight.ParseDef(s.file, &highlight.Header{FileType: s.file.FileType, FtDetect: s.ftdetect})
+		if err != nil {
+			return nil
+		}
+		files := make([]*highlight.File, 0, len(syntaxFiles))
+		for _, f := range syntaxFiles {
+			files = append(files, f.file)
+		}
+		highlight.ResolveIncludes(def, files)
+		syntaxDefs[s.file.FileType] = def
+		return def
+	}
+	return nil
+}
+
+// ****************************************************************************
+// highlightLines()
+// highlightLines colours the lines of fName with the editor's colorscheme,
+// they come back escaped and with their color tags
+// ****************************************************************************
+func highlightLines(fName string, lines []string) []string {
+	out := make([]string, len(lines))
+	var def *highlight.Def
+	if len(lines) > 0 {
+		def = syntaxOf(fName, lines[0])
+	}
+	if def == nil || Colorscheme == nil {
+		for i, l := range lines {
+			out[i] = tview.Escape(l)
+		}
+		return out
+	}
+	matches := highlight.NewHighlighter(def).HighlightString(strings.Join(lines, "\n"))
+	for i, l := range lines {
+		var sb strings.Builder
+		var run []rune
+		flush := func() {
+			sb.WriteString(tview.Escape(string(run)))
+			run = run[:0]
+		}
+		for pos, r := range []rune(l) {
+			if group, ok := matches[i][pos]; ok {
+				flush()
+				sb.WriteString(colorTag(Colorscheme.GetColor(group.String())))
+			}
+			run = append(run, r)
+		}
+		flush()
+		out[i] = sb.String() + "[-]"
+	}
+	return out
+}
+
+// ****************************************************************************
+// colorTag()
+// ****************************************************************************
+func colorTag(style tcell.Style) string {
+	fg, _, _ := style.Decompose()
+	if fg == tcell.ColorDefault {
+		return "[-]"
+	}
+	return fmt.Sprintf("[#%06x]", fg.Hex())
+}
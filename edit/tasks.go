@@ -0,0 +1,494 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"lied/conf"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// Task is a build/run command defined into a [task.Name] section of a workspace
+type Task struct {
+	Name      string
+	Command   string
+	Directory string
+	Formats   string // Comma separated error formats, all of them if empty
+}
+
+// ErrorFormat extracts a location from an output line, thanks to the named
+// groups file, line, col (optional), type (optional) and msg of its regexp
+type ErrorFormat struct {
+	Name string
+	Re   *regexp.Regexp
+	User bool // Defined into lied.ini
+}
+
+// Problem is a location reported by a task
+type Problem struct {
+	Path string
+	Line int // 0-based
+	Col  int // 0-based
+	Type string
+	Msg  string
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	ErrorFormats = []ErrorFormat{
+		{"go", regexp.MustCompile(`^\s*(?P<file>[^\s:]+\.go):(?P<line>\d+)(?::(?P<col>\d+))?: (?P<msg>.*)$`), false},
+		{"gcc", regexp.MustCompile(`^(?P<file>[^\s:][^:]*):(?P<line>\d+):(?P<col>\d+): (?P<type>fatal error|error|warning|note): (?P<msg>.*)$`), false},
+		{"generic", regexp.MustCompile(`^(?P<file>[^\s:][^:]*):(?P<line>\d+)(?::(?P<col>\d+))?:\s*(?P<msg>.*)$`), false},
+	}
+	problems    []Problem
+	problemIdx  = -1
+	lastTask    *Task
+	taskCancel  context.CancelFunc
+	taskFormats []ErrorFormat
+)
+
+// ****************************************************************************
+// AddErrorFormat()
+// AddErrorFormat defines (or redefines) the named error format
+// ****************************************************************************
+func AddErrorFormat(name string, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("error format %s : %v", name, err)
+	}
+	if re.SubexpIndex("file") < 0 || re.SubexpIndex("line") < 0 {
+		return fmt.Errorf("error format %s : the file and line groups are mandatory", name)
+	}
+	for i := range ErrorFormats {
+		if ErrorFormats[i].Name == name {
+			ErrorFormats[i].Re = re
+			ErrorFormats[i].User = true
+			return nil
+		}
+	}
+	ErrorFormats = append(ErrorFormats, ErrorFormat{name, re, true})
+	return nil
+}
+
+// ****************************************************************************
+// CurrentTasks()
+// CurrentTasks returns the workspace's tasks, or the default ones
+// ****************************************************************************
+func CurrentTasks() []Task {
+	if len(WorkspaceTasks) > 0 {
+		return WorkspaceTasks
+	}
+	return []Task{
+		{Name: "go build", Command: "go build ./...", Directory: "%W%", Formats: "go"},
+		{Name: "go vet", Command: "go vet ./...", Directory: "%W%", Formats: "go"},
+		{Name: "go test", Command: "go test ./...", Directory: "%W%", Formats: "go"},
+		{Name: "make", Command: "make", Directory: "%W%", Formats: "gcc,generic"},
+	}
+}
+
+// ****************************************************************************
+// formatsOf()
+// ****************************************************************************
+func formatsOf(t Task) []ErrorFormat {
+	if strings.TrimSpace(t.Formats) == "" {
+		return ErrorFormats
+	}
+	var formats []ErrorFormat
+	for _, name := range strings.Split(t.Formats, ",") {
+		for _, f := range ErrorFormats {
+			if f.Name == strings.TrimSpace(name) {
+				formats = append(formats, f)
+			}
+		}
+	}
+	return formats
+}
+
+// ****************************************************************************
+// parseProblem()
+// parseProblem looks for a location into line, relative to dir, which exists
+// ****************************************************************************
+func parseProblem(line string, dir string, formats []ErrorFormat) (Problem, bool) {
+	var p Problem
+	for _, f := range formats {
+		m := f.Re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		group := func(name string) string {
+			if i := f.Re.SubexpIndex(name); i >= 0 {
+				return m[i]
+			}
+			return ""
+		}
+		p.Path = group("file")
+		if !filepath.IsAbs(p.Path) {
+			p.Path = filepath.Join(dir, p.Path)
+		}
+		p.Path, _ = filepath.Abs(p.Path)
+		if !utils.IsFileExist(p.Path) {
+			continue
+		}
+		// Locations are 1-based, a missing column is 0
+		p.Line, _ = strconv.Atoi(group("line"))
+		p.Col, _ = strconv.Atoi(group("col"))
+		if p.Line > 0 {
+			p.Line--
+		}
+		if p.Col > 0 {
+			p.Col--
+		}
+		p.Type = utils.If(group("type") == "", "error", group("type"))
+		p.Msg = group("msg")
+		return p, true
+	}
+	return Problem{}, false
+}
+
+// ****************************************************************************
+// RunTask()
+// RunTask runs the task, filling the problems list from its output
+// ****************************************************************************
+func RunTask(t any) {
+	task := t.(Task)
+	if taskCancel != nil {
+		ui.SetStatus("A task is already running, Ctrl+K to kill it")
+		return
+	}
+	lastTask = &task
+	cmdline := ExpandPlaceholders(task.Command)
+	dir := expandPath(utils.If(task.Directory == "", "%W%", task.Directory))
+	taskFormats = formatsOf(task)
+	showProblemsScreen()
+	clearProblems()
+	ui.TxtProblems.SetTitle(" $ " + cmdline + " ")
+	ui.LblRC.SetText("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := utils.ShellCommand(ctx, cmdline)
+	cmd.Dir = dir
+	stdout, err1 := cmd.StdoutPipe()
+	stderr, err2 := cmd.StderrPipe()
+	if err := firstError(err1, err2, cmd.Start()); err != nil {
+		cancel()
+		appendTaskLine(err.Error(), dir, true)
+		taskDone(task.Name, -1, false)
+		return
+	}
+	taskCancel = cancel
+	ui.LblPID.SetText(fmt.Sprintf("PID %d", cmd.Process.Pid))
+	ui.PleaseWait()
+	ui.SetStatus(fmt.Sprintf("Running task %s", task.Name))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go streamTask(stdout, dir, false, &wg)
+	go streamTask(stderr, dir, true, &wg)
+	go func() {
+		wg.Wait()
+		err := cmd.Wait()
+		rc := cmd.ProcessState.ExitCode()
+		if err != nil && rc == 0 {
+			rc = -1
+		}
+		killed := ctx.Err() != nil
+		cancel()
+		ui.App.QueueUpdateDraw(func() {
+			taskDone(task.Name, rc, killed)
+		})
+	}()
+}
+
+// ****************************************************************************
+// RunLastTask()
+// ****************************************************************************
+func RunLastTask(f any) {
+	if lastTask == nil {
+		ui.SetStatus("No task run yet")
+		return
+	}
+	RunTask(*lastTask)
+}
+
+// ****************************************************************************
+// KillTask()
+// ****************************************************************************
+func KillTask(f any) {
+	if taskCancel == nil {
+		ui.SetStatus("No task is running")
+		return
+	}
+	taskCancel()
+}
+
+// ****************************************************************************
+// streamTask()
+// ****************************************************************************
+func streamTask(r io.Reader, dir string, isErr bool, wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ui.App.QueueUpdateDraw(func() {
+			appendTaskLine(line, dir, isErr)
+		})
+	}
+}
+
+// ****************************************************************************
+// appendTaskLine()
+// ****************************************************************************
+func appendTaskLine(line string, dir string, isErr bool) {
+	text := tview.Escape(line)
+	if p, ok := parseProblem(line, dir, taskFormats); ok && len(problems) < conf.PROBLEMS_MAX {
+		problems = append(problems, p)
+		addProblemRow(dir, p)
+		text = fmt.Sprintf("[#%06x]%s[-]", problemColor(p.Type).Hex(), text)
+	} else if isErr {
+		text = "[red]" + text + "[-]"
+	}
+	fmt.Fprintln(ui.TxtProblems, text)
+	ui.TxtProblems.ScrollToEnd()
+}
+
+// ****************************************************************************
+// addProblemRow()
+// ****************************************************************************
+func addProblemRow(dir string, p Problem) {
+	row := ui.TblProblems.GetRowCount()
+	rel, err := filepath.Rel(dir, p.Path)
+	if err != nil || utils.IsOutside(rel) {
+		rel = p.Path
+	}
+	color := problemColor(p.Type)
+	ui.TblProblems.SetCell(row, 0, tview.NewTableCell(p.Type).SetTextColor(color).SetReference(len(problems)-1))
+	ui.TblProblems.SetCell(row, 1, tview.NewTableCell(tview.Escape(rel)).SetTextColor(tcell.ColorGreen))
+	ui.TblProblems.SetCell(row, 2, tview.NewTableCell(fmt.Sprintf("%d:%d", p.Line+1, p.Col+1)).SetAlign(tview.AlignRight))
+	ui.TblProblems.SetCell(row, 3, tview.NewTableCell(tview.Escape(p.Msg)))
+	ui.TblProblems.SetTitle(fmt.Sprintf("Problems (%d)", len(problems)))
+	if row == 1 {
+		ui.TblProblems.Select(1, 0)
+	}
+}
+
+// ****************************************************************************
+// problemColor()
+// ****************************************************************************
+func problemColor(t string) tcell.Color {
+	switch strings.ToLower(t) {
+	case "warning":
+		return tcell.ColorYellow
+	case "note", "info":
+		return tcell.ColorLightBlue
+	}
+	return tcell.ColorRed
+}
+
+// ****************************************************************************
+// taskDone()
+// ****************************************************************************
+func taskDone(name string, rc int, killed bool) {
+	taskCancel = nil
+	ui.LblPID.SetText("")
+	ui.LblRC.SetText(fmt.Sprintf("%sRC=%d", utils.If(rc == 0, "[green]", "[red]"), rc))
+	ui.JobsDone()
+	if killed {
+		ui.SetStatus(fmt.Sprintf("Task %s killed", name))
+	} else {
+		ui.SetStatus(fmt.Sprintf("Task %s ended with RC=%d, %d problem(s)", name, rc, len(problems)))
+	}
+}
+
+// ****************************************************************************
+// clearProblems()
+// ****************************************************************************
+func clearProblems() {
+	problems = nil
+	problemIdx = -1
+	ui.TxtProblems.Clear()
+	ui.TblProblems.Clear()
+	ui.TblProblems.SetFixed(1, 0)
+	for i, h := range []string{"Type", "File", "Line", "Message"} {
+		ui.TblProblems.SetCell(0, i, tview.NewTableCell(h).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	ui.TblProblems.SetTitle("Problems")
+}
+
+// ****************************************************************************
+// SwitchProblems()
+// SwitchProblems toggles between the editor and the problems screen
+// ****************************************************************************
+func SwitchProblems(f any) {
+	if ui.CurrentMode == ui.ModeProblems {
+		ShowEditorScreen()
+		return
+	}
+	showProblemsScreen()
+}
+
+// ****************************************************************************
+// showProblemsScreen()
+// ****************************************************************************
+func showProblemsScreen() {
+	idx := ui.GetScreenFromTitle("Problems")
+	if idx == "NIL" {
+		ui.AddNewScreen(ui.ModeProblems, ProblemsSelfInit, nil)
+	} else {
+		i, _ := strconv.Atoi(idx)
+		ui.ShowScreen(i)
+	}
+	ui.App.SetFocus(ui.TblProblems)
+}
+
+// ****************************************************************************
+// ProblemsSelfInit()
+// ****************************************************************************
+func ProblemsSelfInit(a any) {
+	clearProblems()
+	ui.SetDecorator("problems", problemsDecorator)
+	ui.TblProblems.SetSelectedFunc(func(row int, column int) {
+		gotoProblemRow(row)
+	})
+	ui.TblProblems.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(problemsListKeys(), event)
+	})
+	ui.TxtProblems.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(problemsOutputKeys(), event)
+	})
+}
+
+// ****************************************************************************
+// problemsListKeys()
+// ****************************************************************************
+func problemsListKeys() []keys.ScreenKey {
+	return append([]keys.ScreenKey{
+		{Chord: "Enter", Label: "Go to", Done: func() {
+			row, _ := ui.TblProblems.GetSelection()
+			gotoProblemRow(row)
+		}},
+		{Chord: "Tab", Label: "Output", Done: func() { ui.App.SetFocus(ui.TxtProblems) }},
+		{Chord: "Esc", Label: "Editor", Done: ShowEditorScreen},
+	}, problemsKeys()...)
+}
+
+// ****************************************************************************
+// problemsOutputKeys()
+// ****************************************************************************
+func problemsOutputKeys() []keys.ScreenKey {
+	return append([]keys.ScreenKey{
+		{Chord: "Tab", Label: "Problems", Done: func() { ui.App.SetFocus(ui.TblProblems) }},
+		{Chord: "Esc", Label: "Problems", Done: func() { ui.App.SetFocus(ui.TblProblems) }},
+	}, problemsKeys()...)
+}
+
+// ****************************************************************************
+// problemsKeys()
+// problemsKeys are the keys shared by the problems and the output of the task
+// ****************************************************************************
+func problemsKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Ctrl+K", Label: "Kill", Done: func() { KillTask(nil) }},
+		{Chord: "Ctrl+R", Label: "Run again", Done: func() { RunLastTask(nil) }},
+	}
+}
+
+// ****************************************************************************
+// gotoProblemRow()
+// ****************************************************************************
+func gotoProblemRow(row int) {
+	if ref := ui.TblProblems.GetCell(row, 0).GetReference(); ref != nil {
+		gotoProblem(ref.(int))
+	}
+}
+
+// ****************************************************************************
+// gotoProblem()
+// ****************************************************************************
+func gotoProblem(i int) {
+	if i < 0 || i >= len(problems) {
+		return
+	}
+	problemIdx = i
+	p := problems[i]
+	ui.TblProblems.Select(i+1, 0)
+	ShowEditorScreen()
+	OpenFile(p.Path)
+	GotoLine(p.Line, p.Col)
+	ui.SetStatus(fmt.Sprintf("%d/%d %s", i+1, len(problems), p.Msg))
+}
+
+// ****************************************************************************
+// NextProblem()
+// ****************************************************************************
+func NextProblem(f any) {
+	if len(problems) == 0 {
+		ui.SetStatus("No problem")
+		return
+	}
+	gotoProblem((problemIdx + 1) % len(problems))
+}
+
+// ****************************************************************************
+// PreviousProblem()
+// ****************************************************************************
+func PreviousProblem(f any) {
+	if len(problems) == 0 {
+		ui.SetStatus("No problem")
+		return
+	}
+	gotoProblem((problemIdx - 1 + len(problems)) % len(problems))
+}
+
+// ****************************************************************************
+// problemsDecorator()
+// problemsDecorator marks the lines with problems into the gutter
+// ****************************************************************************
+func problemsDecorator(buf *femto.Buffer, top int, bottom int) ([]ui.Highlight, []ui.GutterMark) {
+	var marks []ui.GutterMark
+	if len(problems) == 0 {
+		return nil, marks
+	}
+	path, err := filepath.Abs(buf.Path)
+	if err != nil {
+		return nil, marks
+	}
+	for _, p := range problems {
+		if p.Line >= top && p.Line < bottom && p.Path == path {
+			style := tcell.StyleDefault.Foreground(problemColor(p.Type))
+			marks = append(marks, ui.GutterMark{Line: p.Line, Symbol: '●', Style: style})
+		}
+	}
+	return nil, marks
+}
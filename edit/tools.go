@@ -0,0 +1,196 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"lied/ui"
+	"lied/utils"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pgavlin/femto"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// Tool is an external command defined into a [tool.Name] section of lied.ini
+type Tool struct {
+	Name      string
+	Command   string
+	Directory string
+	Shortcut  string
+	Output    string
+}
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	TOOL_SHOW    = "Show"
+	TOOL_REPLACE = "Replace"
+	TOOL_INSERT  = "Insert"
+	TOOL_IGNORE  = "Ignore"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	Tools []Tool
+)
+
+// ****************************************************************************
+// ExpandPlaceholders()
+// ExpandPlaceholders replaces into the command line s :
+//
+//	%F% the full file name      %f% the file name without extension
+//	%N% the file name           %E% the extension
+//	%D% the file's directory    %W% the workspace root
+//	%L% the line                %C% the column
+//	%S% the selected text       %% a single %
+//
+// The values are quoted for the shell, the template must not quote them
+// ****************************************************************************
+func ExpandPlaceholders(s string) string {
+	return placeholders(utils.ShellQuote).Replace(s)
+}
+
+// ****************************************************************************
+// expandPath()
+// expandPath replaces the placeholders of a directory, which is not given to
+// the shell
+// ****************************************************************************
+func expandPath(s string) string {
+	return placeholders(func(v string) string { return v }).Replace(s)
+}
+
+// ****************************************************************************
+// placeholders()
+// ****************************************************************************
+func placeholders(quote func(string) string) *strings.Replacer {
+	fName := CurrentFile.FName
+	var selection string
+	var line, col int
+	if CurrentFile.Buffer != nil {
+		selection = CurrentFile.Buffer.Cursor.GetSelection()
+		line = CurrentFile.Buffer.Cursor.Y + 1
+		col = CurrentFile.Buffer.Cursor.X + 1
+	}
+	return strings.NewReplacer(
+		"%%", "%",
+		"%F%", quote(fName),
+		"%f%", quote(utils.FilenameWithoutExtension(filepath.Base(fName))),
+		"%N%", quote(filepath.Base(fName)),
+		"%E%", quote(strings.TrimPrefix(filepath.Ext(fName), ".")),
+		"%D%", quote(filepath.Dir(fName)),
+		"%W%", quote(explorerRoot()),
+		"%L%", strconv.Itoa(line),
+		"%C%", strconv.Itoa(col),
+		"%S%", quote(selection),
+	)
+}
+
+// ****************************************************************************
+// RunTool()
+// RunTool runs the tool, feeding it the selection, and uses its output
+// ****************************************************************************
+func RunTool(t any) {
+	tool := t.(Tool)
+	cmdline := ExpandPlaceholders(tool.Command)
+	dir := expandPath(utils.If(tool.Directory == "", "%D%", tool.Directory))
+	if tool.Output == "" || strings.EqualFold(tool.Output, TOOL_SHOW) {
+		runShellIn(cmdline, dir)
+		return
+	}
+
+	var selection string
+	if CurrentFile.Buffer.Cursor.HasSelection() {
+		selection = CurrentFile.Buffer.Cursor.GetSelection()
+	}
+	// Keep where the output goes, the user may go on editing meanwhile
+	fName := CurrentFile.FName
+	buf := CurrentFile.Buffer
+	edits := buf.UndoStack.Len()
+	sel := buf.Cursor.CurSelection
+	loc := buf.Cursor.Loc
+	ui.PleaseWait()
+	ui.SetStatus(fmt.Sprintf("Running %s", tool.Name))
+	go func() {
+		cmd := utils.ShellCommand(context.Background(), cmdline)
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(selection)
+		var outb, errb bytes.Buffer
+		cmd.Stdout = &outb
+		cmd.Stderr = &errb
+		err := cmd.Run()
+		ui.App.QueueUpdateDraw(func() {
+			ui.JobsDone()
+			if err != nil {
+				msg := strings.TrimSpace(errb.String())
+				if msg == "" {
+					msg = err.Error()
+				}
+				ui.SetStatus(fmt.Sprintf("%s failed : %s", tool.Name, msg))
+				return
+			}
+			if strings.EqualFold(tool.Output, TOOL_IGNORE) {
+				ui.SetStatus(fmt.Sprintf("%s done", tool.Name))
+				return
+			}
+			// The positions are only right into the text the tool started on
+			if !isOpenBuffer(fName, buf) {
+				ui.SetStatus(fmt.Sprintf("%s done, but %s has been closed : output dropped", tool.Name, fName))
+				return
+			}
+			if buf.UndoStack.Len() != edits {
+				ui.SetStatus(fmt.Sprintf("%s done, but %s has been edited meanwhile : output dropped", tool.Name, fName))
+				return
+			}
+			out := outb.String()
+			switch {
+			case strings.EqualFold(tool.Output, TOOL_REPLACE) && selection != "":
+				start, end := sel[0], sel[1]
+				if end.LessThan(start) {
+					start, end = end, start
+				}
+				buf.Replace(start, end, out)
+			case strings.EqualFold(tool.Output, TOOL_REPLACE), strings.EqualFold(tool.Output, TOOL_INSERT):
+				buf.Insert(loc, out)
+			}
+			if CurrentFile.Buffer == buf {
+				ui.EdtMain.Relocate()
+			}
+			ui.SetStatus(fmt.Sprintf("%s done", tool.Name))
+		})
+	}()
+}
+
+// ****************************************************************************
+// isOpenBuffer()
+// isOpenBuffer tells if buf is still the buffer of the open file fName
+// ****************************************************************************
+func isOpenBuffer(fName string, buf *femto.Buffer) bool {
+	for _, f := range OpenFiles {
+		if f.FName == fName && f.Buffer == buf {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"lied/conf"
+	"lied/dialog"
+	"lied/ui"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pgavlin/femto"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// diskState is what we know of a file on disk, to notice external changes.
+// It is only kept up to date into OpenFiles, not into CurrentFile
+type diskState struct {
+	ModTime time.Time
+	Size    int64
+	Hash    string
+	Deleted bool
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	changedFiles    []string
+	promptingReload bool
+	DlgReload       *dialog.Dialog
+	DlgOverwrite    *dialog.Dialog
+	overwriteDone   func(ok bool) // What to do once asked about overwriting
+)
+
+// ****************************************************************************
+// newDiskState()
+// newDiskState describes content, the size is the one read and not the one
+// of the file, which may have grown since (see followFile)
+// ****************************************************************************
+func newDiskState(fName string, content []byte) diskState {
+	var d diskState
+	sum := sha256.Sum256(content)
+	d.Hash = hex.EncodeToString(sum[:])
+	d.Size = int64(len(content))
+	if fi, err := os.Stat(fName); err == nil {
+		d.ModTime = fi.ModTime()
+	}
+	return d
+}
+
+// ****************************************************************************
+// sameStat()
+// ****************************************************************************
+func (d diskState) sameStat(fi os.FileInfo) bool {
+	return !d.Deleted && d.ModTime.Equal(fi.ModTime()) && d.Size == fi.Size()
+}
+
+// ****************************************************************************
+// setDiskState()
+// setDiskState records the content just loaded or saved for fName
+// ****************************************************************************
+func setDiskState(fName string, content []byte) {
+	d := newDiskState(fName, content)
+	for i, f := range OpenFiles {
+		if f.FName == fName {
+			OpenFiles[i].Disk = d
+			OpenFiles[i].Seen = d
+		}
+	}
+}
+
+// ****************************************************************************
+// isChangedOnDisk()
+// isChangedOnDisk tells if the file on disk is no more the one we loaded or saved
+// ****************************************************************************
+func isChangedOnDisk(fName string) bool {
+	var f editfile
+	for _, e := range OpenFiles {
+		if e.FName == fName {
+			f = e
+		}
+	}
+	fi, err := os.Stat(fName)
+	if err != nil || f.Disk.Hash == "" || f.Disk.sameStat(fi) {
+		return false
+	}
+	content, err := ioutil.ReadFile(f.FName)
+	if err != nil {
+		return false
+	}
+	return newDiskState(f.FName, content).Hash != f.Disk.Hash
+}
+
+// ****************************************************************************
+// WatchFiles()
+// WatchFiles is the go routine which polls the open files for external changes
+// ****************************************************************************
+func WatchFiles() {
+	for {
+		time.Sleep(conf.WATCH_INTERVAL * time.Second)
+		ui.App.QueueUpdateDraw(func() {
+			for i := range OpenFiles {
+				checkDiskFile(i)
+			}
+		})
+	}
+}
+
+// ****************************************************************************
+// checkDiskFile()
+// ****************************************************************************
+func checkDiskFile(i int) {
+	f := OpenFiles[i]
+	if f.Buffer == nil || f.Disk.Hash == "" {
+		return
+	}
+	fi, err := os.Stat(f.FName)
+	if err != nil {
+		if os.IsNotExist(err) && !f.Seen.Deleted {
+			OpenFiles[i].Seen = diskState{Deleted: true}
+			// Saving the buffer will write the file again
+			f.Buffer.IsModified = true
+			ui.SetStatus(fmt.Sprintf("File %s has been deleted from disk", f.FName))
+		}
+		return
+	}
+	if f.Disk.sameStat(fi) || f.Seen.sameStat(fi) {
+		return
+	}
+	content, err := ioutil.ReadFile(f.FName)
+	if err != nil {
+		return
+	}
+	d := newDiskState(f.FName, content)
+	if d.Hash == f.Disk.Hash {
+		// Only touched
+		OpenFiles[i].Disk = d
+		OpenFiles[i].Seen = d
+		return
+	}
+	if f.Follow {
+		followFile(i, content, d)
+		return
+	}
+	OpenFiles[i].Seen = d
+	queueReload(f.FName)
+}
+
+// ****************************************************************************
+// queueReload()
+// ****************************************************************************
+func queueReload(fName string) {
+	for _, c := range changedFiles {
+		if c == fName {
+			return
+		}
+	}
+	changedFiles = append(changedFiles, fName)
+	if !promptingReload {
+		proposeReload()
+	}
+}
+
+// ****************************************************************************
+// proposeReload()
+// proposeReload asks what to do with the first file changed by another process
+// ****************************************************************************
+func proposeReload() {
+	if len(changedFiles) == 0 {
+		promptingReload = false
+		return
+	}
+	promptingReload = true
+	fName := changedFiles[0]
+	DlgReload = DlgReload.YesNoCancel(fmt.Sprintf("File %s changed on disk", fName), // Title
+		"Reload it from disk ? (No=Keep mine, Cancel=Diff)", // Message
+		confirmReload,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgReload", DlgReload.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgReload")
+}
+
+// ****************************************************************************
+// confirmReload()
+// ****************************************************************************
+func confirmReload(rc dialog.DlgButton, idx int) {
+	fName := changedFiles[0]
+	switch rc {
+	case dialog.BUTTON_YES:
+		changedFiles = changedFiles[1:]
+		ReloadFile(fName)
+		proposeReload()
+	case dialog.BUTTON_NO:
+		changedFiles = changedFiles[1:]
+		ui.SetStatus(fmt.Sprintf("Changes on disk of %s ignored", fName))
+		proposeReload()
+	case dialog.BUTTON_CANCEL:
+		var mine string
+		for _, f := range OpenFiles {
+			if f.FName == fName {
+				mine = f.Buffer.String()
+			}
+		}
+		disk, _ := ReadText(fName)
+		ShowDiff(fmt.Sprintf("%s : mine ⯈ on disk", fName), mine, disk, proposeReload)
+	}
+}
+
+// ****************************************************************************
+// ReloadFile()
+// ReloadFile replaces the buffer of the file by its content on disk
+// ****************************************************************************
+func ReloadFile(fName string) {
+	if CurrentFile.FName != fName {
+		SwitchOpenFile(fName)
+	}
+	content, err := ioutil.ReadFile(fName)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	bom := bomFor(CurrentFile.Encoding)
+	CurrentFile.BOM = bom != nil && bytes.HasPrefix(content, bom)
+	text, err := decodeContent(content, CurrentFile.Encoding, CurrentFile.BOM)
+	if err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	CurrentFile.LineEnding, CurrentFile.MixedEOL = detectLineEndings(text)
+	replaceBuffer(newBuffer(normalizeNewlines(text), fName))
+	setDiskState(fName, content)
+	RemoveSwap(fName)
+	touchRepo(fName)
+	ui.LblEOL.SetText(eolLabel(CurrentFile))
+	ui.SetStatus(fmt.Sprintf("File %s reloaded", fName))
+}
+
+// ****************************************************************************
+// ReloadAnyFile()
+// ****************************************************************************
+func ReloadAnyFile(f any) {
+	ReloadFile(CurrentFile.FName)
+}
+
+// ****************************************************************************
+// ToggleFollow()
+// ToggleFollow switches the follow mode ("tail -f") of the current file
+// ****************************************************************************
+func ToggleFollow(f any) {
+	CurrentFile.Follow = !CurrentFile.Follow
+	syncCurrentFile()
+	if CurrentFile.Follow {
+		scrollToEnd(CurrentFile)
+		ui.SetStatus(fmt.Sprintf("Following %s", CurrentFile.FName))
+	} else {
+		ui.SetStatus(fmt.Sprintf("No more following %s", CurrentFile.FName))
+	}
+}
+
+// ****************************************************************************
+// followFile()
+// followFile appends the text added on disk, or reloads a truncated file. The
+// whole file is decoded, so that a character cut by the previous read or a
+// CR LF split across two reads come out right.
+// ****************************************************************************
+func followFile(i int, content []byte, d diskState) {
+	f := OpenFiles[i]
+	bom := bomFor(f.Encoding)
+	hasBOM := bom != nil && bytes.HasPrefix(content, bom)
+	raw, _ := decodeContent(content, f.Encoding, hasBOM)
+	text := normalizeNewlines(raw)
+	appended := false
+	if f.Disk.Size <= int64(len(content)) && (!hasBOM || f.Disk.Size >= int64(len(bom))) {
+		before, _ := decodeContent(content[:f.Disk.Size], f.Encoding, hasBOM)
+		if before = normalizeNewlines(before); strings.HasPrefix(text, before) {
+			modified := f.Buffer.IsModified
+			f.Buffer.Insert(f.Buffer.End(), text[len(before):])
+			f.Buffer.IsModified = modified
+			appended = true
+		}
+	}
+	if !appended {
+		// Truncated, rotated or rewritten, start again
+		buf := newBuffer(text, f.FName)
+		OpenFiles[i].Buffer = buf
+		OpenFiles[i].View = femto.NewView(buf)
+		OpenFiles[i].BOM = hasBOM
+		OpenFiles[i].LineEnding, OpenFiles[i].MixedEOL = detectLineEndings(raw)
+		RemoveSwap(f.FName)
+		if CurrentFile.FName == f.FName {
+			CurrentFile.Buffer = buf
+			CurrentFile.View = OpenFiles[i].View
+			CurrentFile.BOM = hasBOM
+			CurrentFile.LineEnding, CurrentFile.MixedEOL = OpenFiles[i].LineEnding, OpenFiles[i].MixedEOL
+			ui.EdtMain.OpenBuffer(buf)
+			ui.LblEOL.SetText(eolLabel(CurrentFile))
+		}
+	}
+	OpenFiles[i].Disk = d
+	OpenFiles[i].Seen = d
+	scrollToEnd(OpenFiles[i])
+}
+
+// ****************************************************************************
+// scrollToEnd()
+// ****************************************************************************
+func scrollToEnd(f editfile) {
+	f.Buffer.Cursor.ResetSelection()
+	f.Buffer.Cursor.GotoLoc(f.Buffer.End())
+	if CurrentFile.FName == f.FName {
+		ui.EdtMain.Relocate()
+	}
+}
+
+// ****************************************************************************
+// proposeToOverwrite()
+// proposeToOverwrite asks before writing over a file changed on disk, then
+// gives the answer to done
+// ****************************************************************************
+func proposeToOverwrite(fName string, done func(ok bool)) {
+	overwriteDone = done
+	DlgOverwrite = DlgOverwrite.YesNo(fmt.Sprintf("Save File %s", fName), // Title
+		"This file has been changed on disk. Overwrite it ?", // Message
+		confirmOverwrite,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgOverwrite", DlgOverwrite.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgOverwrite")
+}
+
+// ****************************************************************************
+// confirmOverwrite()
+// ****************************************************************************
+func confirmOverwrite(rc dialog.DlgButton, idx int) {
+	done := overwriteDone
+	overwriteDone = nil
+	if done != nil {
+		done(rc == dialog.BUTTON_YES)
+	}
+}
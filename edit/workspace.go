@@ -0,0 +1,461 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package edit
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"errors"
+	"fmt"
+	"lied/conf"
+	"lied/dialog"
+	"lied/ui"
+	"lied/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pgavlin/femto"
+	"gopkg.in/ini.v1"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// Workspace is a named session : a root directory and the files opened in it
+type Workspace struct {
+	Name     string
+	Root     string
+	Active   string
+	Files    []WorkspaceFile
+	Expanded []string
+	Tasks    []Task
+}
+
+type WorkspaceFile struct {
+	Path string
+	X    int
+	Y    int
+	Top  int
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	WorkspaceName  string
+	WorkspaceRoot  string
+	WorkspaceTasks []Task
+	expandedDirs   = make(map[string]bool)
+	newWSName      string
+	DlgWorkspace   *dialog.Dialog
+	DlgWorkspaceRm *dialog.Dialog
+)
+
+// ****************************************************************************
+// workspacesDir()
+// ****************************************************************************
+func workspacesDir() string {
+	userDir, _ := os.UserHomeDir()
+	return filepath.Join(userDir, conf.APP_FOLDER, conf.DIR_WORKSPACES)
+}
+
+// ****************************************************************************
+// workspaceFile()
+// ****************************************************************************
+func workspaceFile(name string) string {
+	return filepath.Join(workspacesDir(), name+".ini")
+}
+
+// ****************************************************************************
+// ListWorkspaces()
+// ****************************************************************************
+func ListWorkspaces() []string {
+	var names []string
+	entries, err := os.ReadDir(workspacesDir())
+	if err != nil {
+		return names
+	}
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == ".ini" {
+			names = append(names, strings.TrimSuffix(e.Name(), ".ini"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ****************************************************************************
+// LoadWorkspace()
+// ****************************************************************************
+func LoadWorkspace(name string) (Workspace, error) {
+	var ws Workspace
+	inidata, err := ini.Load(workspaceFile(name))
+	if err != nil {
+		return ws, err
+	}
+	section := inidata.Section("workspace")
+	ws.Name = name
+	ws.Root = section.Key("Root").String()
+	ws.Active = section.Key("Active").String()
+	if expanded := section.Key("Expanded").String(); expanded != "" {
+		ws.Expanded = strings.Split(expanded, string(os.PathListSeparator))
+	}
+	for i := 1; inidata.HasSection(fmt.Sprintf("file%d", i)); i++ {
+		sec := inidata.Section(fmt.Sprintf("file%d", i))
+		var f WorkspaceFile
+		f.Path = sec.Key("Path").String()
+		f.X, _ = sec.Key("X").Int()
+		f.Y, _ = sec.Key("Y").Int()
+		f.Top, _ = sec.Key("Top").Int()
+		ws.Files = append(ws.Files, f)
+	}
+	for _, sec := range inidata.Sections() {
+		if strings.HasPrefix(sec.Name(), "task.") {
+			ws.Tasks = append(ws.Tasks, Task{
+				Name:      strings.TrimPrefix(sec.Name(), "task."),
+				Command:   sec.Key("Command").String(),
+				Directory: sec.Key("Directory").String(),
+				Formats:   sec.Key("Formats").String(),
+			})
+		}
+	}
+	return ws, nil
+}
+
+// ****************************************************************************
+// SaveWorkspace()
+// ****************************************************************************
+func SaveWorkspace(ws Workspace) error {
+	if err := os.MkdirAll(workspacesDir(), 0700); err != nil {
+		return err
+	}
+	inidata := ini.Empty()
+	sec, _ := inidata.NewSection("workspace")
+	sec.NewKey("Root", ws.Root)
+	sec.NewKey("Active", ws.Active)
+	sec.NewKey("Expanded", strings.Join(ws.Expanded, string(os.PathListSeparator)))
+	for i, f := range ws.Files {
+		fsec, _ := inidata.NewSection(fmt.Sprintf("file%d", i+1))
+		fsec.NewKey("Path", f.Path)
+		fsec.NewKey("X", fmt.Sprint(f.X))
+		fsec.NewKey("Y", fmt.Sprint(f.Y))
+		fsec.NewKey("Top", fmt.Sprint(f.Top))
+	}
+	for _, t := range ws.Tasks {
+		tsec, _ := inidata.NewSection("task." + t.Name)
+		tsec.NewKey("Command", t.Command)
+		tsec.NewKey("Directory", t.Directory)
+		tsec.NewKey("Formats", t.Formats)
+	}
+	return inidata.SaveTo(workspaceFile(ws.Name))
+}
+
+// ****************************************************************************
+// currentSession()
+// currentSession takes a snapshot of the open files into a workspace
+// ****************************************************************************
+func currentSession(name string, root string) Workspace {
+	ws := Workspace{Name: name, Root: root, Active: CurrentFile.FName, Tasks: WorkspaceTasks}
+	for _, f := range OpenFiles {
+		wf := WorkspaceFile{Path: f.FName, X: f.Buffer.Cursor.X, Y: f.Buffer.Cursor.Y, Top: f.Top}
+		if f.FName == CurrentFile.FName {
+			wf.Top = ui.EdtMain.Topline
+		}
+		ws.Files = append(ws.Files, wf)
+	}
+	for dir, expanded := range expandedDirs {
+		if expanded {
+			ws.Expanded = append(ws.Expanded, dir)
+		}
+	}
+	sort.Strings(ws.Expanded)
+	return ws
+}
+
+// ****************************************************************************
+// SaveCurrentWorkspace()
+// ****************************************************************************
+func SaveCurrentWorkspace() {
+	if WorkspaceName == "" {
+		return
+	}
+	if err := SaveWorkspace(currentSession(WorkspaceName, WorkspaceRoot)); err != nil {
+		ui.SetStatus(err.Error())
+	}
+}
+
+// ****************************************************************************
+// OpenNamedWorkspace()
+// OpenNamedWorkspace closes the open files and restores the given workspace
+// ****************************************************************************
+func OpenNamedWorkspace(name string) error {
+	ws, err := LoadWorkspace(name)
+	if err != nil {
+		return err
+	}
+	if GetGlobalDirtyFlag() {
+		return errors.New("save or close the modified files first")
+	}
+	SaveCurrentWorkspace()
+	for _, f := range OpenFiles {
+		RemoveSwap(f.FName)
+	}
+	OpenFiles = nil
+	WorkspaceName = ws.Name
+	WorkspaceRoot = ws.Root
+	WorkspaceTasks = ws.Tasks
+	addRecentWorkspace(ws.Name)
+	expandedDirs = make(map[string]bool)
+	for _, dir := range ws.Expanded {
+		expandedDirs[dir] = true
+	}
+	for _, f := range ws.Files {
+		if !utils.IsFileExist(f.Path) {
+			continue
+		}
+		OpenFile(f.Path)
+		for i := range OpenFiles {
+			if OpenFiles[i].FName == f.Path {
+				OpenFiles[i].Buffer.Cursor.GotoLoc(femto.Loc{X: f.X, Y: f.Y})
+				OpenFiles[i].Top = f.Top
+			}
+		}
+	}
+	if len(OpenFiles) == 0 {
+		if len(ui.ArrScreens) > 0 {
+			NewFile(ws.Root)
+		}
+	} else {
+		active := ws.Active
+		if !isFileAlreadyOpen(active) {
+			active = OpenFiles[0].FName
+		}
+		// Don't let SwitchOpenFile keep the scroll of the last opened file
+		CurrentFile.FName = ""
+		SwitchOpenFile(active)
+	}
+	ShowTreeDir(ws.Root, showHidden)
+	ui.TblOpenFiles.SetTitle(fmt.Sprintf("Open Files (%d)", len(OpenFiles)))
+	ui.SetStatus(fmt.Sprintf("Workspace %s opened", ws.Name))
+	return nil
+}
+
+// ****************************************************************************
+// explorerRoot()
+// explorerRoot is the directory shown by the explorer
+// ****************************************************************************
+func explorerRoot() string {
+	if WorkspaceName != "" {
+		return WorkspaceRoot
+	}
+	return CurrentWorkspace
+}
+
+// ****************************************************************************
+// NewWorkspace()
+// ****************************************************************************
+func NewWorkspace(f any) {
+	DlgWorkspace = DlgWorkspace.Input("New Workspace", // Title
+		"Please, enter the name of the new workspace :", // Message
+		filepath.Base(explorerRoot()),
+		askWorkspaceRoot,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgWorkspace", DlgWorkspace.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgWorkspace")
+}
+
+// ****************************************************************************
+// askWorkspaceRoot()
+// ****************************************************************************
+func askWorkspaceRoot(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	newWSName = DlgWorkspace.Value
+	if err := checkWorkspaceName(newWSName); err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	DlgWorkspace = DlgWorkspace.Input("New Workspace", // Title
+		"Please, enter its root directory :", // Message
+		explorerRoot(),
+		doNewWorkspace,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgWorkspace", DlgWorkspace.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgWorkspace")
+}
+
+// ****************************************************************************
+// doNewWorkspace()
+// ****************************************************************************
+func doNewWorkspace(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	root, _ := filepath.Abs(DlgWorkspace.Value)
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		ui.SetStatus(fmt.Sprintf("%s is not a directory", root))
+		return
+	}
+	// The new workspace takes over the current session
+	SaveCurrentWorkspace()
+	WorkspaceName = newWSName
+	WorkspaceRoot = root
+	WorkspaceTasks = nil
+	SaveCurrentWorkspace()
+	addRecentWorkspace(WorkspaceName)
+	ShowTreeDir(root, showHidden)
+	ui.SetStatus(fmt.Sprintf("Workspace %s created", WorkspaceName))
+}
+
+// ****************************************************************************
+// checkWorkspaceName()
+// ****************************************************************************
+func checkWorkspaceName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\:`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("%q is not a valid workspace name", name)
+	}
+	if utils.IsFileExist(workspaceFile(name)) {
+		return fmt.Errorf("workspace %s already exists", name)
+	}
+	return nil
+}
+
+// ****************************************************************************
+// ChooseWorkspace()
+// ****************************************************************************
+func ChooseWorkspace(f any) {
+	names := ListWorkspaces()
+	if len(names) == 0 {
+		ui.SetStatus("No workspace yet, create one first")
+		return
+	}
+	DlgWorkspace = DlgWorkspace.List("Open Workspace", // Title
+		"Please, choose the workspace to open :", // Message
+		names,
+		doOpenWorkspace,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgWorkspace", DlgWorkspace.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgWorkspace")
+}
+
+// ****************************************************************************
+// doOpenWorkspace()
+// ****************************************************************************
+func doOpenWorkspace(rc dialog.DlgButton, idx int) {
+	if rc == dialog.BUTTON_OK {
+		if err := OpenNamedWorkspace(DlgWorkspace.Value); err != nil {
+			ui.SetStatus(err.Error())
+		}
+	}
+}
+
+// ****************************************************************************
+// RenameWorkspace()
+// ****************************************************************************
+func RenameWorkspace(f any) {
+	if WorkspaceName == "" {
+		ui.SetStatus("No workspace is open")
+		return
+	}
+	DlgWorkspace = DlgWorkspace.Input("Rename Workspace", // Title
+		"Please, enter the new name of this workspace :", // Message
+		WorkspaceName,
+		doRenameWorkspace,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgWorkspace", DlgWorkspace.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgWorkspace")
+}
+
+// ****************************************************************************
+// doRenameWorkspace()
+// ****************************************************************************
+func doRenameWorkspace(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK || DlgWorkspace.Value == WorkspaceName {
+		return
+	}
+	name := DlgWorkspace.Value
+	if err := checkWorkspaceName(name); err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	if err := os.Rename(workspaceFile(WorkspaceName), workspaceFile(name)); err != nil && !os.IsNotExist(err) {
+		ui.SetStatus(err.Error())
+		return
+	}
+	ui.SetStatus(fmt.Sprintf("Workspace %s renamed to %s", WorkspaceName, name))
+	WorkspaceName = name
+	SaveCurrentWorkspace()
+	addRecentWorkspace(WorkspaceName)
+}
+
+// ****************************************************************************
+// DeleteWorkspace()
+// ****************************************************************************
+func DeleteWorkspace(f any) {
+	names := ListWorkspaces()
+	if len(names) == 0 {
+		ui.SetStatus("No workspace to delete")
+		return
+	}
+	DlgWorkspace = DlgWorkspace.List("Delete Workspace", // Title
+		"Please, choose the workspace to delete :", // Message
+		names,
+		askDeleteWorkspace,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgWorkspace", DlgWorkspace.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgWorkspace")
+}
+
+// ****************************************************************************
+// askDeleteWorkspace()
+// ****************************************************************************
+func askDeleteWorkspace(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	newWSName = DlgWorkspace.Value
+	DlgWorkspaceRm = DlgWorkspaceRm.YesNo("Delete Workspace", // Title
+		fmt.Sprintf("Delete the workspace %s ? Its files are kept.", newWSName), // Message
+		doDeleteWorkspace,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgWorkspaceRm", DlgWorkspaceRm.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgWorkspaceRm")
+}
+
+// ****************************************************************************
+// doDeleteWorkspace()
+// ****************************************************************************
+func doDeleteWorkspace(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_YES {
+		return
+	}
+	if err := os.Remove(workspaceFile(newWSName)); err != nil {
+		ui.SetStatus(err.Error())
+		return
+	}
+	if newWSName == WorkspaceName {
+		// Go on with the same files, out of any workspace
+		WorkspaceName = ""
+		WorkspaceTasks = nil
+	}
+	ui.SetStatus(fmt.Sprintf("Workspace %s deleted", newWSName))
+}
@@ -4,9 +4,15 @@ go 1.20
 
 require (
 	github.com/gdamore/tcell/v2 v2.6.0
+	github.com/mattn/go-runewidth v0.0.15
 	github.com/pgavlin/femto v0.0.0-20201224065653-0c9d20f9cac4
 	github.com/rivo/tview v0.0.0-20231126152417-33a1d271f2b6
 	github.com/saintfish/chardet v0.0.0-20230101081208-5e3ef4b5456d
+	github.com/sergi/go-diff v1.1.0
+	github.com/zyedidia/micro v1.4.1
+	golang.org/x/crypto v0.17.0
+	golang.org/x/text v0.14.0
+	gopkg.in/ini.v1 v1.67.0
 )
 
 require (
@@ -18,15 +24,10 @@ require (
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/lxn/walk v0.0.0-20191128110447-55ccb3a9f5c1 // indirect
 	github.com/lxn/win v0.0.0-20191128105842-2da648fda5b4 // indirect
-	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
-	github.com/sergi/go-diff v1.1.0 // indirect
-	github.com/zyedidia/micro v1.4.1 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/term v0.15.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
 	gopkg.in/Knetic/govaluate.v3 v3.0.0 // indirect
-	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
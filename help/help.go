@@ -1,272 +1,389 @@
 // ****************************************************************************
 //
-//	 _____ _____ _____ _____
-//	|   __|     |   __|  |  |
-//	|  |  |  |  |__   |     |
-//	|_____|_____|_____|__|__|
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
 //
 // ****************************************************************************
-// G O S H   -   Copyright © JPL 2023
+// L I E D   -   Copyright © JPL 2024
 // ****************************************************************************
 package help
 
-import "lied/ui"
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/conf"
+	"lied/dialog"
+	"lied/edit"
+	"lied/keys"
+	"lied/ui"
+	"lied/utils"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type Topic struct {
+	ID    string
+	Title string
+	Text  func() string // Links to other topics are written {{id}} or {{id|label}}
+}
+
+type searchHit struct {
+	text  string
+	score int
+}
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	OVER_PAGE = "dlgHelpOver"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	DlgSearch  *dialog.Dialog
+	topics     []Topic
+	current    string   // Topic shown
+	history    []string // Topics shown before, for Backspace
+	links      []string // Topic of each link of the page, the index is its region
+	selected   = -1     // Link selected by Tab
+	searchHits = make(map[string]string)
+	// Topic shown by F1, from the context of the key
+	contextTopics = map[string]string{
+		keys.CTX_EDITOR:    "editor",
+		keys.CTX_OPENFILES: "openfiles",
+		keys.CTX_EXPLORER:  "explorer",
+		keys.CTX_DIALOG:    "dialogs",
+	}
+	overBack    tview.Primitive // What had the focus before ShowOver, nil if no topic is shown over
+	linkPattern = regexp.MustCompile(`\{\{([a-z.]+)(?:\|([^}]*))?\}\}`)
+	tagPattern  = regexp.MustCompile(`\[[a-zA-Z0-9_,;: \-\.#"]*\]`)
+)
+
+// ****************************************************************************
+// AddTopic()
+// AddTopic adds a topic to the help, or replaces the one with the same id.
+// The text is made each time the topic is shown, so that it follows the key
+// bindings.
+// ****************************************************************************
+func AddTopic(id string, title string, text func() string) {
+	for i := range topics {
+		if topics[i].ID == id {
+			topics[i] = Topic{id, title, text}
+			return
+		}
+	}
+	topics = append(topics, Topic{id, title, text})
+}
 
 // ****************************************************************************
 // SelfInit()
 // ****************************************************************************
 func SelfInit(a any) {
-	SetHelp()
+	ui.TxtHelp.SetRegions(true).SetWordWrap(true)
+	ui.TxtHelp.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		return keys.HandleScreen(helpKeys(), event)
+	})
+	id, _ := a.(string)
+	Show(utils.If(id == "", "index", id))
 }
 
 // ****************************************************************************
-// SetHelp()
+// ShowHelp()
+// ShowHelp brings the help screen to the front, at the topic id
 // ****************************************************************************
-func SetHelp() {
-	ui.TxtHelp.SetDynamicColors(true).SetText(`[yellow]		 _____ _____ _____ _____
-		|   __|     |   __|  |  |
-		|  |  |  |  |__   |     |
-		|_____|_____|_____|__|__|
-        [red]Copyright jpl@ozf.fr 2024
+func ShowHelp(id string) {
+	idx := ui.GetScreenFromTitle("Help")
+	if idx == "NIL" {
+		ui.AddNewScreen(ui.ModeHelp, SelfInit, id)
+		return
+	}
+	i, _ := strconv.Atoi(idx)
+	ui.ShowScreen(i)
+	Show(id)
+}
 
-[white]Gosh is a TUI (Text User Interface) for common management functions on a Linux system.
-Gosh is written in Go. The main layout interface is inspired by [green]AS400[white] text console.
-		
-	╔════╦═══════════╦═══════╗
-	║ [yellow]F1[white] ║ [red]This Help[white] ║ [yellow]!help[white] ║
-	╚════╩═══════════╩═══════╝
-	
-	╔═══════════════════════════════════════════════════╗
- 	║           [red]Fast access to common features[white]          ║
- 	╠═════╦══════════════════════════════╦══════════════╣
- 	║ [yellow]F1[white]  ║ This help                    ║ [yellow]!help[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F2[white]  ║ Shell                        ║ [yellow]!shel[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F3[white]  ║ Files Manager                ║ [yellow]!file[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F4[white]  ║ Process and Services Manager ║ [yellow]!proc[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F5[white]  ║ (refresh)                    ║              ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F6[white]  ║ Text Editor                  ║ [yellow]!edit[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F7[white]  ║ Network Manager              ║ [yellow]!net[white]         ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F8[white]  ║ (special functions)          ║              ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F9[white]  ║ SQLite3 Manager              ║ [yellow]!sql[white]         ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F10[white] ║ Users Manager                ║ [yellow]!user[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F11[white] ║ Dashboard                    ║ [yellow]!dash[white]        ║
- 	╠═════╬══════════════════════════════╬══════════════╣
- 	║ [yellow]F12[white] ║ Exit                         ║ [yellow]!quit[white], [yellow]!exit[white] ║
- 	╚═════╩══════════════════════════════╩══════════════╝
+// ****************************************************************************
+// ShowOver()
+// ShowOver shows the topic id over the screen and its dialogs, which stay as
+// they are, Esc goes back to what had the focus
+// ****************************************************************************
+func ShowOver(id string) {
+	t, ok := findTopic(id)
+	if !ok {
+		ui.SetStatus(fmt.Sprintf("No help about %s", id))
+		return
+	}
+	CloseOver()
+	overBack = ui.App.GetFocus()
+	txt := tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	txt.SetText(plainLinks(t.Text()))
+	txt.SetBackgroundColor(tview.Styles.ContrastBackgroundColor)
+	txt.SetBorder(true).SetTitle(" "+t.Title+" ").SetBorderPadding(0, 0, 1, 1)
+	txt.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			CloseOver()
+			return nil
+		}
+		return event
+	})
+	_, _, sw, sh := ui.PgsApp.GetRect()
+	popup := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(txt, sh*3/4, 1, true).
+			AddItem(nil, 0, 1, false), sw*3/4, 1, true).
+		AddItem(nil, 0, 1, false)
+	ui.PgsApp.AddPage(OVER_PAGE, popup, true, true)
+	ui.App.SetFocus(txt)
+}
 
+// ****************************************************************************
+// CloseOver()
+// CloseOver closes the topic shown by ShowOver, it tells if there was one
+// ****************************************************************************
+func CloseOver() bool {
+	if overBack == nil {
+		return false
+	}
+	ui.PgsApp.RemovePage(OVER_PAGE)
+	ui.App.SetFocus(overBack)
+	overBack = nil
+	return true
+}
 
-	╔════╦═══════╦═══════╗
-	║ [yellow]F2[white] ║ [red]Shell[white] ║ [yellow]!shel[white] ║
-	╚════╩═══════╩═══════╝
+// ****************************************************************************
+// ContextTopic()
+// ContextTopic returns the topic about the widget the keys go to
+// ****************************************************************************
+func ContextTopic(ctx string) string {
+	if ui.CurrentMode != ui.ModeTextEdit && ctx == keys.CTX_GLOBAL {
+		return "screens"
+	}
+	if id, ok := contextTopics[ctx]; ok {
+		return id
+	}
+	return "index"
+}
 
+// ****************************************************************************
+// Show()
+// ****************************************************************************
+func Show(id string) {
+	t, ok := findTopic(id)
+	if !ok {
+		ui.SetStatus(fmt.Sprintf("No help about %s", id))
+		return
+	}
+	if current != "" && current != id {
+		history = append(history, current)
+	}
+	render(t)
+}
 
-	╔════╦═══════════════╦═══════╗
-	║ [yellow]F3[white] ║ [red]Files Manager[white] ║ [yellow]!file[white] ║
-	╚════╩═══════════════╩═══════╝
+// ****************************************************************************
+// findTopic()
+// ****************************************************************************
+func findTopic(id string) (Topic, bool) {
+	for _, t := range topics {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Topic{}, false
+}
 
-	[yellow]TAB  [white]  : Move between panels
-	[yellow]Del  [white]  : Delete the file or folder highlighted or the selection
-	[yellow]Ins  [white]  : Add the current file or folder to the selection
-	[yellow]Ctrl+A[white] : Select or unselect all the files and folders in the current folder
-	[yellow]Ctrl+C[white] : Select or unselect all the files and folders in the current folder
-	
-	╔════╦══════════════════════════════╦═══════╗
-	║ [yellow]F4[white] ║ [red]Process and Services Manager[white] ║ [yellow]!proc[white] ║
-	╚════╩══════════════════════════════╩═══════╝
+// ****************************************************************************
+// render()
+// render shows the topic, its links become regions of the text view
+// ****************************************************************************
+func render(t Topic) {
+	current = t.ID
+	links = nil
+	selected = -1
+	text := linkPattern.ReplaceAllStringFunc(t.Text(), func(m string) string {
+		sub := linkPattern.FindStringSubmatch(m)
+		target, ok := findTopic(sub[1])
+		label := utils.If(sub[2] != "", sub[2], target.Title)
+		if !ok {
+			return utils.If(sub[2] != "", sub[2], sub[1])
+		}
+		links = append(links, target.ID)
+		return fmt.Sprintf(`[aqua::u]["%d"]%s[""][-::-]`, len(links)-1, label)
+	})
+	ui.TxtHelp.SetTitle(" " + t.Title + " ")
+	ui.TxtHelp.SetText(text)
+	ui.TxtHelp.Highlight()
+	ui.TxtHelp.ScrollToBeginning()
+	ui.App.SetFocus(ui.TxtHelp)
+}
 
-	╔════╦════════╦═══════╗
-	║ [yellow]F6[white] ║ [red]Editor[white] ║ [yellow]!edit[white] ║
-	╚════╩════════╩═══════╝
-	
-	╔════╦═════════════════╦══════╗
-	║ [yellow]F7[white] ║ [red]Network Manager[white] ║ [yellow]!net[white] ║
-	╚════╩═════════════════╩══════╝
+// ****************************************************************************
+// RegisterScreenKeys()
+// ****************************************************************************
+func RegisterScreenKeys() {
+	keys.AddScreen("Help", helpKeys)
+	conf.IKEY_LABELS = keys.ScreenLabels("Help")
+}
 
- 	╔════╦═════════════════╦══════╗
- 	║ [yellow]F9[white] ║ [red]SQLite3 Manager[white] ║ [yellow]!sql[white] ║
- 	╚════╩═════════════════╩══════╝
+// ****************************************************************************
+// helpKeys()
+// ****************************************************************************
+func helpKeys() []keys.ScreenKey {
+	return []keys.ScreenKey{
+		{Chord: "Tab", Label: "Next link", Done: func() { selectLink(1) }},
+		{Chord: "Backtab", Label: "Previous link", Done: func() { selectLink(-1) }},
+		{Chord: "Enter", Label: "Follow link", Done: func() {
+			if selected >= 0 {
+				Show(links[selected])
+			}
+		}},
+		{Chord: "Backspace", Label: "Back", Done: back},
+		{Chord: "/", Label: "Search topics", Done: func() { Search(nil) }},
+		{Chord: "Esc", Label: "Editor", Done: edit.ShowEditorScreen},
+	}
+}
 
-Here are the .commands available :
- 	╔════════════════╦═══════════════════════════════════════════════════╗
- 	║ [yellow].OPEN database[white] ║ Open the database by its file name                ║
- 	╠════════════════╬═══════════════════════════════════════════════════╣
- 	║ [yellow].TABLE[white]         ║ List all tables available in the current database ║
- 	╠════════════════╬═══════════════════════════════════════════════════╣
- 	║ [yellow].DATABASE[white]      ║ List names and files of attached databases        ║
- 	╠════════════════╬═══════════════════════════════════════════════════╣
- 	║ [yellow].SCHEMA table[white]  ║ Show the CREATE statements for the matching table ║
- 	╠════════════════╬═══════════════════════════════════════════════════╣
- 	║ [yellow].COLUMNS table[white] ║ Show the columns types for the matching table     ║
- 	╚════════════════╩═══════════════════════════════════════════════════╝
+// ****************************************************************************
+// selectLink()
+// ****************************************************************************
+func selectLink(delta int) {
+	if len(links) == 0 {
+		return
+	}
+	switch {
+	case selected < 0 && delta < 0:
+		selected = len(links) - 1
+	case selected < 0:
+		selected = 0
+	default:
+		selected = (selected + delta + len(links)) % len(links)
+	}
+	ui.TxtHelp.Highlight(strconv.Itoa(selected))
+	ui.TxtHelp.ScrollToHighlight()
+}
 
-The common SQL statements are summarized as following :
-	╔════════════════════════════════════════╦════════════════════════════════════════════════════════════════════════════╗
-	║                                        ║ ANALYZE;                                                                   ║
-	║                                        ║ or                                                                         ║
-	║ SQLite [yellow]ANALYZE[white] Statement               ║ ANALYZE database_name;                                                     ║
-	║                                        ║ or                                                                         ║
-	║                                        ║ ANALYZE database_name.table_name;                                          ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2….columnN                                           ║
-	║ SQLite [yellow]AND/OR[white] Clause                   ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  CONDITION-1 {AND|OR} CONDITION-2;                                   ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]ALTER TABLE[white] Statement           ║ ALTER TABLE table_name ADD COLUMN column_def…;                             ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]ALTER TABLE[white] Statement (Rename)  ║ ALTER TABLE table_name RENAME TO new_table_name;                           ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]ATTACH DATABASE[white] Statement       ║ ATTACH DATABASE ‘DatabaseName’ As ‘Alias-Name’;                            ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ BEGIN;                                                                     ║
-	║ SQLite [yellow]BEGIN TRANSACTION[white] Statement     ║ or                                                                         ║
-	║                                        ║ BEGIN EXCLUSIVE TRANSACTION;                                               ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2….columnN                                           ║
-	║ SQLite [yellow]BETWEEN[white] Clause                  ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  column_name BETWEEN val-1 AND val-2;                                ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]CREATE INDEX[white] Statement          ║ CREATE INDEX index_name                                                    ║
-	║                                        ║ ON table_name ( column_name COLLATE NOCASE );                              ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]CREATE UNIQUE INDEX[white] Statement   ║ CREATE UNIQUE INDEX index_name                                             ║
-	║                                        ║ ON table_name ( column1, column2,…columnN);                                ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ CREATE TABLE table_name(                                                   ║
-	║                                        ║    column1 datatype,                                                       ║
-	║                                        ║    column2 datatype,                                                       ║
-	║ SQLite [yellow]CREATE TABLE[white] Statement          ║    column3 datatype,                                                       ║
-	║                                        ║    …                                                                       ║
-	║                                        ║    columnN data type,                                                      ║
-	║                                        ║    PRIMARY KEY( one or more columns ));                                    ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ CREATE TRIGGER database_name.trigger_name                                  ║
-	║                                        ║ BEFORE INSERT ON table_name FOR EACH ROW                                   ║
-	║                                        ║ BEGIN                                                                      ║
-	║ SQLite [yellow]CREATE TRIGGER[white] Statement        ║    stmt1;                                                                  ║
-	║                                        ║    stmt2;                                                                  ║
-	║                                        ║    …                                                                       ║
-	║                                        ║ END;                                                                       ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]CREATE VIEW[white] Statement           ║ CREATE VIEW database_name.view_name  AS                                    ║
-	║                                        ║ SELECT statement…;                                                         ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ CREATE VIRTUAL TABLE database_name.table_name USING weblog( access.log );  ║
-	║ SQLite [yellow]CREATE VIRTUAL TABLE[white] Statement  ║ or                                                                         ║
-	║                                        ║ CREATE VIRTUAL TABLE database_name.table_name USING fts3( );               ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]COMMIT TRANSACTION[white] Statement    ║ COMMIT;                                                                    ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT COUNT(column_name)                                                  ║
-	║ SQLite [yellow]COUNT[white] Clause                    ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  CONDITION;                                                          ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ DELETE FROM table_name                                                     ║
-	║ SQLite [yellow]DELETE[white] Statement                ║ WHERE  {CONDITION};                                                        ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ DETACH DATABASE ‘Alias-Name’;                                              ║
-	║ SQLite [yellow]DETACH DATABASE[white] Statement       ║                                                                            ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT DISTINCT column1, column2… columnN                                  ║
-	║ SQLite [yellow]DISTINCT[white] Clause                 ║ FROM   table_name;                                                         ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ DROP INDEX database_name.index_name;                                       ║
-	║ SQLite [yellow]DROP INDEX[white] Statement            ║                                                                            ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ DROP TABLE database_name.table_name;                                       ║
-	║ SQLite [yellow]DROP TABLE[white] Statement            ║                                                                            ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ DROP INDEX database_name.view_name;                                        ║
-	║ SQLite [yellow]DROP VIEW[white] Statement             ║                                                                            ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ DROP INDEX database_name.trigger_name;                                     ║
-	║ SQLite [yellow]DROP TRIGGER[white] Statement          ║                                                                            ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]EXISTS[white] Clause                   ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  column_name EXISTS (SELECT * FROM   table_name );                   ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ EXPLAIN INSERT statement…;                                                 ║
-	║ SQLite [yellow]EXPLAIN[white] Statement               ║ or                                                                         ║
-	║                                        ║ EXPLAIN QUERY PLAN SELECT statement…;                                      ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]GLOB[white] Clause                     ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  column_name GLOB { PATTERN };                                       ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT SUM(column_name)                                                    ║
-	║ SQLite [yellow]GROUP BY[white] Clause                 ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  CONDITION GROUP BY column_name;                                     ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT SUM(column_name)                                                    ║
-	║                                        ║ FROM   table_name                                                          ║
-	║ SQLite [yellow]HAVING[white] Clause                   ║ WHERE  CONDITION                                                           ║
-	║                                        ║ GROUP BY column_name                                                       ║
-	║                                        ║ HAVING (arithmetic function condition);                                    ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]INSERT INTO[white] Statement           ║ INSERT INTO table_name( column1, column2… columnN)                         ║
-	║                                        ║ VALUES ( value1, value2… valueN);                                          ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]IN[white] Clause                       ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  column_name IN (val-1, val-2,… val-N);                              ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]LIKE[white] Clause                     ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  column_name LIKE { PATTERN };                                       ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]NOT IN[white] Clause                   ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  column_name NOT IN (val-1, val-2,… val-N);                          ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]ORDER BY[white] Clause                 ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  CONDITION                                                           ║
-	║                                        ║ ORDER BY column_name {ASC|DESC};                                           ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]PRAGMA[white] Statement                ║ PRAGMA pragma_name;                                                        ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]RELEASE[white] SAVEPOINT Statement     ║ RELEASE savepoint_name;                                                    ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ REINDEX collation_name;                                                    ║
-	║ SQLite [yellow]REINDEX[white] Statement               ║ REINDEX database_name.index_name;                                          ║
-	║                                        ║ REINDEX database_name.table_name;                                          ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ ROLLBACK;                                                                  ║
-	║ SQLite [yellow]ROLLBACK[white] Statement              ║ or                                                                         ║
-	║                                        ║ ROLLBACK TO SAVEPOINT savepoint_name;                                      ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]SAVEPOINT[white] Statement             ║ SAVEPOINT savepoint_name;                                                  ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]SELECT[white] Statement                ║ SELECT column1, column2… columnN                                           ║
-	║                                        ║ FROM   table_name;                                                         ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ UPDATE table_name                                                          ║
-	║ SQLite [yellow]UPDATE[white] Statement                ║ SET column1 = value1, column2 = value2… columnN=valueN                     ║
-	║                                        ║ [ WHERE  CONDITION ];                                                      ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║ SQLite [yellow]VACUUM[white] Statement                ║ VACUUM;                                                                    ║
-	╠════════════════════════════════════════╬════════════════════════════════════════════════════════════════════════════╣
-	║                                        ║ SELECT column1, column2… columnN                                           ║
-	║ SQLite [yellow]WHERE[white] Clause                    ║ FROM   table_name                                                          ║
-	║                                        ║ WHERE  CONDITION;                                                          ║
-	╚════════════════════════════════════════╩════════════════════════════════════════════════════════════════════════════╝
+// ****************************************************************************
+// back()
+// ****************************************************************************
+func back() {
+	if len(history) == 0 {
+		ui.SetStatus("No previous topic")
+		return
+	}
+	id := history[len(history)-1]
+	history = history[:len(history)-1]
+	if t, ok := findTopic(id); ok {
+		render(t)
+	}
+}
 
-	╔═════╦═══════════════╦═══════╗
-	║ [yellow]F10[white] ║ [red]Users Manager[white] ║ [yellow]!user[white] ║
-	╚═════╩═══════════════╩═══════╝
+// ****************************************************************************
+// Search()
+// Search pops up the list of the topics, matching the query against their
+// titles, then their text
+// ****************************************************************************
+func Search(f any) {
+	DlgSearch = DlgSearch.Filter(" Help topics ", // Title
+		"> ", // Message
+		filterTopics,
+		doSearch,
+		0,
+		ui.GetCurrentScreen(), ui.TxtHelp) // Focus return
+	ui.PgsApp.AddPage("dlgHelpSearch", DlgSearch.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgHelpSearch")
+}
+
+// ****************************************************************************
+// filterTopics()
+// ****************************************************************************
+func filterTopics(query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	pattern := []rune(strings.ReplaceAll(query, " ", ""))
+	searchHits = make(map[string]string)
+	var hits []searchHit
+	for _, t := range topics {
+		text := t.Title
+		score, ok := utils.FuzzyScore(pattern, t.Title)
+		if !ok {
+			line := matchingLine(t, query)
+			if line == "" {
+				continue
+			}
+			// Below the titles matching the query
+			text = fmt.Sprintf("%-24s %s", t.Title, line)
+			score = -1000
+		}
+		searchHits[text] = t.ID
+		hits = append(hits, searchHit{text, score})
+	}
+	if len(pattern) > 0 {
+		sort.SliceStable(hits, func(i, j int) bool {
+			return hits[i].score > hits[j].score
+		})
+	}
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.text)
+	}
+	return out
+}
+
+// ****************************************************************************
+// matchingLine()
+// matchingLine returns the first line of the topic which contains query, as
+// it is displayed
+// ****************************************************************************
+func matchingLine(t Topic, query string) string {
+	text := plainLinks(t.Text())
+	for _, line := range strings.Split(tagPattern.ReplaceAllString(text, ""), "\n") {
+		if line = strings.TrimSpace(line); strings.Contains(strings.ToLower(line), query) {
+			if r := []rune(line); len(r) > 60 {
+				line = string(r[:60]) + "…"
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// ****************************************************************************
+// plainLinks()
+// plainLinks replaces the links of a text by their labels
+// ****************************************************************************
+func plainLinks(text string) string {
+	return linkPattern.ReplaceAllStringFunc(text, func(m string) string {
+		sub := linkPattern.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return sub[2]
+		}
+		target, _ := findTopic(sub[1])
+		return target.Title
+	})
+}
 
-	╔═════╦═══════════╦═══════╗
-	║ [yellow]F11[white] ║ [red]Dashboard[white] ║ [yellow]!dash[white] ║
-	╚═════╩═══════════╩═══════╝
-`)
+// ****************************************************************************
+// doSearch()
+// ****************************************************************************
+func doSearch(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK || DlgSearch.Value == "" {
+		return
+	}
+	Show(searchHits[DlgSearch.Value])
 }
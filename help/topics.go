@@ -0,0 +1,221 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package help
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/conf"
+	"lied/keys"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// ****************************************************************************
+// init()
+// init adds the topics about the editor itself, the other packages add their
+// own ones with AddTopic
+// ****************************************************************************
+func init() {
+	AddTopic("index", conf.APP_NAME, indexTopic)
+	AddTopic("editor", "Editor", func() string {
+		return "The editor is the main panel of the screen, the name of the file being edited is shown above it.\n" +
+			"The gutter shows the lines changed since the last commit, and the conflicts of a merge.\n\n" +
+			"[red]Keys of the editor[white]\n\n" + bindingTable(keys.CTX_EDITOR) +
+			"\nThe " + link("global") + " work too. " + keysOf("find.show") + " shows the find bar, " +
+			keysOf("cmdline.show") + " the " + link("cmdline") + " and " + keysOf("palette.show") +
+			" the " + link("palette") + ".\n" +
+			"See also the " + link("openfiles") + " and the " + link("explorer") + " panels.\n"
+	})
+	AddTopic("openfiles", "Open files", func() string {
+		return "The table of the open files lists the files being edited, " + conf.ICON_MODIFIED +
+			" marks the modified ones and " + conf.ICON_CONFLICT + " the ones with merge conflicts.\n\n" +
+			"[red]Keys of the open files[white]\n\n" + bindingTable(keys.CTX_OPENFILES) +
+			"\nThe " + link("global") + " work too. " + keysOf("panel.next") + " moves to the next panel.\n"
+	})
+	AddTopic("explorer", "Explorer", func() string {
+		return "The explorer shows the tree of the workspace. Enter opens a file or a folder.\n\n" +
+			"[red]Keys of the explorer[white]\n\n" + bindingTable(keys.CTX_EXPLORER) +
+			"\nThe " + link("global") + " work too. " + keysOf("panel.next") + " moves to the next panel.\n"
+	})
+	AddTopic("dialogs", "Dialogs", func() string {
+		return "Tab moves between the fields and the buttons of a dialog, Enter presses the focused button.\n\n" +
+			"[red]Keys of the dialogs[white]\n\n" + bindingTable(keys.CTX_DIALOG) +
+			"\nThe " + link("global") + " work too. " + keysOf("help.show") + " shows this page over the dialog, Esc goes back to it.\n"
+	})
+	AddTopic("global", "Global keys", func() string {
+		return "These keys work everywhere, unless the panel having the focus binds them to another action.\n\n" +
+			bindingTable(keys.CTX_GLOBAL) +
+			"\nSee also the keys of the " + link("editor") + ", of the " + link("openfiles") +
+			", of the " + link("explorer") + " and of the " + link("dialogs") + ".\n"
+	})
+	AddTopic("screens", "Other screens", screensTopic)
+	AddTopic("palette", "Command palette", func() string {
+		return keysOf("palette.show") + " lists all the " + link("actions") + " and the items of the " +
+			link("menus") + ", with their keys. Type a few letters of the command, in the order they appear into " +
+			"its name (\"sva\" finds \"Save as…\"), then Enter to run it.\n"
+	})
+	AddTopic("actions", "Actions", actionsTopic)
+	AddTopic("menus", "Menus", menusTopic)
+	AddTopic("bindings", "Bindings file", bindingsTopic)
+}
+
+// ****************************************************************************
+// indexTopic()
+// ****************************************************************************
+func indexTopic() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[yellow]%s[white] version %s - %s\n\n", conf.APP_STRING, conf.APP_VERSION, conf.APP_URL)
+	sb.WriteString("Lied is a text editor for the terminal. It edits the files of a workspace, " +
+		"with the GIT tooling, a shell and the search into the files at hand.\n\n")
+	fmt.Fprintf(&sb, "Tab selects the next link, Enter follows it, Backspace goes back, / searches the topics "+
+		"and Esc goes back to the editor. %s shows the help about the panel having the focus.\n\n", keysOf("help.show"))
+	sb.WriteString("[red]Topics[white]\n\n")
+	for _, t := range topics {
+		if t.ID != "index" {
+			sb.WriteString("  • " + link(t.ID) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// screensTopic()
+// ****************************************************************************
+func screensTopic() string {
+	var sb strings.Builder
+	sb.WriteString("Besides the editor, Lied shows its tools into screens of their own. " +
+		"The " + link("global") + " work into all of them.\n\n")
+	for _, title := range keys.Screens() {
+		fmt.Fprintf(&sb, "[red]%s[white]\n", title)
+		for _, b := range keys.ScreenBindings(title) {
+			fmt.Fprintf(&sb, "  [yellow]%-16s[white] %s\n", tview.Escape(b[0]), tview.Escape(b[1]))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// actionsTopic()
+// ****************************************************************************
+func actionsTopic() string {
+	var sb strings.Builder
+	sb.WriteString("Every action can be bound to keys, by its name, into the " + link("bindings") + ".\n\n")
+	for _, a := range keys.Actions() {
+		if strings.HasPrefix(a.Name, "mnu") {
+			continue
+		}
+		fmt.Fprintf(&sb, "  [yellow]%-22s[white] %-32s %s\n", a.Name, tview.Escape(a.Label), allKeysOf(a.Name))
+	}
+	sb.WriteString("\nThe items of the " + link("menus") + " are actions too.\n")
+	return sb.String()
+}
+
+// ****************************************************************************
+// menusTopic()
+// ****************************************************************************
+func menusTopic() string {
+	var sb strings.Builder
+	sb.WriteString("The items of the menus can be bound to keys like the other " + link("actions") +
+		", and run from the " + link("palette") + ".\n")
+	title := ""
+	for _, a := range keys.Actions() {
+		if !strings.HasPrefix(a.Name, "mnu") {
+			continue
+		}
+		menu, label, _ := strings.Cut(a.Label, " : ")
+		if menu != title {
+			title = menu
+			fmt.Fprintf(&sb, "\n[red]%s[white]\n\n", tview.Escape(title))
+		}
+		fmt.Fprintf(&sb, "  [yellow]%-26s[white] %-32s %s\n", a.Name, tview.Escape(label), allKeysOf(a.Name))
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// bindingsTopic()
+// ****************************************************************************
+func bindingsTopic() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "The keys are read from [yellow]%s[white], which is written with the default keys when it does not exist.\n\n",
+		filepath.Join("~", conf.APP_FOLDER, conf.FILE_BINDINGS))
+	sb.WriteString("Each section is a context, a key is looked for into the context of the panel having the focus, then into the global one :\n\n")
+	for _, ctx := range keys.CONTEXTS {
+		fmt.Fprintf(&sb, "  [yellow]%s[white]\n", tview.Escape("["+ctx+"]"))
+	}
+	sb.WriteString("\nEach line binds a sequence of keys to one of the " + link("actions") + " :\n\n")
+	sb.WriteString("  [yellow]Ctrl+S = file.save[white]\n")
+	sb.WriteString("  [yellow]Ctrl+K Ctrl+S = file.saveas[white]\n")
+	fmt.Fprintf(&sb, "  [yellow]Ctrl+L = %s[white]\n\n", keys.UNBOUND)
+	fmt.Fprintf(&sb, "The modifiers are Ctrl, Alt and Shift, the action %s unbinds the keys so that the panel gets them.\n", keys.UNBOUND)
+	return sb.String()
+}
+
+// ****************************************************************************
+// bindingTable()
+// ****************************************************************************
+func bindingTable(ctx string) string {
+	var sb strings.Builder
+	for _, b := range keys.Bindings(ctx) {
+		fmt.Fprintf(&sb, "  [yellow]%-18s[white] %-32s [gray]%s[white]\n", tview.Escape(b[0]), tview.Escape(keys.Label(b[1])), b[1])
+	}
+	if sb.Len() == 0 {
+		return "  No key is bound here.\n"
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// keysOf()
+// keysOf returns the first keys of an action, as they are shown into a text
+// ****************************************************************************
+func keysOf(action string) string {
+	for _, ctx := range keys.CONTEXTS {
+		if seqs := keys.KeysOf(ctx, action); len(seqs) > 0 {
+			return "[yellow]" + tview.Escape(seqs[0]) + "[white]"
+		}
+	}
+	return "[yellow]" + action + "[white] (unbound)"
+}
+
+// ****************************************************************************
+// allKeysOf()
+// ****************************************************************************
+func allKeysOf(action string) string {
+	var list []string
+	for _, ctx := range keys.CONTEXTS {
+		for _, b := range keys.Bindings(ctx) {
+			if b[1] != action {
+				continue
+			}
+			if ctx == keys.CTX_GLOBAL {
+				list = append(list, tview.Escape(b[0]))
+			} else {
+				list = append(list, fmt.Sprintf("%s (%s)", tview.Escape(b[0]), ctx))
+			}
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// ****************************************************************************
+// link()
+// ****************************************************************************
+func link(id string) string {
+	return "{{" + id + "}}"
+}
@@ -0,0 +1,472 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package keys
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"lied/menu"
+	"lied/ui"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"gopkg.in/ini.v1"
+)
+
+// ****************************************************************************
+// CONSTANTS
+// ****************************************************************************
+const (
+	CTX_GLOBAL    = "global"
+	CTX_EDITOR    = "editor"
+	CTX_OPENFILES = "openfiles"
+	CTX_EXPLORER  = "explorer"
+	CTX_DIALOG    = "dialog"
+	UNBOUND       = "none"
+)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	// CONTEXTS are looked for in this order into the bindings file
+	CONTEXTS = []string{CTX_GLOBAL, CTX_EDITOR, CTX_OPENFILES, CTX_EXPLORER, CTX_DIALOG}
+	actions  = make(map[string]menu.MenuItem)
+	names    []string                             // Actions in the order they were registered
+	bindings = make(map[string]map[string]string) // Action of a key sequence, by context
+	pending  string                               // Beginning of a key sequence being typed
+	current  = CTX_GLOBAL                         // Context of the last key
+	// Other names of the keys, tcell.KeyNames gives the canonical ones
+	keyAliases = map[string]string{
+		"escape":   "Esc",
+		"return":   "Enter",
+		"pageup":   "PgUp",
+		"pagedown": "PgDn",
+		"del":      "Delete",
+		"ins":      "Insert",
+		"space":    "Space",
+	}
+)
+
+// ****************************************************************************
+// Register()
+// Register adds an action to the registry, its name is the one used by the
+// bindings file
+// ****************************************************************************
+func Register(name string, label string, done func(any), param any) {
+	if _, ok := actions[name]; !ok {
+		names = append(names, name)
+	}
+	actions[name] = menu.MenuItem{Name: name, Label: label, Done: done, Param: param, Enabled: true}
+}
+
+// ****************************************************************************
+// RegisterMenu()
+// RegisterMenu registers the items of the menu made by build, so that they can
+// be bound to keys and found by the palette. The menu is built again to run
+// an item, its state and parameter being the current ones. Only the items
+// named "mnu…" are kept, the others are dynamic (like the open files).
+// ****************************************************************************
+func RegisterMenu(build func() *menu.Menu) {
+	m := build()
+	for _, item := range m.Items() {
+		if item.Done == nil || !strings.HasPrefix(item.Name, "mnu") {
+			continue
+		}
+		name := item.Name
+		Register(name, m.Title()+" : "+item.Label, func(any) {
+			for _, it := range build().Items() {
+				if it.Name == name {
+					if it.Enabled {
+						it.Done(it.Param)
+					} else {
+						ui.SetStatus(fmt.Sprintf("%s is not available", it.Label))
+					}
+					return
+				}
+			}
+		}, nil)
+	}
+}
+
+// ****************************************************************************
+// Actions()
+// Actions returns the registered actions, in the order they were registered
+// ****************************************************************************
+func Actions() []menu.MenuItem {
+	list := make([]menu.MenuItem, 0, len(names))
+	for _, n := range names {
+		list = append(list, actions[n])
+	}
+	return list
+}
+
+// ****************************************************************************
+// Run()
+// Run runs the action called name
+// ****************************************************************************
+func Run(name string) error {
+	a, ok := actions[name]
+	if !ok {
+		return fmt.Errorf("unknown action %s", name)
+	}
+	a.Done(a.Param)
+	return nil
+}
+
+// ****************************************************************************
+// Bind()
+// Bind maps a key sequence like "Ctrl+S" or "Ctrl+K Ctrl+S" to an action into
+// ctx, the action "none" unbinds the sequence
+// ****************************************************************************
+func Bind(ctx string, sequence string, action string) error {
+	if !isContext(ctx) {
+		return fmt.Errorf("unknown context %s", ctx)
+	}
+	seq, err := ParseSequence(sequence)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(action, UNBOUND) {
+		action = ""
+	} else if _, ok := actions[action]; !ok {
+		return fmt.Errorf("unknown action %s", action)
+	}
+	if bindings[ctx] == nil {
+		bindings[ctx] = make(map[string]string)
+	}
+	bindings[ctx][seq] = action
+	return nil
+}
+
+// ****************************************************************************
+// isContext()
+// ****************************************************************************
+func isContext(ctx string) bool {
+	for _, c := range CONTEXTS {
+		if c == ctx {
+			return true
+		}
+	}
+	return false
+}
+
+// ****************************************************************************
+// Load()
+// Load reads the bindings file, which overrides the default bindings. Each
+// section is a context, each key a sequence and each value an action.
+// ****************************************************************************
+func Load(fName string) []error {
+	inidata, err := ini.LoadSources(ini.LoadOptions{KeyValueDelimiters: "="}, fName)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, sec := range inidata.Sections() {
+		if sec.Name() == ini.DefaultSection {
+			continue
+		}
+		for _, k := range sec.Keys() {
+			if err := Bind(sec.Name(), k.Name(), strings.TrimSpace(k.Value())); err != nil {
+				errs = append(errs, fmt.Errorf("%s [%s] %s : %s", fName, sec.Name(), k.Name(), err.Error()))
+			}
+		}
+	}
+	return errs
+}
+
+// ****************************************************************************
+// Save()
+// Save writes the active bindings into fName, with the list of the actions
+// ****************************************************************************
+func Save(fName string) error {
+	f, err := os.OpenFile(fName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "; Key bindings, a section by context and a \"sequence = action\" line by binding.")
+	fmt.Fprintln(w, "; A sequence is made of chords like F5, Esc, Ctrl+S, Alt+Shift+PgDn or Ctrl+K Ctrl+S,")
+	fmt.Fprintln(w, "; the bindings of the global context apply everywhere, \"none\" unbinds a sequence.")
+	fmt.Fprintln(w, ";")
+	fmt.Fprintln(w, "; Actions :")
+	for _, a := range Actions() {
+		fmt.Fprintf(w, ";   %-24s %s\n", a.Name, a.Label)
+	}
+	for _, ctx := range CONTEXTS {
+		fmt.Fprintf(w, "\n[%s]\n", ctx)
+		seqs := make([]string, 0, len(bindings[ctx]))
+		for seq := range bindings[ctx] {
+			seqs = append(seqs, seq)
+		}
+		sort.Strings(seqs)
+		for _, seq := range seqs {
+			action := bindings[ctx][seq]
+			if action == "" {
+				action = UNBOUND
+			}
+			fmt.Fprintf(w, "%s = %s\n", seq, action)
+		}
+	}
+	return w.Flush()
+}
+
+// ****************************************************************************
+// Handle()
+// Handle runs the action bound to event into ctx, or into the global context.
+// It returns nil once the event has been used, as an input capture does.
+// ****************************************************************************
+func Handle(ctx string, event *tcell.EventKey) *tcell.EventKey {
+	current = ctx
+	seq := strings.TrimSpace(pending + " " + EventChord(event))
+	contexts := []string{ctx}
+	if ctx != CTX_GLOBAL {
+		contexts = append(contexts, CTX_GLOBAL)
+	}
+	for _, c := range contexts {
+		if action, ok := bindings[c][seq]; ok {
+			pending = ""
+			if action == "" {
+				return event
+			}
+			Run(action)
+			return nil
+		}
+	}
+	for _, c := range contexts {
+		for s, action := range bindings[c] {
+			if action != "" && strings.HasPrefix(s, seq+" ") {
+				pending = seq
+				ui.SetStatus(seq + " …")
+				return nil
+			}
+		}
+	}
+	if pending != "" {
+		pending = ""
+		ui.SetStatus(seq + " is not bound")
+		return nil
+	}
+	return event
+}
+
+// ****************************************************************************
+// Current()
+// Current returns the context of the last key handled
+// ****************************************************************************
+func Current() string {
+	return current
+}
+
+// ****************************************************************************
+// Bindings()
+// Bindings returns the pairs of sequence and action bound into ctx alone, in
+// the order the actions were registered
+// ****************************************************************************
+func Bindings(ctx string) [][2]string {
+	order := make(map[string]int, len(names))
+	for i, n := range names {
+		order[n] = i
+	}
+	var list [][2]string
+	for seq, a := range bindings[ctx] {
+		if a != "" {
+			list = append(list, [2]string{seq, a})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i][1] != list[j][1] {
+			return order[list[i][1]] < order[list[j][1]]
+		}
+		return list[i][0] < list[j][0]
+	})
+	return list
+}
+
+// ****************************************************************************
+// Label()
+// ****************************************************************************
+func Label(action string) string {
+	return actions[action].Label
+}
+
+// ****************************************************************************
+// KeysOf()
+// KeysOf returns the sequences bound to action into ctx, shortest first
+// ****************************************************************************
+func KeysOf(ctx string, action string) []string {
+	var list []string
+	contexts := []string{ctx}
+	if ctx != CTX_GLOBAL {
+		contexts = append(contexts, CTX_GLOBAL)
+	}
+	for i, c := range contexts {
+		for seq, a := range bindings[c] {
+			// A global binding is hidden by the same sequence into ctx
+			if _, hidden := bindings[ctx][seq]; a == action && (i == 0 || !hidden) {
+				list = append(list, seq)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i]) != len(list[j]) {
+			return len(list[i]) < len(list[j])
+		}
+		return list[i] < list[j]
+	})
+	return list
+}
+
+// ****************************************************************************
+// Labels()
+// Labels makes a line of keys like "F1=Help F2=Panel" with the actions bound
+// into ctx, unbound actions are left out
+// ****************************************************************************
+func Labels(ctx string, actionNames ...string) string {
+	var labels []string
+	for _, n := range actionNames {
+		if seqs := KeysOf(ctx, n); len(seqs) > 0 {
+			labels = append(labels, seqs[0]+"="+actions[n].Label)
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
+// ****************************************************************************
+// ParseSequence()
+// ParseSequence returns the canonical form of a sequence of chords
+// ****************************************************************************
+func ParseSequence(sequence string) (string, error) {
+	var chords []string
+	for _, s := range strings.Fields(sequence) {
+		chord, err := ParseChord(s)
+		if err != nil {
+			return "", err
+		}
+		chords = append(chords, chord)
+	}
+	if len(chords) == 0 {
+		return "", errors.New("empty key sequence")
+	}
+	return strings.Join(chords, " "), nil
+}
+
+// ****************************************************************************
+// ParseChord()
+// ParseChord returns the canonical form of a chord like "alt+shift+pgdn"
+// ****************************************************************************
+func ParseChord(chord string) (string, error) {
+	parts := strings.Split(chord, "+")
+	name := parts[len(parts)-1]
+	if name == "" && len(parts) > 1 {
+		// "Ctrl++"
+		name = "+"
+		parts = parts[:len(parts)-1]
+	}
+	var mods tcell.ModMask
+	for _, p := range parts[:len(parts)-1] {
+		switch strings.ToLower(p) {
+		case "ctrl":
+			mods |= tcell.ModCtrl
+		case "alt":
+			mods |= tcell.ModAlt
+		case "shift":
+			mods |= tcell.ModShift
+		default:
+			return "", fmt.Errorf("unknown modifier %s in %s", p, chord)
+		}
+	}
+	if runes := []rune(name); len(runes) == 1 {
+		return chordName(mods&^tcell.ModShift, runeName(runes[0])), nil
+	}
+	if alias, ok := keyAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	if name == "Space" {
+		return chordName(mods&^tcell.ModShift, name), nil
+	}
+	if strings.EqualFold(name, "Tab") && mods&tcell.ModShift != 0 || strings.EqualFold(name, "Backtab") {
+		return chordName(mods&^tcell.ModShift, tcell.KeyNames[tcell.KeyBacktab]), nil
+	}
+	for k, n := range tcell.KeyNames {
+		if strings.EqualFold(n, name) && !strings.HasPrefix(n, "Ctrl-") && k != tcell.KeyBackspace2 {
+			return chordName(mods, n), nil
+		}
+	}
+	return "", fmt.Errorf("unknown key %s in %s", name, chord)
+}
+
+// ****************************************************************************
+// EventChord()
+// EventChord returns the canonical form of the chord of a key event
+// ****************************************************************************
+func EventChord(event *tcell.EventKey) string {
+	mods := event.Modifiers() & (tcell.ModCtrl | tcell.ModAlt | tcell.ModShift)
+	key := event.Key()
+	switch {
+	case key == tcell.KeyRune:
+		// Shift is already into the rune
+		return chordName(mods&^tcell.ModShift, runeName(event.Rune()))
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ &&
+		key != tcell.KeyBackspace && key != tcell.KeyTab && key != tcell.KeyEnter:
+		return chordName(mods|tcell.ModCtrl, string(rune('A'+key-tcell.KeyCtrlA)))
+	case key == tcell.KeyBackspace2:
+		key = tcell.KeyBackspace
+	case key == tcell.KeyBacktab:
+		mods &^= tcell.ModShift
+	}
+	name, ok := tcell.KeyNames[key]
+	if !ok {
+		return event.Name()
+	}
+	if strings.HasPrefix(name, "Ctrl-") {
+		name = strings.TrimPrefix(name, "Ctrl-")
+		mods |= tcell.ModCtrl
+	}
+	return chordName(mods, name)
+}
+
+// ****************************************************************************
+// runeName()
+// ****************************************************************************
+func runeName(r rune) string {
+	if r == ' ' {
+		return "Space"
+	}
+	return string(unicode.ToUpper(r))
+}
+
+// ****************************************************************************
+// chordName()
+// ****************************************************************************
+func chordName(mods tcell.ModMask, name string) string {
+	var sb strings.Builder
+	if mods&tcell.ModCtrl != 0 {
+		sb.WriteString("Ctrl+")
+	}
+	if mods&tcell.ModAlt != 0 {
+		sb.WriteString("Alt+")
+	}
+	if mods&tcell.ModShift != 0 {
+		sb.WriteString("Shift+")
+	}
+	sb.WriteString(name)
+	return sb.String()
+}
@@ -0,0 +1,242 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package keys
+
+import (
+	"lied/menu"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ****************************************************************************
+// TestParseChord()
+// ****************************************************************************
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		chord string
+		want  string
+		ok    bool
+	}{
+		{"F5", "F5", true},
+		{"f5", "F5", true},
+		{"ctrl+s", "Ctrl+S", true},
+		{"Alt+Ctrl+s", "Ctrl+Alt+S", true},
+		{"alt+shift+pgdn", "Alt+Shift+PgDn", true},
+		{"Shift+F11", "Shift+F11", true},
+		{"escape", "Esc", true},
+		{"Return", "Enter", true},
+		{"Alt+Enter", "Alt+Enter", true},
+		{"PageUp", "PgUp", true},
+		{"Del", "Delete", true},
+		{"Ctrl+Space", "Ctrl+Space", true},
+		{"Shift+Tab", "Backtab", true},
+		{"Backtab", "Backtab", true},
+		{"Shift+a", "A", true},
+		{"Alt+:", "Alt+:", true},
+		{"Ctrl++", "Ctrl++", true},
+		{":", ":", true},
+		{"Hyper+S", "", false},
+		{"Ctrl+Nope", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseChord(tt.chord)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseChord(%q) error = %v, want ok=%v", tt.chord, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseChord(%q) = %q, want %q", tt.chord, got, tt.want)
+		}
+	}
+}
+
+// ****************************************************************************
+// TestParseSequence()
+// ****************************************************************************
+func TestParseSequence(t *testing.T) {
+	tests := []struct {
+		sequence string
+		want     string
+		ok       bool
+	}{
+		{"ctrl+k  ctrl+s", "Ctrl+K Ctrl+S", true},
+		{"F1", "F1", true},
+		{"", "", false},
+		{"Ctrl+K Nope", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSequence(tt.sequence)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSequence(%q) = %q, %v, want %q, ok=%v", tt.sequence, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+// ****************************************************************************
+// TestEventChord()
+// ****************************************************************************
+func TestEventChord(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *tcell.EventKey
+		want  string
+	}{
+		{"function key", tcell.NewEventKey(tcell.KeyF5, 0, tcell.ModNone), "F5"},
+		{"shifted function key", tcell.NewEventKey(tcell.KeyF11, 0, tcell.ModShift), "Shift+F11"},
+		{"control letter", tcell.NewEventKey(tcell.KeyCtrlS, 0, tcell.ModCtrl), "Ctrl+S"},
+		{"control letter without modifier", tcell.NewEventKey(tcell.KeyCtrlP, 0, tcell.ModNone), "Ctrl+P"},
+		{"rune", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), "X"},
+		{"alt rune", tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModAlt), "Alt+X"},
+		{"shifted rune", tcell.NewEventKey(tcell.KeyRune, ':', tcell.ModShift), ":"},
+		{"space", tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModCtrl), "Ctrl+Space"},
+		{"escape", tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone), "Esc"},
+		{"enter", tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), "Enter"},
+		{"alt enter", tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModAlt), "Alt+Enter"},
+		{"tab", tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone), "Tab"},
+		{"backtab", tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModShift), "Backtab"},
+		{"backspace", tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone), "Backspace"},
+		{"alt page down", tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModAlt), "Alt+PgDn"},
+	}
+	for _, tt := range tests {
+		if got := EventChord(tt.event); got != tt.want {
+			t.Errorf("%s: EventChord() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// ****************************************************************************
+// TestParseChordCanonical()
+// ****************************************************************************
+func TestParseChordCanonical(t *testing.T) {
+	for _, chord := range []string{"Ctrl+S", "Alt+PgUp", "Shift+F11", "Alt+:", "Esc", "Alt+Enter", "Backspace"} {
+		parsed, err := ParseChord(chord)
+		if err != nil || parsed != chord {
+			t.Errorf("ParseChord(%q) = %q, %v, is not canonical", chord, parsed, err)
+		}
+	}
+}
+
+// ****************************************************************************
+// resetRegistry()
+// resetRegistry gives the test an empty registry, the former one is restored
+// once it is over
+// ****************************************************************************
+func resetRegistry(t *testing.T) {
+	oldActions, oldNames, oldBindings := actions, names, bindings
+	t.Cleanup(func() { actions, names, bindings = oldActions, oldNames, oldBindings })
+	actions = make(map[string]menu.MenuItem)
+	names = nil
+	bindings = make(map[string]map[string]string)
+	for _, name := range []string{"file.save", "file.open", "edit.copy", "dialog.cancel"} {
+		Register(name, name, func(any) {}, nil)
+	}
+}
+
+// ****************************************************************************
+// TestSaveLoad()
+// ****************************************************************************
+func TestSaveLoad(t *testing.T) {
+	resetRegistry(t)
+	defaults := []struct{ ctx, seq, action string }{
+		{CTX_GLOBAL, "Ctrl+S", "file.save"},
+		{CTX_GLOBAL, "Ctrl+K Ctrl+O", "file.open"},
+		{CTX_EDITOR, "Ctrl+C", "edit.copy"},
+		{CTX_EDITOR, "Ctrl+O", UNBOUND},
+		{CTX_DIALOG, "Esc", "dialog.cancel"},
+	}
+	for _, b := range defaults {
+		if err := Bind(b.ctx, b.seq, b.action); err != nil {
+			t.Fatalf("Bind(%s, %s, %s): %v", b.ctx, b.seq, b.action, err)
+		}
+	}
+	want := make(map[string]map[string]string)
+	for ctx, m := range bindings {
+		want[ctx] = make(map[string]string)
+		for seq, a := range m {
+			want[ctx][seq] = a
+		}
+	}
+	fName := filepath.Join(t.TempDir(), "bindings.ini")
+	if err := Save(fName); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	bindings = make(map[string]map[string]string)
+	if errs := Load(fName); len(errs) > 0 {
+		t.Fatalf("Load: %v", errs)
+	}
+	if !reflect.DeepEqual(bindings, want) {
+		t.Errorf("Load(Save()) = %v, want %v", bindings, want)
+	}
+}
+
+// ****************************************************************************
+// TestLoad()
+// ****************************************************************************
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		ctx    string
+		seq    string
+		action string
+		bound  bool
+		errs   int
+	}{
+		{"binds", "[global]\nctrl+k ctrl+s = file.save\n", CTX_GLOBAL, "Ctrl+K Ctrl+S", "file.save", true, 0},
+		{"overrides", "[global]\nCtrl+S = file.open\n", CTX_GLOBAL, "Ctrl+S", "file.open", true, 0},
+		{"unbinds", "[global]\nCtrl+S = none\n", CTX_GLOBAL, "Ctrl+S", "", true, 0},
+		{"unknown action", "[editor]\nCtrl+C = edit.nope\n", CTX_EDITOR, "Ctrl+C", "edit.copy", true, 1},
+		{"unknown context", "[nope]\nCtrl+C = edit.copy\n", "nope", "Ctrl+C", "", false, 1},
+		{"unknown key", "[editor]\nCtrl+Nope = edit.copy\n", CTX_EDITOR, "Ctrl+C", "edit.copy", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			Bind(CTX_GLOBAL, "Ctrl+S", "file.save")
+			Bind(CTX_EDITOR, "Ctrl+C", "edit.copy")
+			fName := filepath.Join(t.TempDir(), "bindings.ini")
+			if err := os.WriteFile(fName, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			errs := Load(fName)
+			if len(errs) != tt.errs {
+				t.Errorf("Load returned %d errors %v, want %d", len(errs), errs, tt.errs)
+			}
+			action, bound := bindings[tt.ctx][tt.seq]
+			if bound != tt.bound || action != tt.action {
+				t.Errorf("%s [%s] = %q, %v, want %q, %v", tt.seq, tt.ctx, action, bound, tt.action, tt.bound)
+			}
+		})
+	}
+}
+
+// ****************************************************************************
+// TestSaveListsActions()
+// ****************************************************************************
+func TestSaveListsActions(t *testing.T) {
+	resetRegistry(t)
+	fName := filepath.Join(t.TempDir(), "bindings.ini")
+	if err := Save(fName); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, _ := os.ReadFile(fName)
+	for _, s := range []string{"file.save", "[global]", "[dialog]"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("the bindings file misses %s", s)
+		}
+	}
+}
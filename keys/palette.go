@@ -0,0 +1,102 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package keys
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"fmt"
+	"lied/dialog"
+	"lied/ui"
+	"lied/utils"
+	"sort"
+	"strings"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type paletteHit struct {
+	text  string
+	score int
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	DlgPalette     *dialog.Dialog
+	paletteCtx     string
+	paletteActions = make(map[string]string) // Action of an entry of the palette
+)
+
+// ****************************************************************************
+// ShowPalette()
+// ShowPalette pops up the fuzzy list of the registered actions, with their
+// keys into the context the palette has been called from
+// ****************************************************************************
+func ShowPalette(f any) {
+	paletteCtx = current
+	DlgPalette = DlgPalette.Filter(" Commands ", // Title
+		"> ", // Message
+		filterPalette,
+		doPalette,
+		0,
+		ui.GetCurrentScreen(), ui.App.GetFocus()) // Focus return
+	ui.PgsApp.AddPage("dlgPalette", DlgPalette.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgPalette")
+}
+
+// ****************************************************************************
+// filterPalette()
+// filterPalette matches the query against the labels, then the names, of the
+// actions, which keep the order they were registered in when it is empty
+// ****************************************************************************
+func filterPalette(query string) []string {
+	pattern := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	paletteActions = make(map[string]string)
+	var hits []paletteHit
+	for _, a := range Actions() {
+		score, ok := utils.FuzzyScore(pattern, a.Label)
+		if !ok {
+			if score, ok = utils.FuzzyScore(pattern, a.Name); !ok {
+				continue
+			}
+		}
+		text := fmt.Sprintf("%-48s %s", a.Label, strings.Join(KeysOf(paletteCtx, a.Name), ", "))
+		paletteActions[text] = a.Name
+		hits = append(hits, paletteHit{text, score})
+	}
+	if len(pattern) > 0 {
+		sort.SliceStable(hits, func(i, j int) bool {
+			return hits[i].score > hits[j].score
+		})
+	}
+	out := make([]string, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.text)
+	}
+	return out
+}
+
+// ****************************************************************************
+// doPalette()
+// ****************************************************************************
+func doPalette(rc dialog.DlgButton, idx int) {
+	if rc != dialog.BUTTON_OK || DlgPalette.Value == "" {
+		return
+	}
+	if err := Run(paletteActions[DlgPalette.Value]); err != nil {
+		ui.SetStatus(err.Error())
+	}
+}
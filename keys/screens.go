@@ -0,0 +1,156 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package keys
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// ScreenKey is a key handled by a widget of a screen, from its input capture.
+// Done is nil for a key the widget handles by itself (like the arrows of a
+// text view), which is only told about.
+type ScreenKey struct {
+	Chord string // Canonical, see ParseChord
+	Label string
+	Done  func()
+}
+
+// screenKeys are the tables of the widgets of a screen, the key bar and the
+// help are made from them
+type screenKeys struct {
+	title   string
+	widgets []func() []ScreenKey
+}
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	screens []screenKeys // In the order they were added
+)
+
+// ****************************************************************************
+// AddScreen()
+// AddScreen registers the key tables of the widgets of a screen, or replaces
+// the ones of the screen with the same title
+// ****************************************************************************
+func AddScreen(title string, widgets ...func() []ScreenKey) {
+	for i := range screens {
+		if screens[i].title == title {
+			screens[i].widgets = widgets
+			return
+		}
+	}
+	screens = append(screens, screenKeys{title, widgets})
+}
+
+// ****************************************************************************
+// Screens()
+// Screens returns the titles of the screens having keys, in the order they
+// were added
+// ****************************************************************************
+func Screens() []string {
+	titles := make([]string, 0, len(screens))
+	for _, s := range screens {
+		titles = append(titles, s.title)
+	}
+	return titles
+}
+
+// ****************************************************************************
+// HandleScreen()
+// HandleScreen runs the key of list matching event. It returns nil once the
+// event has been used, as an input capture does.
+// ****************************************************************************
+func HandleScreen(list []ScreenKey, event *tcell.EventKey) *tcell.EventKey {
+	chord := EventChord(event)
+	for _, k := range list {
+		if k.Chord == chord && k.Done != nil {
+			k.Done()
+			return nil
+		}
+	}
+	return event
+}
+
+// ****************************************************************************
+// ScreenBindings()
+// ScreenBindings returns the pairs of keys and label of a screen, like
+// "Up/Down" and "History". The labels of a key into several widgets are
+// joined, then the keys having the same label.
+// ****************************************************************************
+func ScreenBindings(title string) [][2]string {
+	var chords []string
+	labels := make(map[string][]string)
+	for _, s := range screens {
+		if s.title != title {
+			continue
+		}
+		for _, widget := range s.widgets {
+			for _, k := range widget() {
+				if _, ok := labels[k.Chord]; !ok {
+					chords = append(chords, k.Chord)
+				}
+				if !contains(labels[k.Chord], k.Label) {
+					labels[k.Chord] = append(labels[k.Chord], k.Label)
+				}
+			}
+		}
+	}
+	var order []string
+	byLabel := make(map[string][]string)
+	for _, c := range chords {
+		label := strings.Join(labels[c], "/")
+		if _, ok := byLabel[label]; !ok {
+			order = append(order, label)
+		}
+		byLabel[label] = append(byLabel[label], c)
+	}
+	list := make([][2]string, 0, len(order))
+	for _, label := range order {
+		list = append(list, [2]string{strings.Join(byLabel[label], "/"), label})
+	}
+	return list
+}
+
+// ****************************************************************************
+// ScreenLabels()
+// ScreenLabels makes the line of keys of a screen, like "Enter=Run
+// Up/Down=History"
+// ****************************************************************************
+func ScreenLabels(title string) string {
+	var labels []string
+	for _, b := range ScreenBindings(title) {
+		labels = append(labels, b[0]+"="+b[1])
+	}
+	return strings.Join(labels, " ")
+}
+
+// ****************************************************************************
+// contains()
+// ****************************************************************************
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package keys
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ****************************************************************************
+// TestScreenLabels()
+// ****************************************************************************
+func TestScreenLabels(t *testing.T) {
+	old := screens
+	t.Cleanup(func() { screens = old })
+	screens = nil
+	input := func() []ScreenKey {
+		return []ScreenKey{
+			{Chord: "Enter", Label: "Run"},
+			{Chord: "Up", Label: "History"},
+			{Chord: "Down", Label: "History"},
+			{Chord: "Tab", Label: "Output"},
+			{Chord: "Esc", Label: "Editor"},
+		}
+	}
+	output := func() []ScreenKey {
+		return []ScreenKey{
+			{Chord: "Enter", Label: "Open"},
+			{Chord: "Tab", Label: "Command"},
+			{Chord: "Esc", Label: "Command"},
+		}
+	}
+	AddScreen("Shell", input, output)
+	AddScreen("Diff", func() []ScreenKey { return []ScreenKey{{Chord: "Esc", Label: "Close"}} })
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Shell", "Enter=Run/Open Up/Down=History Tab=Output/Command Esc=Editor/Command"},
+		{"Diff", "Esc=Close"},
+		{"None", ""},
+	}
+	for _, tt := range tests {
+		if got := ScreenLabels(tt.title); got != tt.want {
+			t.Errorf("ScreenLabels(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+	if got := Screens(); len(got) != 2 || got[0] != "Shell" || got[1] != "Diff" {
+		t.Errorf("Screens() = %v", got)
+	}
+}
+
+// ****************************************************************************
+// TestHandleScreen()
+// ****************************************************************************
+func TestHandleScreen(t *testing.T) {
+	var ran string
+	list := []ScreenKey{
+		{Chord: "Alt+R", Label: "Regex", Done: func() { ran = "regex" }},
+		{Chord: "Ctrl+K", Label: "Kill", Done: func() { ran = "kill" }},
+		{Chord: "Space", Label: "Stage", Done: func() { ran = "stage" }},
+		{Chord: "Up", Label: "Scroll"},
+	}
+	tests := []struct {
+		name  string
+		event *tcell.EventKey
+		ran   string
+		used  bool
+	}{
+		{"alt rune", tcell.NewEventKey(tcell.KeyRune, 'r', tcell.ModAlt), "regex", true},
+		{"alt shifted rune", tcell.NewEventKey(tcell.KeyRune, 'R', tcell.ModAlt|tcell.ModShift), "regex", true},
+		{"control key", tcell.NewEventKey(tcell.KeyCtrlK, 0, tcell.ModCtrl), "kill", true},
+		{"space", tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), "stage", true},
+		{"told about only", tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), "", false},
+		{"rune without alt", tcell.NewEventKey(tcell.KeyRune, 'r', tcell.ModNone), "", false},
+	}
+	for _, tt := range tests {
+		ran = ""
+		used := HandleScreen(list, tt.event) == nil
+		if used != tt.used || ran != tt.ran {
+			t.Errorf("%s: used = %v, ran %q, want %v, %q", tt.name, used, ran, tt.used, tt.ran)
+		}
+	}
+}
@@ -23,11 +23,13 @@ import (
 	"os/user"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"lied/conf"
 	"lied/dialog"
 	"lied/edit"
 	"lied/help"
+	"lied/keys"
 	"lied/menu"
 	"lied/ui"
 	"lied/utils"
@@ -49,20 +51,35 @@ var (
 	MnuConfig           *menu.Menu
 	MnuGIT              *menu.Menu
 	args                []string
-	config              conf.Config
+	config              = conf.Config{Backup: conf.BACKUP_NONE}
 	MnuInputTheme       *menu.Menu
+	MnuRecent           *menu.Menu
+	MnuTools            *menu.Menu
+	MnuTasks            *menu.Menu
 	DlgInputGitUser     *dialog.Dialog
 	DlgInputGitPassword *dialog.Dialog
+	DlgInputGitHost     *dialog.Dialog
+	DlgInputPassphrase  *dialog.Dialog
+	newGitPassword      string
+	newGitHost          string
 	DlgInputFormatTime  *dialog.Dialog
 	DlgInputFormatDate  *dialog.Dialog
 	DlgInputFileOpen    *dialog.Dialog
 	DlgInputShell       *dialog.Dialog
+	DlgInputBackup      *dialog.Dialog
+	arrThemes           = []string{"atom-dark-tc", "bubblegum", "cmc-16", "cmc-paper", "cmc-tc",
+		"darcula", "default", "geany", "github-tc", "gruvbox-tc", "gruvbox", "material-tc", "monokai",
+		"railscast", "simple", "solarized-tc", "solarized", "twilight", "zenburn"}
 )
 
 // ****************************************************************************
 // init()
 // ****************************************************************************
 func init() {
+	// lied is its own GIT_ASKPASS helper, see edit.ServeAskpass()
+	if sock := os.Getenv(conf.ASKPASS_ENV); sock != "" {
+		os.Exit(edit.Askpass(sock))
+	}
 	args = os.Args
 	ui.SessionID, _ = utils.RandomHex(3)
 	hostname, err = os.Hostname()
@@ -130,129 +147,15 @@ func init() {
 // main()
 // ****************************************************************************
 func main() {
-	// Main keyboard's events manager
+	// Main keyboard's events manager, the keys are bound to the actions by
+	// the bindings file, see loadBindings()
 	ui.App.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		evkSaveAs := tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModAlt)
-		if event.Key() == evkSaveAs.Key() && event.Rune() == evkSaveAs.Rune() && event.Modifiers() == evkSaveAs.Modifiers() {
-			edit.SaveFileAs()
-			return nil
-		}
-		switch event.Key() {
-		case tcell.KeyF1:
-			// ui.AddNewScreen(ui.ModeHelp, help.SelfInit, nil)
-			SwitchHelp()
-		case tcell.KeyF8:
-			ShowConfigMenu()
-		case tcell.KeyF6:
-			// ui.ShowPreviousScreen()
-			edit.SwitchPreviousFile()
-		case tcell.KeyF7:
-			// ui.ShowNextScreen()
-			edit.SwitchNextFile()
-		case tcell.KeyF10:
-			ShowMainMenu()
-		case tcell.KeyF3:
-			ShowGITMenu()
-		case tcell.KeyF4:
-			InputShell(nil)
-		case tcell.KeyF12:
-			ShowQuitDialog(nil)
-		case tcell.KeyCtrlC:
-			edit.CurrentFile.View.Copy()
-			return nil
-		case tcell.KeyCtrlX:
-			edit.CurrentFile.View.Cut()
-			return nil
-		case tcell.KeyCtrlZ:
-			edit.CurrentFile.View.Undo()
-			return nil
-		case tcell.KeyCtrlY:
-			edit.CurrentFile.View.Redo()
-			return nil
-		case tcell.KeyCtrlA:
-			edit.CurrentFile.View.SelectAll()
-			return nil
-		case tcell.KeyCtrlV:
-			edit.CurrentFile.View.Paste()
-			return nil
-		case tcell.KeyCtrlL:
-			edit.CurrentFile.View.DeleteLine()
-			return nil
-		case tcell.KeyCtrlS:
-			edit.SaveFile()
-			return nil
-		case tcell.KeyCtrlN:
-			edit.NewFile(config.Workspace)
-			return nil
-		case tcell.KeyCtrlO:
-			InputFileOpen(config.Workspace)
-			return nil
-		case tcell.KeyCtrlT:
-			edit.CloseCurrentFile()
-			return nil
-			/*
-				case tcell.KeyEsc:
-					ui.App.SetFocus(ui.TblOpenFiles)
-					return nil
-			*/
-		}
-		return event
-	})
-
-	// Editor keyboard's events manager
-	ui.EdtMain.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		evkSaveAs := tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModAlt)
-		if event.Key() == evkSaveAs.Key() && event.Rune() == evkSaveAs.Rune() && event.Modifiers() == evkSaveAs.Modifiers() {
-			edit.SaveFileAs()
-			return nil
-		}
-		switch event.Key() {
-		case tcell.KeyCtrlS:
-			edit.SaveFile()
-			return nil
-		case tcell.KeyCtrlN:
-			edit.NewFile(config.Workspace)
-			return nil
-		case tcell.KeyCtrlO:
-			InputFileOpen(config.Workspace)
-			return nil
-		case tcell.KeyCtrlT:
-			edit.CloseCurrentFile()
-			return nil
-		case tcell.KeyEsc:
-			ui.App.SetFocus(ui.TblOpenFiles)
-			return nil
-		}
-		return event
-	})
-	ui.TblOpenFiles.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyF2:
-			ui.App.SetFocus(ui.TrvExplorer)
-			return nil
-		case tcell.KeyEnter:
-			idx, _ := ui.TblOpenFiles.GetSelection()
-			fName := ui.TblOpenFiles.GetCell(idx, 3).Text
-			edit.SwitchOpenFile(fName)
-			edit.SetFocusOnPath(fName)
-			ui.App.SetFocus(ui.EdtMain)
-			return nil
-		}
-		return event
-	})
-	ui.TrvExplorer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyF2:
-			ui.App.SetFocus(ui.EdtMain)
-			return nil
-		}
-		return event
-	})
-	ui.EdtMain.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyF2:
-			ui.App.SetFocus(ui.TblOpenFiles)
-			return nil
+		event = keys.Handle(keyContext(), event)
+		if event != nil && event.Key() == tcell.KeyCtrlC {
+			// tview stops the application on its own Ctrl+C, which would
+			// skip the check of the unsaved files, a copy of it is only
+			// passed to the widget having the focus
+			return tcell.NewEventKey(event.Key(), event.Rune(), event.Modifiers())
 		}
 		return event
 	})
@@ -284,7 +187,22 @@ func main() {
 	ui.SetStatus("Welcome")
 	ui.LblHostname.SetText("♯" + greeting)
 
+	// Now the editor screen exists, the menus can be bound to keys and the
+	// recovery dialogs can be shown
+	loadBindings()
+	initCmdLine()
+	edit.ProposeRecovery()
+
 	go ui.UpdateTime()
+	go edit.AutoSave()
+	go edit.WatchFiles()
+	go edit.RefreshIndex()
+	go edit.UpdateStatus()
+	go edit.WatchRepos()
+	edit.GitGutter()
+	if err := edit.ServeAskpass(); err != nil {
+		ui.SetStatus(fmt.Sprintf("GIT credentials won't be served : %s", err.Error()))
+	}
 	if err := ui.App.SetRoot(ui.PgsApp, true).SetFocus(ui.EdtMain).EnableMouse(true).Run(); err != nil {
 		panic(err)
 	}
@@ -292,9 +210,10 @@ func main() {
 }
 
 // ****************************************************************************
-// ShowMainMenu()
+// buildMainMenu()
+// buildMainMenu fills the main menu, which the palette of commands lists too
 // ****************************************************************************
-func ShowMainMenu() {
+func buildMainMenu() *menu.Menu {
 	MnuMain = MnuMain.New(" "+conf.APP_NAME+" ", ui.GetCurrentScreen(), ui.EdtMain)
 	// Dynamic options (files currently open)
 	for i, e := range edit.OpenFiles {
@@ -313,22 +232,137 @@ func ShowMainMenu() {
 	// Fixed options
 	MnuMain.AddSeparator()
 	// MnuMain.AddItem("mnuOpenWorkspace", "Open Workspace", edit.OpenWorkspace, nil, true, false)
+	MnuMain.AddItem("mnuNewWorkspace", "New workspace…", edit.NewWorkspace, nil, true, false)
+	MnuMain.AddItem("mnuOpenWorkspace", "Open workspace…", edit.ChooseWorkspace, nil, true, false)
+	MnuMain.AddItem("mnuRecentWorkspaces", "Recent workspaces…", ShowRecentWorkspacesMenu, nil, true, false)
+	MnuMain.AddItem("mnuRenameWorkspace", "Rename workspace…", edit.RenameWorkspace, nil, edit.WorkspaceName != "", false)
+	MnuMain.AddItem("mnuDeleteWorkspace", "Delete workspace…", edit.DeleteWorkspace, nil, true, false)
+	MnuMain.AddSeparator()
 	MnuMain.AddItem("mnuSave", "Save", edit.SaveAnyFile, nil, true, false)
 	MnuMain.AddItem("mnuSaveAs", "Save as…", edit.SaveAnyFileAs, nil, true, false)
 	MnuMain.AddItem("mnuNew", "New", edit.NewAnyFile, config.Workspace, true, false)
 	MnuMain.AddItem("mnuOpen", "Open…", InputFileOpen, config.Workspace, true, false)
+	MnuMain.AddItem("mnuQuickOpen", "Go to file…", edit.QuickOpen, nil, true, false)
+	MnuMain.AddItem("mnuRecentFiles", "Recent files…", ShowRecentFilesMenu, nil, true, false)
 	MnuMain.AddItem("mnuClose", "Close", edit.CloseAnyFile, nil, true, false)
+	MnuMain.AddItem("mnuReload", "Reload from disk", edit.ReloadAnyFile, nil, true, false)
+	MnuMain.AddItem("mnuFollow", "Follow (tail -f)", edit.ToggleFollow, nil, true, edit.CurrentFile.Follow)
+	MnuMain.AddItem("mnuReopenEncoding", "Reopen with encoding…", edit.ReopenWithEncoding, nil, true, false)
+	MnuMain.AddItem("mnuSaveEncoding", "Save with encoding…", edit.SaveWithEncoding, nil, true, false)
+	MnuMain.AddItem("mnuEOLLF", "Line endings LF (Unix)", edit.SetLineEnding, edit.EOL_LF, true, edit.CurrentFile.LineEnding == edit.EOL_LF)
+	MnuMain.AddItem("mnuEOLCRLF", "Line endings CRLF (Windows)", edit.SetLineEnding, edit.EOL_CRLF, true, edit.CurrentFile.LineEnding == edit.EOL_CRLF)
+	MnuMain.AddItem("mnuEOLCR", "Line endings CR (Classic Mac)", edit.SetLineEnding, edit.EOL_CR, true, edit.CurrentFile.LineEnding == edit.EOL_CR)
+	MnuMain.AddSeparator()
+	MnuMain.AddItem("mnuFind", "Find & Replace…", edit.ShowFindBar, nil, true, false)
+	MnuMain.AddItem("mnuFindWorkspace", "Find in Workspace…", edit.SwitchSearch, nil, true, false)
+	MnuMain.AddItem("mnuTools", "Tools…", ShowToolsMenu, nil, true, false)
+	MnuMain.AddItem("mnuTasks", "Tasks…", ShowTasksMenu, nil, true, false)
 	MnuMain.AddSeparator()
 	MnuMain.AddItem("mnuQuit", "Quit", ShowQuitDialog, nil, true, false)
+	return MnuMain
+}
+
+// ****************************************************************************
+// ShowMainMenu()
+// ****************************************************************************
+func ShowMainMenu() {
+	buildMainMenu()
 	// Popup menu
 	ui.PgsApp.AddPage("dlgMainMenu", MnuMain.Popup(), true, false)
 	ui.PgsApp.ShowPage("dlgMainMenu")
 }
 
 // ****************************************************************************
-// ShowConfigMenu()
+// ShowRecentFilesMenu()
 // ****************************************************************************
-func ShowConfigMenu() {
+func ShowRecentFilesMenu(p any) {
+	entries := edit.RecentFiles()
+	if len(entries) == 0 {
+		ui.SetStatus("No recent file")
+		return
+	}
+	MnuRecent = MnuRecent.New(" Recent Files ", ui.GetCurrentScreen(), ui.EdtMain)
+	for _, e := range entries {
+		sha, _ := utils.GetSha256(e.Path)
+		MnuRecent.AddItem(sha,
+			fmt.Sprintf("%s %s  %s ⯈ %s", e.Time.Format(config.FormatDate), e.Time.Format(config.FormatTime), filepath.Base(e.Path), filepath.Dir(e.Path)),
+			edit.OpenAnyFile,
+			e.Path,
+			true,
+			e.Path == edit.CurrentFile.FName)
+	}
+	ui.PgsApp.AddPage("dlgRecentMenu", MnuRecent.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgRecentMenu")
+}
+
+// ****************************************************************************
+// ShowRecentWorkspacesMenu()
+// ****************************************************************************
+func ShowRecentWorkspacesMenu(p any) {
+	entries := edit.RecentWorkspaces()
+	if len(entries) == 0 {
+		ui.SetStatus("No recent workspace")
+		return
+	}
+	MnuRecent = MnuRecent.New(" Recent Workspaces ", ui.GetCurrentScreen(), ui.EdtMain)
+	for _, e := range entries {
+		MnuRecent.AddItem("mnuWS"+e.Path,
+			fmt.Sprintf("%s %s  %s", e.Time.Format(config.FormatDate), e.Time.Format(config.FormatTime), e.Path),
+			edit.OpenRecentWorkspace,
+			e.Path,
+			true,
+			e.Path == edit.WorkspaceName)
+	}
+	ui.PgsApp.AddPage("dlgRecentMenu", MnuRecent.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgRecentMenu")
+}
+
+// ****************************************************************************
+// buildToolsMenu()
+// ****************************************************************************
+func buildToolsMenu() *menu.Menu {
+	MnuTools = MnuTools.New(" Tools ", ui.GetCurrentScreen(), ui.EdtMain)
+	for i, t := range edit.Tools {
+		label := t.Name
+		if seqs := keys.KeysOf(keys.CTX_GLOBAL, "tool."+t.Name); len(seqs) > 0 {
+			label = fmt.Sprintf("%s (%s)", t.Name, seqs[0])
+		}
+		MnuTools.AddItem(fmt.Sprintf("mnuTool%d", i), label, edit.RunTool, t, true, false)
+	}
+	if len(edit.Tools) == 0 {
+		MnuTools.AddItem("mnuNoTool", "No tool, add [tool.Name] sections to lied.ini", nil, nil, false, false)
+	}
+	return MnuTools
+}
+
+// ****************************************************************************
+// ShowToolsMenu()
+// ****************************************************************************
+func ShowToolsMenu(p any) {
+	buildToolsMenu()
+	ui.PgsApp.AddPage("dlgToolsMenu", MnuTools.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgToolsMenu")
+}
+
+// ****************************************************************************
+// ShowTasksMenu()
+// ****************************************************************************
+func ShowTasksMenu(p any) {
+	MnuTasks = MnuTasks.New(" Tasks ", ui.GetCurrentScreen(), ui.EdtMain)
+	for i, t := range edit.CurrentTasks() {
+		MnuTasks.AddItem(fmt.Sprintf("mnuTask%d", i), t.Name, edit.RunTask, t, true, false)
+	}
+	MnuTasks.AddSeparator()
+	MnuTasks.AddItem("mnuRunAgain", "Run again", edit.RunLastTask, nil, true, false)
+	MnuTasks.AddItem("mnuProblems", "Problems", edit.SwitchProblems, nil, true, false)
+	ui.PgsApp.AddPage("dlgTasksMenu", MnuTasks.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgTasksMenu")
+}
+
+// ****************************************************************************
+// buildConfigMenu()
+// ****************************************************************************
+func buildConfigMenu() *menu.Menu {
 	MnuConfig = MnuConfig.New(" Settings ", ui.GetCurrentScreen(), ui.EdtMain)
 	// Menu Options
 	MnuConfig.AddItem("mnuCfgTheme", "Theme", InputConfigTheme, nil, true, false)
@@ -338,25 +372,57 @@ func ShowConfigMenu() {
 	MnuConfig.AddItem("mnuCfgShowHidden", "Show Hidden", SwitchShowHidden, nil, true, config.ShowHidden)
 	MnuConfig.AddItem("mnuCfgFormatTime", "Time Format", InputConfigFormatTime, nil, true, false)
 	MnuConfig.AddItem("mnuCfgFormatDate", "Date Format", InputConfigFormatDate, nil, true, false)
+	MnuConfig.AddItem("mnuCfgBackup", "Backups…", InputConfigBackup, nil, true, config.Backup != conf.BACKUP_NONE)
+	return MnuConfig
+}
+
+// ****************************************************************************
+// ShowConfigMenu()
+// ****************************************************************************
+func ShowConfigMenu() {
+	buildConfigMenu()
 	// Popup menu
 	ui.PgsApp.AddPage("dlgConfigMenu", MnuConfig.Popup(), true, false)
 	ui.PgsApp.ShowPage("dlgConfigMenu")
 }
 
 // ****************************************************************************
-// ShowGITMenu()
+// buildGITMenu()
 // ****************************************************************************
-func ShowGITMenu() {
+func buildGITMenu() *menu.Menu {
 	MnuGIT = MnuGIT.New(" GIT Tracking ", ui.GetCurrentScreen(), ui.EdtMain)
 	// Menu Options
-	MnuGIT.AddItem("mnuGITStatus", "Status", InputConfigTheme, nil, true, false)
-	MnuGIT.AddItem("mnuGITCommit", "Commit", InputConfigGitUser, nil, true, false)
-	MnuGIT.AddItem("mnuGITPush", "Push", InputConfigGitPassword, nil, true, false)
-	MnuGIT.AddItem("mnuGITCommitPush", "Commit & Push", SwitchConfirmExit, nil, true, config.ConfirmExit)
-	MnuGIT.AddItem("mnuGITFetch", "Fetch", SwitchShowHidden, nil, true, config.ShowHidden)
-	MnuGIT.AddItem("mnuGITPull", "Pull (Fetch & Merge)", InputConfigFormatTime, nil, true, false)
-	MnuGIT.AddItem("mnuGITBang", "Initialize (GIT Bang)", InputConfigFormatDate, nil, true, false)
-	MnuGIT.AddItem("mnuGITConfigure", "Configure", InputConfigFormatDate, nil, true, false)
+	MnuGIT.AddItem("mnuGITStatus", "Status", edit.GitStatus, nil, true, false)
+	MnuGIT.AddItem("mnuGITBranches", "Branches…", edit.GitBranches, nil, true, false)
+	MnuGIT.AddItem("mnuGITCommit", "Commit", edit.GitCommit, nil, true, false)
+	MnuGIT.AddItem("mnuGITPush", "Push", edit.GitPush, nil, true, false)
+	MnuGIT.AddItem("mnuGITCommitPush", "Commit & Push", edit.GitCommitPush, nil, true, false)
+	MnuGIT.AddItem("mnuGITFetch", "Fetch", edit.GitFetch, nil, true, false)
+	MnuGIT.AddItem("mnuGITPull", "Pull (Fetch & Merge)", edit.GitPull, nil, true, false)
+	MnuGIT.AddItem("mnuGITBang", "Initialize (GIT Bang)", edit.GitInit, nil, true, false)
+	MnuGIT.AddItem("mnuGITConfigure", "Configure", edit.GitConfigure, nil, true, false)
+	MnuGIT.AddSeparator()
+	MnuGIT.AddItem("mnuGITNextChange", "Next change", edit.NextChange, nil, true, false)
+	MnuGIT.AddItem("mnuGITPrevChange", "Previous change", edit.PreviousChange, nil, true, false)
+	MnuGIT.AddItem("mnuGITHunk", "Show change…", edit.ShowHunk, nil, true, false)
+	MnuGIT.AddSeparator()
+	MnuGIT.AddItem("mnuGITNextConflict", "Next conflict", edit.NextConflict, nil, true, false)
+	MnuGIT.AddItem("mnuGITPrevConflict", "Previous conflict", edit.PreviousConflict, nil, true, false)
+	MnuGIT.AddItem("mnuGITOurs", "Accept ours", edit.AcceptOurs, nil, true, false)
+	MnuGIT.AddItem("mnuGITTheirs", "Accept theirs", edit.AcceptTheirs, nil, true, false)
+	MnuGIT.AddItem("mnuGITBoth", "Accept both", edit.AcceptBoth, nil, true, false)
+	MnuGIT.AddItem("mnuGITResolved", "Mark resolved", edit.MarkResolved, nil, true, false)
+	MnuGIT.AddSeparator()
+	MnuGIT.AddItem("mnuGITBlame", "Blame", edit.GitBlame, nil, true, false)
+	MnuGIT.AddItem("mnuGITHistory", "File history", edit.GitHistory, nil, true, false)
+	return MnuGIT
+}
+
+// ****************************************************************************
+// ShowGITMenu()
+// ****************************************************************************
+func ShowGITMenu() {
+	buildGITMenu()
 	// Popup menu
 	ui.PgsApp.AddPage("dlgGITMenu", MnuGIT.Popup(), true, false)
 	ui.PgsApp.ShowPage("dlgGITMenu")
@@ -370,6 +436,7 @@ func appQuit() {
 	// TODO : Clean up lied_XXX null files
 	edit.CheckOpenFilesForSaving()
 	saveSettings()
+	edit.StopAskpass()
 	ui.SetStatus(fmt.Sprintf("Quitting session #%s", ui.SessionID))
 	ui.App.Stop()
 	fmt.Printf("♯%s - %s\n", conf.APP_STRING, conf.APP_URL)
@@ -379,33 +446,53 @@ func appQuit() {
 // readSettings()
 // ****************************************************************************
 func readSettings() {
-	// Read MRU list and open them
-	ui.SetStatus("Reading MRU list")
-	fMRU, err := os.Open(filepath.Join(appDir, conf.FILE_MRU))
-	if err == nil {
-		defer fMRU.Close()
-		sMRU := bufio.NewScanner(fMRU)
-		for sMRU.Scan() {
-			edit.OpenFile(sMRU.Text())
-		}
-	}
-
 	// Read INI file
 	ui.SetStatus("Reading INI file")
 	inidata, err := ini.Load(filepath.Join(appDir, conf.FILE_INI))
 	if err != nil {
 		ui.SetStatus("No INI file found")
-	} else {
+		inidata = ini.Empty()
+	}
+	section := inidata.Section("general")
+
+	// Reopen the last workspace if any, else the MRU list
+	config.WorkspaceName = section.Key("WorkspaceName").String()
+	if config.WorkspaceName != "" {
+		ui.SetStatus(fmt.Sprintf("Opening workspace %s", config.WorkspaceName))
+		if err := edit.OpenNamedWorkspace(config.WorkspaceName); err != nil {
+			ui.SetStatus(err.Error())
+			config.WorkspaceName = ""
+		}
+	}
+	if config.WorkspaceName == "" {
+		ui.SetStatus("Reading MRU list")
+		fMRU, err := os.Open(filepath.Join(appDir, conf.FILE_MRU))
+		if err == nil {
+			defer fMRU.Close()
+			sMRU := bufio.NewScanner(fMRU)
+			for sMRU.Scan() {
+				edit.OpenFile(sMRU.Text())
+			}
+		}
+	}
+
+	if err == nil {
 		// Read them
-		section := inidata.Section("general")
 		config.Theme = section.Key("Theme").String()
 		config.GitUser = section.Key("GitUser").String()
-		config.GitPassword = section.Key("GitPassword").String()
+		edit.GitUser = config.GitUser
+		// Former versions kept the password in clear text and without its
+		// host, it is not given to git until it is set again
+		if password := section.Key("GitPassword").String(); password != "" {
+			ui.AddSecret(password)
+			ui.SetStatus("The Git password was stored in clear text, please set it again to store it encrypted")
+		}
 		config.Workspace = section.Key("Workspace").String()
 		config.ShowHidden, _ = section.Key("ShowHidden").Bool()
 		config.ConfirmExit, _ = section.Key("ConfirmExit").Bool()
 		config.FormatTime = section.Key("FormatTime").String()
 		config.FormatDate = section.Key("FormatDate").String()
+		config.Backup = section.Key("Backup").MustString(conf.BACKUP_NONE)
 		// Set them
 		setTheme(config.Theme)
 		if config.FormatTime == "" {
@@ -416,12 +503,46 @@ func readSettings() {
 			config.FormatDate = "02/01/2006"
 		}
 		ui.MyConfig.FormatDate = config.FormatDate
-		if config.Workspace == "" {
-			config.Workspace, _ = os.Getwd()
+		edit.BackupMode = config.Backup
+		if config.WorkspaceName != "" {
+			// The workspace has restored its own files and cursors
+			config.Workspace = edit.WorkspaceRoot
+		} else {
+			if config.Workspace == "" {
+				config.Workspace, _ = os.Getwd()
+			}
+			edit.SwitchOpenFile(section.Key("CurrentFile").String())
+			edit.CurrentFile.Buffer.Cursor.X, _ = section.Key("CurrentX").Int()
+			edit.CurrentFile.Buffer.Cursor.Y, _ = section.Key("CurrentY").Int()
+		}
+	}
+
+	// Read the user's tools
+	edit.Tools = nil
+	for _, sec := range inidata.Sections() {
+		if strings.HasPrefix(sec.Name(), "tool.") {
+			edit.Tools = append(edit.Tools, edit.Tool{
+				Name:      strings.TrimPrefix(sec.Name(), "tool."),
+				Command:   sec.Key("Command").String(),
+				Directory: sec.Key("Directory").String(),
+				Shortcut:  sec.Key("Shortcut").String(),
+				Output:    sec.Key("Output").MustString(edit.TOOL_SHOW),
+			})
 		}
-		edit.SwitchOpenFile(section.Key("CurrentFile").String())
-		edit.CurrentFile.Buffer.Cursor.X, _ = section.Key("CurrentX").Int()
-		edit.CurrentFile.Buffer.Cursor.Y, _ = section.Key("CurrentY").Int()
+	}
+
+	// Read the user's error formats
+	for _, sec := range inidata.Sections() {
+		if strings.HasPrefix(sec.Name(), "format.") {
+			if err := edit.AddErrorFormat(strings.TrimPrefix(sec.Name(), "format."), sec.Key("Regexp").String()); err != nil {
+				ui.SetStatus(err.Error())
+			}
+		}
+	}
+
+	// Look for swap files left over by a crashed session
+	if n := edit.FindSwapFiles(); n > 0 {
+		ui.SetStatus(fmt.Sprintf("%d swap file(s) found", n))
 	}
 }
 
@@ -441,21 +562,40 @@ func saveSettings() {
 		wMRU.Flush()
 	}
 
+	// Save the current workspace, if any
+	edit.SaveCurrentWorkspace()
+
 	// Save INI file
 	inidata := ini.Empty()
 	sec, _ := inidata.NewSection("general")
 	sec.NewKey("Theme", config.Theme)
 	sec.NewKey("GitUser", config.GitUser)
-	sec.NewKey("GitPassword", config.GitPassword)
 	sec.NewKey("Workspace", edit.CurrentWorkspace)
+	sec.NewKey("WorkspaceName", edit.WorkspaceName)
 	sec.NewKey("ShowHidden", utils.If(config.ShowHidden, "True", "False"))
 	sec.NewKey("ConfirmExit", utils.If(config.ConfirmExit, "True", "False"))
 	sec.NewKey("FormatTime", config.FormatTime)
 	sec.NewKey("FormatDate", config.FormatDate)
+	sec.NewKey("Backup", config.Backup)
 	sec.NewKey("CurrentFile", edit.CurrentFile.FName)
 	sec.NewKey("CurrentX", strconv.Itoa(edit.CurrentFile.Buffer.Cursor.X))
 	sec.NewKey("CurrentY", strconv.Itoa(edit.CurrentFile.Buffer.Cursor.Y))
 
+	for _, t := range edit.Tools {
+		tsec, _ := inidata.NewSection("tool." + t.Name)
+		tsec.NewKey("Command", t.Command)
+		tsec.NewKey("Directory", t.Directory)
+		tsec.NewKey("Shortcut", t.Shortcut)
+		tsec.NewKey("Output", t.Output)
+	}
+
+	for _, f := range edit.ErrorFormats {
+		if f.User {
+			fsec, _ := inidata.NewSection("format." + f.Name)
+			fsec.NewKey("Regexp", f.Re.String())
+		}
+	}
+
 	err = inidata.SaveTo(filepath.Join(appDir, conf.FILE_INI))
 	if err != nil {
 		ui.SetStatus(err.Error())
@@ -475,70 +615,51 @@ func ShowQuitDialog(p any) {
 
 // ****************************************************************************
 // SwitchHelp()
+// SwitchHelp shows the help about the panel having the focus, or goes back to
+// the editor from the help
 // ****************************************************************************
 func SwitchHelp() {
-	if ui.CurrentMode == ui.ModeTextEdit {
-		// We are in TextEdit mode, so we want to switch to Help mode (if any)
-		idx := ui.GetScreenFromTitle("Help")
-		ui.SetStatus(fmt.Sprintf("Help IDX=%s", idx))
-		if idx == "NIL" {
-			// There is no Help mode yet
-			ui.AddNewScreen(ui.ModeHelp, help.SelfInit, nil)
-		} else {
-			i, _ := strconv.Atoi(idx)
-			ui.ShowScreen(i)
-		}
-	} else {
-		// We are in Help mode, so we want to go back to TextEdit mode (if any)
-		idx := ui.GetScreenFromTitle("Editor")
-		ui.SetStatus(fmt.Sprintf("Editor IDX=%s", idx))
-		if idx == "NIL" {
-			// There is no TextEdit mode yet
-			ui.AddNewScreen(ui.ModeTextEdit, edit.SelfInit, config.Workspace)
-		} else {
-			i, _ := strconv.Atoi(idx)
-			ui.ShowScreen(i)
-		}
+	if help.CloseOver() {
+		return
 	}
+	if ui.CurrentMode == ui.ModeHelp {
+		edit.ShowEditorScreen()
+		return
+	}
+	ctx := keys.Current()
+	if ctx == keys.CTX_DIALOG {
+		// Over the dialog, which would be lost behind the help screen
+		help.ShowOver(help.ContextTopic(ctx))
+		return
+	}
+	help.ShowHelp(help.ContextTopic(ctx))
 }
 
 // ****************************************************************************
-// InputConfigTheme()
+// buildThemeMenu()
 // ****************************************************************************
-func InputConfigTheme(f any) {
+func buildThemeMenu() *menu.Menu {
 	MnuInputTheme = MnuInputTheme.New(" Themes ", ui.GetCurrentScreen(), ui.EdtMain)
-	arrThemes := []string{"atom-dark-tc",
-		"bubblegum",
-		"cmc-16",
-		"cmc-paper",
-		"cmc-tc",
-		"darcula",
-		"default",
-		"geany",
-		"github-tc",
-		"gruvbox-tc",
-		"gruvbox",
-		"material-tc",
-		"monokai",
-		"railscast",
-		"simple",
-		"solarized-tc",
-		"solarized",
-		"twilight",
-		"zenburn"}
-
 	for _, thm := range arrThemes {
 		chk := false
 		if thm == config.Theme {
 			chk = true
 		}
-		MnuInputTheme.AddItem(thm,
+		MnuInputTheme.AddItem("mnuTheme-"+thm,
 			thm,
 			setTheme,
 			thm,
 			true,
 			chk)
 	}
+	return MnuInputTheme
+}
+
+// ****************************************************************************
+// InputConfigTheme()
+// ****************************************************************************
+func InputConfigTheme(f any) {
+	buildThemeMenu()
 	// Popup menu
 	ui.PgsApp.AddPage("dlgThemeMenu", MnuInputTheme.Popup(), true, false)
 	ui.PgsApp.ShowPage("dlgThemeMenu")
@@ -572,18 +693,25 @@ func InputConfigGitUser(f any) {
 // ****************************************************************************
 func setGitUser(rc dialog.DlgButton, idx int) {
 	if rc == dialog.BUTTON_OK {
-		config.GitUser = DlgInputGitUser.Value
-		ui.SetStatus(fmt.Sprintf("Git User is set to %s", config.GitUser))
+		applyGitUser(DlgInputGitUser.Value)
 	}
 }
 
+// ****************************************************************************
+// applyGitUser()
+// ****************************************************************************
+func applyGitUser(user string) {
+	config.GitUser = user
+	edit.GitUser = config.GitUser
+	ui.SetStatus(fmt.Sprintf("Git User is set to %s", config.GitUser))
+}
+
 // ****************************************************************************
 // InputConfigGitPassword()
 // ****************************************************************************
 func InputConfigGitPassword(f any) {
-	DlgInputGitPassword = DlgInputGitPassword.Input("Git Password", // Title
+	DlgInputGitPassword = DlgInputGitPassword.Password("Git Password", // Title
 		"Please, enter the Git password :", // Message
-		config.GitPassword,
 		setGitPassword,
 		0,
 		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
@@ -593,12 +721,66 @@ func InputConfigGitPassword(f any) {
 
 // ****************************************************************************
 // setGitPassword()
+// setGitPassword asks for the host the password is given to
 // ****************************************************************************
 func setGitPassword(rc dialog.DlgButton, idx int) {
-	if rc == dialog.BUTTON_OK {
-		config.GitPassword = DlgInputGitPassword.Value
-		ui.SetStatus(fmt.Sprintf("Git Password is set to %s", config.GitPassword))
+	newGitPassword = DlgInputGitPassword.Value
+	DlgInputGitPassword.Value = ""
+	if rc != dialog.BUTTON_OK {
+		newGitPassword = ""
+		return
 	}
+	DlgInputGitHost = DlgInputGitHost.Input("Git Host", // Title
+		"Please, enter the host the Git password is for (https://host) :", // Message
+		edit.RemoteHost(),
+		setGitHost,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgInputGitHost", DlgInputGitHost.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgInputGitHost")
+}
+
+// ****************************************************************************
+// setGitHost()
+// setGitHost asks for the master passphrase which encrypts the password
+// ****************************************************************************
+func setGitHost(rc dialog.DlgButton, idx int) {
+	newGitHost = DlgInputGitHost.Value
+	if rc != dialog.BUTTON_OK || edit.HostOf(newGitHost) == "" {
+		if rc == dialog.BUTTON_OK {
+			ui.SetStatus("Git Password not saved : the host must be an http(s) URL")
+		}
+		newGitPassword = ""
+		newGitHost = ""
+		return
+	}
+	DlgInputPassphrase = DlgInputPassphrase.Password("Master Passphrase", // Title
+		"Please, enter the master passphrase protecting the Git password :", // Message
+		setPassphrase,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgInputPassphrase", DlgInputPassphrase.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgInputPassphrase")
+}
+
+// ****************************************************************************
+// setPassphrase()
+// ****************************************************************************
+func setPassphrase(rc dialog.DlgButton, idx int) {
+	passphrase := DlgInputPassphrase.Value
+	DlgInputPassphrase.Value = ""
+	password := newGitPassword
+	host := newGitHost
+	newGitPassword = ""
+	newGitHost = ""
+	if rc != dialog.BUTTON_OK {
+		return
+	}
+	if err := edit.SaveCredentials(host, password, passphrase); err != nil {
+		ui.SetStatus(fmt.Sprintf("Git Password not saved : %s", err.Error()))
+		return
+	}
+	ui.SetStatus(fmt.Sprintf("Git Password for %s is saved encrypted", edit.HostOf(host)))
 }
 
 // ****************************************************************************
@@ -620,12 +802,19 @@ func InputConfigFormatTime(f any) {
 // ****************************************************************************
 func setFormatTime(rc dialog.DlgButton, idx int) {
 	if rc == dialog.BUTTON_OK {
-		config.FormatTime = DlgInputFormatTime.Value
-		ui.SetStatus(fmt.Sprintf("Time Format is set to %s", config.FormatTime))
-		ui.MyConfig.FormatTime = config.FormatTime
+		applyFormatTime(DlgInputFormatTime.Value)
 	}
 }
 
+// ****************************************************************************
+// applyFormatTime()
+// ****************************************************************************
+func applyFormatTime(format string) {
+	config.FormatTime = format
+	ui.SetStatus(fmt.Sprintf("Time Format is set to %s", config.FormatTime))
+	ui.MyConfig.FormatTime = config.FormatTime
+}
+
 // ****************************************************************************
 // InputConfigFormatDate()
 // ****************************************************************************
@@ -645,12 +834,51 @@ func InputConfigFormatDate(f any) {
 // ****************************************************************************
 func setFormatDate(rc dialog.DlgButton, idx int) {
 	if rc == dialog.BUTTON_OK {
-		config.FormatDate = DlgInputFormatDate.Value
-		ui.SetStatus(fmt.Sprintf("Date Format is set to %s", config.FormatDate))
-		ui.MyConfig.FormatDate = config.FormatDate
+		applyFormatDate(DlgInputFormatDate.Value)
+	}
+}
+
+// ****************************************************************************
+// applyFormatDate()
+// ****************************************************************************
+func applyFormatDate(format string) {
+	config.FormatDate = format
+	ui.SetStatus(fmt.Sprintf("Date Format is set to %s", config.FormatDate))
+	ui.MyConfig.FormatDate = config.FormatDate
+}
+
+// ****************************************************************************
+// InputConfigBackup()
+// ****************************************************************************
+func InputConfigBackup(f any) {
+	DlgInputBackup = DlgInputBackup.List("Backups", // Title
+		"Please, choose how to back up the files being saved :", // Message
+		[]string{conf.BACKUP_NONE, conf.BACKUP_TILDE, conf.BACKUP_TIMESTAMP},
+		setBackup,
+		0,
+		ui.GetCurrentScreen(), ui.EdtMain) // Focus return
+	ui.PgsApp.AddPage("dlgInputBackup", DlgInputBackup.Popup(), true, false)
+	ui.PgsApp.ShowPage("dlgInputBackup")
+}
+
+// ****************************************************************************
+// setBackup()
+// ****************************************************************************
+func setBackup(rc dialog.DlgButton, idx int) {
+	if rc == dialog.BUTTON_OK {
+		applyBackup(DlgInputBackup.Value)
 	}
 }
 
+// ****************************************************************************
+// applyBackup()
+// ****************************************************************************
+func applyBackup(mode string) {
+	config.Backup = mode
+	edit.BackupMode = config.Backup
+	ui.SetStatus(fmt.Sprintf("Backups are set to %s", config.Backup))
+}
+
 // ****************************************************************************
 // InputFileOpen()
 // ****************************************************************************
@@ -669,8 +897,19 @@ func InputFileOpen(f any) {
 // ****************************************************************************
 func doOpenFile(rc dialog.DlgButton, idx int) {
 	if rc == dialog.BUTTON_OK {
-		fn := DlgInputFileOpen.Value
+		openFileNamed(DlgInputFileOpen.Value)
+	}
+}
+
+// ****************************************************************************
+// openFileNamed()
+// ****************************************************************************
+func openFileNamed(fn string) {
+	if fi, err := os.Stat(fn); err == nil && fi.Mode().IsRegular() {
 		ui.SetStatus("Opening " + fn)
+		edit.OpenFile(fn)
+	} else {
+		ui.SetStatus(fmt.Sprintf("%s is not a file", fn))
 	}
 }
 
@@ -695,7 +934,11 @@ func SwitchConfirmExit(dummy any) {
 // InputShell()
 // ****************************************************************************
 func InputShell(f any) {
-	sh := ""
+	if ui.CurrentMode == ui.ModeShell {
+		edit.SwitchShell(nil)
+		return
+	}
+	sh := edit.LastShellCommand()
 	DlgInputShell = DlgInputShell.Input("Shell", // Title
 		"$> ", // Message
 		sh,
@@ -710,7 +953,7 @@ func InputShell(f any) {
 // runShell()
 // ****************************************************************************
 func runShell(rc dialog.DlgButton, idx int) {
-	if rc == dialog.BUTTON_OK {
-		ui.SetStatus(fmt.Sprintf("Running %s", DlgInputShell.Value))
+	if rc == dialog.BUTTON_OK && DlgInputShell.Value != "" {
+		edit.RunShell(DlgInputShell.Value)
 	}
 }
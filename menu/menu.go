@@ -89,6 +89,27 @@ func (m *Menu) AddSeparator() {
 	m.items = append(m.items, *item)
 }
 
+// ****************************************************************************
+// Title() Menu
+// ****************************************************************************
+func (m *Menu) Title() string {
+	return strings.TrimSpace(m.title)
+}
+
+// ****************************************************************************
+// Items() Menu
+// Items returns the items of the menu, separators left out
+// ****************************************************************************
+func (m *Menu) Items() []MenuItem {
+	var list []MenuItem
+	for _, item := range m.items {
+		if item.Name != "SEPARATOR" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // ****************************************************************************
 // SetEnabled() Menu
 // ****************************************************************************
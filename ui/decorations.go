@@ -0,0 +1,247 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package ui
+
+// ****************************************************************************
+// IMPORTS
+// ****************************************************************************
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/pgavlin/femto"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+// Highlight is a range of runes [Start, End) on a buffer line to be painted.
+// A Full highlight paints only the background of Style, up to the right edge.
+type Highlight struct {
+	Line  int
+	Start int
+	End   int
+	Style tcell.Style
+	Full  bool
+}
+
+// GutterMark is a symbol painted into the line numbers column
+type GutterMark struct {
+	Line   int
+	Symbol rune
+	Style  tcell.Style
+}
+
+// visualCell is where a rune of a line is drawn, relative to the first row
+// of the line and to the first column of the text
+type visualCell struct {
+	row   int
+	col   int
+	width int
+}
+
+// Decorator returns the highlights and gutter marks for the visible lines [top, bottom)
+type Decorator func(buf *femto.Buffer, top int, bottom int) ([]Highlight, []GutterMark)
+
+// ****************************************************************************
+// GLOBALS
+// ****************************************************************************
+var (
+	decorators   = make(map[string]Decorator)
+	decoratorsMu sync.Mutex
+)
+
+// ****************************************************************************
+// SetDecorator()
+// SetDecorator registers (or removes, if d is nil) a named editor decorator
+// ****************************************************************************
+func SetDecorator(name string, d Decorator) {
+	decoratorsMu.Lock()
+	defer decoratorsMu.Unlock()
+	if d == nil {
+		delete(decorators, name)
+	} else {
+		decorators[name] = d
+	}
+}
+
+// ****************************************************************************
+// drawDecorations()
+// drawDecorations paints the decorators output over the editor once it is drawn
+// ****************************************************************************
+func drawDecorations(screen tcell.Screen) {
+	if _, item := PgsApp.GetFrontPage(); item != FlxEditor {
+		return
+	}
+	buf := EdtMain.Buf
+	if buf == nil {
+		return
+	}
+	x, y, width, height := EdtMain.GetInnerRect()
+	top := EdtMain.Topline
+	bottom := top + height
+	if bottom > buf.NumLines {
+		bottom = buf.NumLines
+	}
+	offset := 0
+	if buf.Settings["ruler"] == true {
+		offset = len(strconv.Itoa(buf.NumLines)) + 1
+	}
+	textWidth := width - offset
+	if textWidth <= 0 {
+		return
+	}
+	tabsize := int(buf.Settings["tabsize"].(float64))
+	softwrap := buf.Settings["softwrap"] == true
+	left := 0
+	if !softwrap {
+		left = leftCol(EdtMain)
+	}
+
+	// Screen row and layout of each visible line, a wrapped line takes several rows
+	rows := make(map[int]int)
+	cells := make(map[int][]visualCell)
+	row := 0
+	for n := top; n < bottom && row < height; n++ {
+		layout, count := layoutLine([]rune(buf.Line(n)), tabsize, textWidth, softwrap)
+		rows[n] = row
+		cells[n] = layout
+		row += count
+	}
+
+	decoratorsMu.Lock()
+	names := make([]string, 0, len(decorators))
+	for name := range decorators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]Decorator, 0, len(names))
+	for _, name := range names {
+		list = append(list, decorators[name])
+	}
+	decoratorsMu.Unlock()
+
+	paint := func(line int, cell visualCell, cols int, h Highlight) {
+		sy := rows[line] + cell.row
+		if sy >= height {
+			return
+		}
+		_, bg, _ := h.Style.Decompose()
+		for col := cell.col - left; col < cell.col-left+cols && col < textWidth; col++ {
+			if col < 0 {
+				continue
+			}
+			r, comb, style, _ := screen.GetContent(x+offset+col, y+sy)
+			if h.Full {
+				style = style.Background(bg)
+			} else {
+				style = h.Style
+			}
+			screen.SetContent(x+offset+col, y+sy, r, comb, style)
+		}
+	}
+
+	for _, d := range list {
+		highlights, marks := d(buf, top, bottom)
+		for _, m := range marks {
+			if _, ok := rows[m.Line]; !ok || offset == 0 {
+				continue
+			}
+			screen.SetContent(x+offset-1, y+rows[m.Line], m.Symbol, nil, m.Style)
+		}
+		for _, h := range highlights {
+			layout, ok := cells[h.Line]
+			if !ok {
+				continue
+			}
+			last := len(layout) - 1
+			start, end := clamp(h.Start, 0, last), clamp(h.End, 0, last)
+			if h.Full {
+				// From the start up to the right edge, and the rows the line wraps on
+				from := layout[start]
+				paint(h.Line, from, textWidth+left-from.col, h)
+				for r := from.row + 1; r <= layout[last].row; r++ {
+					paint(h.Line, visualCell{r, left, textWidth}, textWidth, h)
+				}
+				continue
+			}
+			for i := start; i < end; i++ {
+				paint(h.Line, layout[i], layout[i].width, h)
+			}
+		}
+	}
+}
+
+// ****************************************************************************
+// layoutLine()
+// layoutLine places the runes of a line the way femto draws them, wrapping
+// them at width when softwrap is set and the line is too long. The cell after
+// the last rune is the end of the line. It returns the cells and the number of
+// rows of the line.
+// ****************************************************************************
+func layoutLine(line []rune, tabsize int, width int, softwrap bool) ([]visualCell, int) {
+	place := func(wrap bool) ([]visualCell, int) {
+		cells := make([]visualCell, 0, len(line)+1)
+		row, col := 0, 0
+		for _, r := range line {
+			w := 1
+			if r == '\t' {
+				w = tabsize - col%tabsize
+			} else if rw := runewidth.RuneWidth(r); rw > 1 {
+				w = rw
+			}
+			cells = append(cells, visualCell{row, col, w})
+			col += w
+			if wrap && col >= width {
+				row++
+				col = 0
+			}
+		}
+		cells = append(cells, visualCell{row, col, 0})
+		return cells, row + 1
+	}
+	cells, rows := place(false)
+	if softwrap && cells[len(cells)-1].col > width {
+		cells, rows = place(true)
+	}
+	return cells, rows
+}
+
+// ****************************************************************************
+// leftCol()
+// leftCol returns the first column shown by the editor once scrolled to the
+// right, femto does not export it
+// ****************************************************************************
+func leftCol(v *femto.View) int {
+	f := reflect.ValueOf(v).Elem().FieldByName("leftCol")
+	if !f.IsValid() || f.Kind() != reflect.Int {
+		return 0
+	}
+	return int(f.Int())
+}
+
+// ****************************************************************************
+// clamp()
+// ****************************************************************************
+func clamp(v int, lo int, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -60,6 +61,13 @@ type Config struct {
 const (
 	ModeHelp Mode = iota
 	ModeTextEdit
+	ModeSearch
+	ModeDiff
+	ModeShell
+	ModeProblems
+	ModeGit
+	ModeBlame
+	ModeHistory
 )
 
 // ****************************************************************************
@@ -76,6 +84,26 @@ var (
 	App          *tview.Application
 	FlxHelp      *tview.Flex
 	FlxEditor    *tview.Flex
+	FlxEditPane  *tview.Flex
+	FlxSearch    *tview.Flex
+	InpSearch    *tview.InputField
+	TblSearch    *tview.Table
+	FlxDiff      *tview.Flex
+	TxtDiff      *tview.TextView
+	FlxShell     *tview.Flex
+	InpShell     *tview.InputField
+	InpCmd       *tview.InputField
+	TxtShell     *tview.TextView
+	FlxProblems  *tview.Flex
+	TblProblems  *tview.Table
+	TxtProblems  *tview.TextView
+	FlxGit       *tview.Flex
+	TblGit       *tview.Table
+	TblGitDiff   *tview.Table
+	FlxBlame     *tview.Flex
+	TblBlame     *tview.Table
+	FlxHistory   *tview.Flex
+	TblHistory   *tview.Table
 	TxtHelp      *tview.TextView
 	lblTitle     *tview.TextView
 	lblStatus    *tview.TextView
@@ -93,12 +121,15 @@ var (
 	TrvExplorer  *tview.TreeView
 	MyConfig     Config
 	LblEncoding  *tview.TextView
+	LblEOL       *tview.TextView
 	LblCursor    *tview.TextView
 	LblDirty     *tview.TextView
 	LblPercent   *tview.TextView
 	LblCommit    *tview.TextView
 	LblGITStatus *tview.TextView
 	LblGITBranch *tview.TextView
+	secrets      []string
+	secretsMu    sync.Mutex
 )
 
 // ****************************************************************************
@@ -112,6 +143,20 @@ func (m *Mode) UnmarshalText(b []byte) error {
 		*m = ModeHelp
 	case str == "ModeTextEdit":
 		*m = ModeTextEdit
+	case str == "ModeSearch":
+		*m = ModeSearch
+	case str == "ModeDiff":
+		*m = ModeDiff
+	case str == "ModeShell":
+		*m = ModeShell
+	case str == "ModeProblems":
+		*m = ModeProblems
+	case str == "ModeGit":
+		*m = ModeGit
+	case str == "ModeBlame":
+		*m = ModeBlame
+	case str == "ModeHistory":
+		*m = ModeHistory
 	}
 
 	return nil
@@ -127,6 +172,20 @@ func (m Mode) String() string {
 		return "ModeHelp"
 	case ModeTextEdit:
 		return "ModeTextEdit"
+	case ModeSearch:
+		return "ModeSearch"
+	case ModeDiff:
+		return "ModeDiff"
+	case ModeShell:
+		return "ModeShell"
+	case ModeProblems:
+		return "ModeProblems"
+	case ModeGit:
+		return "ModeGit"
+	case ModeBlame:
+		return "ModeBlame"
+	case ModeHistory:
+		return "ModeHistory"
 	}
 	return "?"
 }
@@ -193,6 +252,11 @@ func SetUI(fQuit Fn, hostname string) {
 	LblEncoding.SetBackgroundColor(tcell.ColorDarkGreen)
 	LblEncoding.SetTextColor(tcell.ColorWheat)
 
+	LblEOL = tview.NewTextView()
+	LblEOL.SetBorder(false)
+	LblEOL.SetBackgroundColor(tcell.ColorDarkGreen)
+	LblEOL.SetTextColor(tcell.ColorWheat)
+
 	LblCursor = tview.NewTextView()
 	LblCursor.SetBorder(false)
 	LblCursor.SetBackgroundColor(tcell.ColorDarkGreen)
@@ -241,6 +305,12 @@ func SetUI(fQuit Fn, hostname string) {
 	TrvExplorer = tview.NewTreeView()
 	TrvExplorer.SetBorder(true)
 	TrvExplorer.SetTitle("Explorer")
+	InpCmd = tview.NewInputField()
+	InpCmd.SetLabel(":")
+	InpCmd.SetFieldBackgroundColor(tcell.ColorDefault)
+	FlxEditPane = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(TxtEditName, 3, 0, false).
+		AddItem(EdtMain, 0, 1, true)
 
 	//*************************************************************************
 	// Help Layout
@@ -250,7 +320,7 @@ func SetUI(fQuit Fn, hostname string) {
 			AddItem(lblDate, 10, 0, false).
 			AddItem(lblTitle, 0, 1, false).
 			AddItem(lblTime, 8, 0, false), 1, 0, false).
-		AddItem(TxtHelp, 0, 1, false).
+		AddItem(TxtHelp, 0, 1, true).
 		AddItem(LblKeys, 2, 1, false).
 		AddItem(tview.NewFlex().
 			AddItem(LblHostname, len(hostname)+3, 0, false).
@@ -267,9 +337,7 @@ func SetUI(fQuit Fn, hostname string) {
 			AddItem(lblTitle, 0, 1, false).
 			AddItem(lblTime, 10, 0, false), 1, 0, false).
 		AddItem(tview.NewFlex().
-			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(TxtEditName, 3, 0, false).
-				AddItem(EdtMain, 0, 1, true), 0, 2, true).
+			AddItem(FlxEditPane, 0, 2, true).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
 				AddItem(TblOpenFiles, 12, 0, false).
 				AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
@@ -284,8 +352,174 @@ func SetUI(fQuit Fn, hostname string) {
 			AddItem(lblStatus, 0, 1, false).
 			AddItem(LblPercent, 6, 0, false).
 			AddItem(LblCursor, 15, 0, false).
-			AddItem(LblEncoding, 10, 0, false).
+			AddItem(LblEncoding, 14, 0, false).
+			AddItem(LblEOL, 6, 0, false).
 			AddItem(LblDirty, 10, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false).
+		AddItem(InpCmd, 1, 0, false)
+
+	//*************************************************************************
+	// Search Layout
+	//*************************************************************************
+	InpSearch = tview.NewInputField()
+	InpSearch.SetLabel("Find in Workspace ⯈ ")
+	InpSearch.SetBorder(true)
+	TblSearch = tview.NewTable()
+	TblSearch.SetBorder(true)
+	TblSearch.SetSelectable(true, false)
+	TblSearch.SetTitle("Results")
+	FlxSearch = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(InpSearch, 3, 0, true).
+		AddItem(TblSearch, 0, 1, false).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblScreen, 5, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
+
+	//*************************************************************************
+	// Diff Layout
+	//*************************************************************************
+	TxtDiff = tview.NewTextView()
+	TxtDiff.SetBorder(true)
+	TxtDiff.SetDynamicColors(true)
+	TxtDiff.SetWrap(false)
+	FlxDiff = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(TxtDiff, 0, 1, true).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblScreen, 5, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
+
+	//*************************************************************************
+	// Shell Layout
+	//*************************************************************************
+	InpShell = tview.NewInputField()
+	InpShell.SetLabel("$> ")
+	InpShell.SetBorder(true)
+	TxtShell = tview.NewTextView()
+	TxtShell.SetBorder(true)
+	TxtShell.SetDynamicColors(true)
+	TxtShell.SetScrollable(true)
+	TxtShell.SetTitle("Output")
+	FlxShell = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(InpShell, 3, 0, true).
+		AddItem(TxtShell, 0, 1, false).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblPID, 10, 0, false).
+			AddItem(LblRC, 8, 0, false).
+			AddItem(LblScreen, 5, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
+
+	//*************************************************************************
+	// Problems Layout
+	//*************************************************************************
+	TblProblems = tview.NewTable()
+	TblProblems.SetBorder(true)
+	TblProblems.SetSelectable(true, false)
+	TblProblems.SetTitle("Problems")
+	TxtProblems = tview.NewTextView()
+	TxtProblems.SetBorder(true)
+	TxtProblems.SetDynamicColors(true)
+	TxtProblems.SetScrollable(true)
+	TxtProblems.SetTitle("Output")
+	FlxProblems = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(TblProblems, 0, 1, true).
+		AddItem(TxtProblems, 0, 1, false).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblPID, 10, 0, false).
+			AddItem(LblRC, 8, 0, false).
+			AddItem(LblScreen, 5, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
+
+	//*************************************************************************
+	// Git Layout
+	//*************************************************************************
+	TblGit = tview.NewTable()
+	TblGit.SetBorder(true)
+	TblGit.SetSelectable(true, false)
+	TblGit.SetTitle("Changes")
+	TblGitDiff = tview.NewTable()
+	TblGitDiff.SetBorder(true)
+	TblGitDiff.SetSelectable(true, false)
+	TblGitDiff.SetTitle("Diff")
+	FlxGit = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(TblGit, 0, 1, true).
+		AddItem(TblGitDiff, 0, 2, false).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblScreen, 5, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
+
+	//*************************************************************************
+	// Blame Layout
+	//*************************************************************************
+	TblBlame = tview.NewTable()
+	TblBlame.SetBorder(true)
+	TblBlame.SetSelectable(true, false)
+	TblBlame.SetTitle("Blame")
+	FlxBlame = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(TblBlame, 0, 1, true).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblScreen, 5, 0, false).
+			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
+
+	//*************************************************************************
+	// History Layout
+	//*************************************************************************
+	TblHistory = tview.NewTable()
+	TblHistory.SetBorder(true)
+	TblHistory.SetSelectable(true, false)
+	TblHistory.SetTitle("History")
+	FlxHistory = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(lblDate, 10, 0, false).
+			AddItem(lblTitle, 0, 1, false).
+			AddItem(lblTime, 10, 0, false), 1, 0, false).
+		AddItem(TblHistory, 0, 1, true).
+		AddItem(LblKeys, 2, 1, false).
+		AddItem(tview.NewFlex().
+			AddItem(LblHostname, len(hostname)+3, 0, false).
+			AddItem(lblStatus, 0, 1, false).
+			AddItem(LblScreen, 5, 0, false).
 			AddItem(LblHourglass, 2, 0, false), 1, 0, false)
 
 	//*************************************************************************
@@ -301,6 +535,7 @@ func SetUI(fQuit Fn, hostname string) {
 				PgsApp.SwitchToPage(GetCurrentScreen())
 			}
 		})
+	App.SetAfterDrawFunc(drawDecorations)
 	IdxScreens = -1
 }
 
@@ -366,6 +601,7 @@ func GetTitle() string {
 // SetStatus displays the status message during a specific time
 // ****************************************************************************
 func SetStatus(txt string) {
+	txt = Redact(txt)
 	lblStatus.SetText(txt)
 	DurationOfTime := time.Duration(conf.STATUS_MESSAGE_DURATION) * time.Second
 	f := func() {
@@ -502,8 +738,36 @@ func AddNewScreen(mode Mode, selfInit FnAny, param any) {
 		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxEditor, true, true)
 	case ModeHelp:
 		screen.Title = "Help"
-		screen.Keys = ""
+		screen.Keys = conf.IKEY_LABELS
 		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxHelp, true, true)
+	case ModeSearch:
+		screen.Title = "Search"
+		screen.Keys = conf.SKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxSearch, true, true)
+	case ModeDiff:
+		screen.Title = "Diff"
+		screen.Keys = conf.DKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxDiff, true, true)
+	case ModeShell:
+		screen.Title = "Shell"
+		screen.Keys = conf.HKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxShell, true, true)
+	case ModeProblems:
+		screen.Title = "Problems"
+		screen.Keys = conf.PKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxProblems, true, true)
+	case ModeGit:
+		screen.Title = "Git"
+		screen.Keys = conf.GKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxGit, true, true)
+	case ModeBlame:
+		screen.Title = "Blame"
+		screen.Keys = conf.BKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxBlame, true, true)
+	case ModeHistory:
+		screen.Title = "History"
+		screen.Keys = conf.YKEY_LABELS
+		PgsApp.AddPage(screen.Title+"_"+screen.ID, FlxHistory, true, true)
 	}
 	IdxScreens++
 	screen.Idx = IdxScreens
@@ -530,3 +794,34 @@ func PleaseWait() {
 func JobsDone() {
 	LblHourglass.SetText("")
 }
+
+// ****************************************************************************
+// AddSecret()
+// AddSecret registers a password which must never be displayed nor logged
+// ****************************************************************************
+func AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// ****************************************************************************
+// Redact()
+// Redact masks the registered secrets into txt
+// ****************************************************************************
+func Redact(txt string) string {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		txt = strings.ReplaceAll(txt, s, "********")
+	}
+	return txt
+}
@@ -0,0 +1,113 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ****************************************************************************
+// AtomicWriteFile()
+// AtomicWriteFile writes data into a temporary file of the same directory,
+// syncs it and renames it over fName. Symbolic links are followed, so the link
+// itself is kept, and the mode and ownership of an existing file are kept too.
+// The backup function, if any, is called with the target before it's replaced.
+// When the directory does not let us create the temporary file, the existing
+// file is overwritten in place.
+// ****************************************************************************
+func AtomicWriteFile(fName string, data []byte, perm os.FileMode, backup func(target string) error) error {
+	target, err := filepath.EvalSymlinks(fName)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		target = fName
+	}
+	exists := false
+	if fi, err := os.Stat(target); err == nil {
+		perm = fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		exists = true
+	}
+
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".lied-*")
+	if err != nil {
+		if exists && errors.Is(err, fs.ErrPermission) {
+			return writeInPlace(target, data, backup)
+		}
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if exists {
+		copyOwner(target, tmp)
+	}
+	// After the owner, as changing it clears the setuid and setgid bits
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if exists && backup != nil {
+		if err := backup(target); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	// Make the rename itself durable
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// ****************************************************************************
+// writeInPlace()
+// writeInPlace truncates and rewrites an existing file, which keeps its mode,
+// its owner and its links but is not atomic
+// ****************************************************************************
+func writeInPlace(target string, data []byte, backup func(target string) error) error {
+	if backup != nil {
+		if err := backup(target); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
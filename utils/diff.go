@@ -0,0 +1,79 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+const (
+	DIFF_EQUAL  = ' '
+	DIFF_INSERT = '+'
+	DIFF_DELETE = '-'
+)
+
+// DiffLine is one line of a line-based diff, Old and New are the 0-based line
+// numbers in each text (-1 when the line doesn't exist on that side)
+type DiffLine struct {
+	Op   byte
+	Text string
+	Old  int
+	New  int
+}
+
+// ****************************************************************************
+// DiffLines()
+// DiffLines computes the line-based differences between two texts
+// ****************************************************************************
+func DiffLines(a string, b string) []DiffLine {
+	var out []DiffLine
+	dmp := diffmatchpatch.New()
+	ra, rb, lines := dmp.DiffLinesToRunes(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(ra, rb, false), lines)
+	oldN, newN := 0, 0
+	for _, d := range diffs {
+		for _, l := range splitLines(d.Text) {
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				out = append(out, DiffLine{DIFF_EQUAL, l, oldN, newN})
+				oldN++
+				newN++
+			case diffmatchpatch.DiffDelete:
+				out = append(out, DiffLine{DIFF_DELETE, l, oldN, -1})
+				oldN++
+			case diffmatchpatch.DiffInsert:
+				out = append(out, DiffLine{DIFF_INSERT, l, -1, newN})
+				newN++
+			}
+		}
+	}
+	return out
+}
+
+// ****************************************************************************
+// splitLines()
+// ****************************************************************************
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,61 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ****************************************************************************
+// FuzzyScore()
+// FuzzyScore tells if the letters of pattern appear in order into candidate,
+// and how well : consecutive letters, starts of path segments or words, and
+// letters into the base name score higher
+// ****************************************************************************
+func FuzzyScore(pattern []rune, candidate string) (int, bool) {
+	c := []rune(candidate)
+	base := 0
+	for i, r := range c {
+		if r == '/' || r == '\\' {
+			base = i + 1
+		}
+	}
+	score := 0
+	pi := len(pattern) - 1
+	prev := -1
+	// Match from the end, so that the base name gets the letters first
+	for i := len(c) - 1; i >= 0 && pi >= 0; i-- {
+		if unicode.ToLower(c[i]) != pattern[pi] {
+			continue
+		}
+		score++
+		if prev == i+1 {
+			score += 5
+		}
+		if i == 0 || strings.ContainsRune(`/\_-. `, c[i-1]) || (unicode.IsUpper(c[i]) && unicode.IsLower(c[i-1])) {
+			score += 8
+		}
+		if i >= base {
+			score += 3
+		}
+		prev = i
+		pi--
+	}
+	if pi >= 0 {
+		return 0, false
+	}
+	if strings.HasPrefix(strings.ToLower(string(c[base:])), string(pattern)) {
+		score += 20
+	}
+	return score - len(c)/8, true
+}
@@ -0,0 +1,70 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import "testing"
+
+// ****************************************************************************
+// TestFuzzyScoreMatch()
+// ****************************************************************************
+func TestFuzzyScoreMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		candidate string
+		ok        bool
+	}{
+		{"", "anything", true},
+		{"edit", "edit/edit.go", true},
+		{"eg", "edit/edit.go", true},
+		{"sav", "Save as…", true},
+		{"SAV", "Save as…", false}, // The pattern is lowered by the callers
+		{"ge", "edit.go", false},   // In order only
+		{"edits", "edit.go", false},
+		{"x", "", false},
+		{"é", "Café", true},
+	}
+	for _, tt := range tests {
+		if _, ok := FuzzyScore([]rune(tt.pattern), tt.candidate); ok != tt.ok {
+			t.Errorf("FuzzyScore(%q, %q) matched = %v, want %v", tt.pattern, tt.candidate, ok, tt.ok)
+		}
+	}
+}
+
+// ****************************************************************************
+// TestFuzzyScoreOrder()
+// ****************************************************************************
+func TestFuzzyScoreOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		better  string
+		worse   string
+	}{
+		{"base name", "gui", "edit/gui.go", "gui/edit.go"},
+		{"consecutive letters", "save", "Save", "Set a value"},
+		{"start of words", "sa", "Save all", "Pleasant"},
+		{"camel case", "qo", "QuickOpen", "quota"},
+		{"prefix of the base name", "tool", "edit/tools.go", "edit/mytool.go"},
+		{"shorter", "main", "main.go", "cmd/tools/main_windows_amd64.go"},
+	}
+	for _, tt := range tests {
+		b, okb := FuzzyScore([]rune(tt.pattern), tt.better)
+		w, okw := FuzzyScore([]rune(tt.pattern), tt.worse)
+		if !okb || !okw {
+			t.Errorf("%s: %q matches %q %v and %q %v", tt.name, tt.pattern, tt.better, okb, tt.worse, okw)
+			continue
+		}
+		if b <= w {
+			t.Errorf("%s: FuzzyScore(%q) %q = %d, not above %q = %d", tt.name, tt.pattern, tt.better, b, tt.worse, w)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ****************************************************************************
+// TYPES
+// ****************************************************************************
+type ignoreRule struct {
+	base     string
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Ignorer holds the .gitignore rules collected while walking a tree
+type Ignorer struct {
+	rules []ignoreRule
+}
+
+// ****************************************************************************
+// Load()
+// Load reads the .gitignore file of the given directory, if any
+// ****************************************************************************
+func (ig *Ignorer) Load(dir string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		ig.rules = append(ig.rules, rule)
+	}
+}
+
+// ****************************************************************************
+// IsIgnored()
+// IsIgnored tells if the path is excluded by the rules loaded so far
+// ****************************************************************************
+func (ig *Ignorer) IsIgnored(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || IsOutside(rel) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		subject := rel
+		if !rule.anchored {
+			subject = filepath.Base(path)
+		}
+		if rule.re.MatchString(subject) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ****************************************************************************
+// globToRegexp()
+// ****************************************************************************
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			j := strings.IndexByte(glob[i:], ']')
+			if j < 0 {
+				sb.WriteString(`\[`)
+			} else {
+				class := glob[i+1 : i+j]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				sb.WriteString("[" + class + "]")
+				i += j
+			}
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// ****************************************************************************
+// WalkFiles()
+// WalkFiles calls fn for every regular file under root, skipping .git, the
+// hidden entries (unless showHidden) and whatever .gitignore excludes. The
+// walk ends early when stop is closed or fn returns false.
+// ****************************************************************************
+func WalkFiles(root string, showHidden bool, stop <-chan struct{}, fn func(path string) bool) error {
+	return WalkTree(root, showHidden, stop, func(path string, d os.DirEntry) bool {
+		return d.IsDir() || fn(path)
+	})
+}
+
+// ****************************************************************************
+// WalkTree()
+// WalkTree is WalkFiles, with fn called for the directories walked into too,
+// root included
+// ****************************************************************************
+func WalkTree(root string, showHidden bool, stop <-chan struct{}, fn func(path string, d os.DirEntry) bool) error {
+	var ig Ignorer
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		select {
+		case <-stop:
+			return filepath.SkipAll
+		default:
+		}
+		if path != root {
+			name := d.Name()
+			if name == ".git" || (!showHidden && strings.HasPrefix(name, ".")) || ig.IsIgnored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			ig.Load(path)
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+		if !fn(path, d) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+}
+
+// ****************************************************************************
+// IsSkipped()
+// IsSkipped tells if WalkFiles would skip path, a file under root, without
+// walking the whole tree
+// ****************************************************************************
+func IsSkipped(root string, path string, showHidden bool) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || IsOutside(rel) {
+		return true
+	}
+	var ig Ignorer
+	ig.Load(root)
+	parts := strings.Split(rel, string(filepath.Separator))
+	dir := root
+	for i, name := range parts {
+		p := filepath.Join(dir, name)
+		isDir := i < len(parts)-1
+		if name == ".git" || (!showHidden && strings.HasPrefix(name, ".")) || ig.IsIgnored(p, isDir) {
+			return true
+		}
+		if isDir {
+			ig.Load(p)
+		}
+		dir = p
+	}
+	return false
+}
+
+// ****************************************************************************
+// IsOutside()
+// IsOutside tells if a path made relative by filepath.Rel goes up out of its
+// base, a name like "..foo" stays inside
+// ****************************************************************************
+func IsOutside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,25 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+//go:build !unix
+
+package utils
+
+import (
+	"os"
+)
+
+// ****************************************************************************
+// copyOwner()
+// copyOwner does nothing where files have no Unix owner
+// ****************************************************************************
+func copyOwner(source string, f *os.File) {
+}
@@ -0,0 +1,33 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+//go:build unix
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// ****************************************************************************
+// copyOwner()
+// copyOwner gives f the owner and group of the source file, when allowed to
+// ****************************************************************************
+func copyOwner(source string, f *os.File) {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		f.Chown(int(st.Uid), int(st.Gid))
+	}
+}
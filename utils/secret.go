@@ -0,0 +1,87 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	SECRET_SALT = 16
+	SECRET_KEY  = 32
+)
+
+// ****************************************************************************
+// secretCipher()
+// secretCipher derives an AES-256-GCM cipher from the passphrase with scrypt
+// ****************************************************************************
+func secretCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, SECRET_KEY)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ****************************************************************************
+// Seal()
+// Seal encrypts data with a key derived from the passphrase, the result is
+// made of the salt, the nonce and the ciphertext
+// ****************************************************************************
+func Seal(passphrase string, data []byte) ([]byte, error) {
+	salt := make([]byte, SECRET_SALT)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := secretCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(salt, nonce...)
+	return aead.Seal(sealed, nonce, data, nil), nil
+}
+
+// ****************************************************************************
+// Unseal()
+// Unseal decrypts what Seal returned, it fails with a wrong passphrase
+// ****************************************************************************
+func Unseal(passphrase string, sealed []byte) ([]byte, error) {
+	if len(sealed) < SECRET_SALT {
+		return nil, errors.New("invalid secret")
+	}
+	aead, err := secretCipher(passphrase, sealed[:SECRET_SALT])
+	if err != nil {
+		return nil, err
+	}
+	rest := sealed[SECRET_SALT:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("invalid secret")
+	}
+	data, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase")
+	}
+	return data, nil
+}
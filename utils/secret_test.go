@@ -0,0 +1,80 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+// ****************************************************************************
+// TestSealUnseal()
+// ****************************************************************************
+func TestSealUnseal(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		data       []byte
+		unseal     string
+		tamper     bool
+		ok         bool
+	}{
+		{"round trip", "master", []byte("https://host\nsecret"), "master", false, true},
+		{"empty data", "master", []byte{}, "master", false, true},
+		{"unicode passphrase", "mot de passe é", []byte("secret"), "mot de passe é", false, true},
+		{"wrong passphrase", "master", []byte("secret"), "Master", false, false},
+		{"tampered", "master", []byte("secret"), "master", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Seal(tt.passphrase, tt.data)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			if len(tt.data) > 0 && bytes.Contains(sealed, tt.data) {
+				t.Fatalf("Seal leaves the data in clear")
+			}
+			if tt.tamper {
+				sealed[len(sealed)-1] ^= 1
+			}
+			data, err := Unseal(tt.unseal, sealed)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Unseal error = %v, want ok=%v", err, tt.ok)
+			}
+			if tt.ok && !bytes.Equal(data, tt.data) {
+				t.Errorf("Unseal = %q, want %q", data, tt.data)
+			}
+		})
+	}
+}
+
+// ****************************************************************************
+// TestUnsealInvalid()
+// ****************************************************************************
+func TestUnsealInvalid(t *testing.T) {
+	for _, sealed := range [][]byte{nil, []byte("short"), make([]byte, SECRET_SALT+4)} {
+		if _, err := Unseal("master", sealed); err == nil {
+			t.Errorf("Unseal(%q) succeeded", sealed)
+		}
+	}
+}
+
+// ****************************************************************************
+// TestSealSalted()
+// ****************************************************************************
+func TestSealSalted(t *testing.T) {
+	a, _ := Seal("master", []byte("secret"))
+	b, _ := Seal("master", []byte("secret"))
+	if bytes.Equal(a, b) {
+		t.Errorf("Seal returns the same bytes twice")
+	}
+}
@@ -0,0 +1,41 @@
+// ****************************************************************************
+//
+//	 _ _          _
+//	| (_) ___  __| |
+//	| | |/ _ \/ _` |
+//	| | |  __/ (_| |
+//	|_|_|\___|\__,_|
+//
+// ****************************************************************************
+// L I E D   -   Copyright © JPL 2024
+// ****************************************************************************
+//go:build !unix
+
+package utils
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ****************************************************************************
+// ShellCommand()
+// ShellCommand runs cmdline through the command interpreter
+// ****************************************************************************
+func ShellCommand(ctx context.Context, cmdline string) *exec.Cmd {
+	shell := os.Getenv("COMSPEC")
+	if shell == "" {
+		shell = "cmd.exe"
+	}
+	return exec.CommandContext(ctx, shell, "/C", cmdline)
+}
+
+// ****************************************************************************
+// ShellQuote()
+// ShellQuote quotes s as a single word for the command interpreter
+// ****************************************************************************
+func ShellQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -91,6 +91,21 @@ func IsTextFile(fName string) bool {
 	return (utf8.ValidString(string(fileScanner.Text())))
 }
 
+// ****************************************************************************
+// IsBinaryFile()
+// IsBinaryFile sniffs the head of the file for NUL bytes, cheaper than GetMimeType
+// ****************************************************************************
+func IsBinaryFile(fName string) bool {
+	readFile, err := os.Open(fName)
+	if err != nil {
+		return true
+	}
+	defer readFile.Close()
+	head := make([]byte, 8000)
+	n, _ := io.ReadFull(readFile, head)
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
 // ****************************************************************************
 // GetMimeType()
 // ****************************************************************************
@@ -363,8 +378,9 @@ func CopyFile(source string, dest string) (err error) {
 
 	_, err = io.Copy(destfile, sourcefile)
 	if err == nil {
-		sourceinfo, err := os.Stat(source)
-		if err != nil {
+		var sourceinfo os.FileInfo
+		sourceinfo, err = os.Stat(source)
+		if err == nil {
 			err = os.Chmod(dest, sourceinfo.Mode())
 		}
 